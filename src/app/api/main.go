@@ -1,15 +1,23 @@
 package main
 
 import (
+	"app"
+	"app/infra"
+	"app/infra/social"
+	"app/infra/social/providers"
 	"app/interfaces"
 	"app/interfaces/errs"
 	"app/interfaces/handlers"
 	"app/interfaces/repos/gormdb"
 	"app/usecases"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"log"
 
@@ -23,6 +31,18 @@ import (
 	_ "github.com/jinzhu/gorm/dialects/mysql"
 )
 
+// defaultOrderWorkflow allows orders to be completed or cancelled after received
+const defaultOrderWorkflow = "1:2,3;2:;3:"
+
+// minSecretKeyLen is the minimum length of SECRET_KEY which signs tokens with HS256
+const minSecretKeyLen = 32
+
+// default order statuses which decrement or give back the stock, completed and cancelled
+const (
+	defaultStockConfirmStatuses = "2"
+	defaultStockReleaseStatuses = "3"
+)
+
 func main() {
 	durl, err := parseDBURL(os.Getenv("DATABASE_URL"))
 	if err != nil {
@@ -34,33 +54,120 @@ func main() {
 		log.Fatalf("cannot connect to db, err:%s", err)
 	}
 
+	// amounts are stored in minor units of the shop's currency, migrations convert amounts to it
+	app.DefaultCurrency = getenv("CURRENCY", app.DefaultCurrency)
+
 	if os.Getenv("AUTO_MIGRATE") == "yes" {
 		db.LogMode(true)
-		interfaces.InitDB(db)
+		if err := interfaces.InitDB(db); err != nil {
+			log.Fatalf("db can't migrated, err:%s", err)
+		}
+	}
+
+	wf, err := app.ParseOrderWorkflow(getenv("ORDER_WORKFLOW", defaultOrderWorkflow))
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	// Dependencies
-	// mail := infra.NewFakeMail()
+	mail, err := mailSender(os.Getenv("SMTP_URL"))
+	if err != nil {
+		log.Fatal(err)
+	}
 	errH := &errs.Handler{Debug: "on"}
-	socialAuth := usecases.NewSocialAuth()
+	emails := usecases.NewEmails(mail, getenv("DEFAULT_LOCALE", "tr"))
 
 	// Repos
 	gormRepo := gormdb.NewRepo(db)
 	catalogRepo := gormdb.NewCatalog(gormRepo)
 	userRepo := gormdb.NewUser(gormRepo)
-
-	// middlewares
-	authReqMid := interfaces.NewAuthRequiredMid(errH)
-	setUserMid := interfaces.NewSetUserMid(gormRepo, errH)
+	cartRepo := gormdb.NewCart(gormRepo)
+	orderRepo := gormdb.NewOrder(gormRepo)
+	addressRepo := gormdb.NewAddress(gormRepo)
+	tokenRepo := gormdb.NewToken(gormRepo)
+	taxRepo := gormdb.NewTax(gormRepo)
+	couponRepo := gormdb.NewCoupon(gormRepo)
+	stockRepo := gormdb.NewStock(gormRepo)
+	optionRepo := gormdb.NewOption(gormRepo)
 
 	// services
+	keys, err := keySet()
+	if err != nil {
+		log.Fatal(err)
+	}
+	tokenSrv, err := tokens(tokenRepo, keys)
+	if err != nil {
+		log.Fatal(err)
+	}
+	taxSrv, err := taxes(taxRepo)
+	if err != nil {
+		log.Fatal(err)
+	}
+	stockSrv, err := stock(stockRepo)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// links in emails are read once, so a missing one fails here instead of sending broken links
+	verifyURL, resetURL := os.Getenv("EMAIL_VERIFICATION_URL"), os.Getenv("PASSWORD_RESET_URL")
+	skipVerification := os.Getenv("EMAIL_VERIFICATION") == "off"
+	if verifyURL == "" && !skipVerification {
+		log.Fatal("EMAIL_VERIFICATION_URL must be set unless EMAIL_VERIFICATION is off")
+	}
+	if resetURL == "" {
+		log.Fatal("PASSWORD_RESET_URL must be set")
+	}
+
 	// userSrv := usecases.NewUser(gormRepo, mail)
+	socialAuth := usecases.NewSocialAuth(userRepo, socialProviders())
+	profileSrv := usecases.NewProfile(userRepo, tokenSrv, emails)
+	profileSrv.VerifyURL = verifyURL
 	catalogSrv := usecases.NewCatalog(catalogRepo)
+	searcher := productSearcher(gormRepo, catalogRepo)
+	if idx, ok := searcher.(*usecases.IndexSearcher); ok {
+		catalogSrv.Indexer = idx
+	}
+	cartSrv := usecases.NewCart(cartRepo)
+	couponSrv := usecases.NewCoupons(couponRepo)
+	optionSrv := usecases.NewOptions(optionRepo)
+	checkoutSrv := usecases.NewCheckout(orderRepo, cartSrv, taxSrv, couponSrv, stockSrv, wf, emails)
+	if checkoutSrv.DeliveryFee, err = app.ParseMoney(getenv("DELIVERY_FEE", "0"), app.DefaultCurrency); err != nil {
+		log.Fatal(err)
+	}
+	orderSrv := usecases.NewOrders(orderRepo, stockSrv, wf, emails)
+	addressSrv := usecases.NewAddress(addressRepo)
+	guard := attemptGuard()
+	usersSrv := usecases.NewUsers(userRepo, addressRepo, orderRepo, tokenSrv, emails)
+	usersSrv.ResetURL = resetURL
+
+	// middlewares
+	authReqMid := interfaces.NewAuthRequiredMid(errH)
+	setUserMid := interfaces.NewSetUserMid(tokenSrv, errH)
+	catalogAdminMid := interfaces.NewAdminRequiredMid(errH, app.PermManageCatalog)
+	orderAdminMid := interfaces.NewAdminRequiredMid(errH, app.PermManageOrders)
+	userAdminMid := interfaces.NewAdminRequiredMid(errH, app.PermManageUsers)
+	promoAdminMid := interfaces.NewAdminRequiredMid(errH, app.PermManagePromotions)
+	adminMid := interfaces.NewAdminRequiredMid(errH)
 
 	// handlers
-	authH := handlers.NewAuthHandler(userRepo, socialAuth)
-	accountH := handlers.NewAccount(userRepo)
-	catalogH := handlers.NewCatalog(catalogSrv, errH)
+	authH := handlers.NewAuthHandler(userRepo, socialAuth, emails, tokenSrv, guard)
+	authH.SkipEmailVerification = skipVerification
+	authH.VerifyURL = verifyURL
+	authH.ResetURL = resetURL
+	authH.TrustProxyHeaders = os.Getenv("TRUST_PROXY") == "on"
+	accountH := handlers.NewAccount(profileSrv)
+	catalogH := handlers.NewCatalog(catalogSrv, searcher, errH)
+	cartH := handlers.NewCart(cartSrv, errH)
+	orderH := handlers.NewOrder(checkoutSrv, orderSrv, errH)
+	addressH := handlers.NewAddress(addressSrv, errH)
+	taxH := handlers.NewTax(taxSrv, errH)
+	couponH := handlers.NewCoupon(couponSrv, errH)
+	stockH := handlers.NewStock(stockSrv, errH)
+	optionH := handlers.NewOption(optionSrv, errH)
+	emailH := handlers.NewEmail(emails, errH)
+	jwksH := handlers.NewJWKS(keys)
+	identityH := handlers.NewIdentity(socialAuth, errH)
+	usersH := handlers.NewUsers(usersSrv, errH)
 
 	r := mux.NewRouter()
 	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -70,6 +177,19 @@ func main() {
 	authH.SetRoutes(r)
 	accountH.SetRoutes(r, authReqMid)
 	catalogH.SetRoutes(r)
+	catalogH.SetAdminRoutes(r, catalogAdminMid)
+	taxH.SetAdminRoutes(r, catalogAdminMid)
+	couponH.SetAdminRoutes(r, promoAdminMid)
+	stockH.SetAdminRoutes(r, catalogAdminMid)
+	optionH.SetAdminRoutes(r, catalogAdminMid)
+	cartH.SetRoutes(r, authReqMid)
+	orderH.SetRoutes(r, authReqMid)
+	orderH.SetAdminRoutes(r, orderAdminMid)
+	addressH.SetRoutes(r, authReqMid)
+	identityH.SetRoutes(r, authReqMid)
+	usersH.SetAdminRoutes(r, userAdminMid)
+	emailH.SetAdminRoutes(r, adminMid)
+	jwksH.SetRoutes(r)
 
 	r.PathPrefix("/").Handler(http.FileServer(http.Dir("clients/web")))
 
@@ -106,3 +226,212 @@ func parseDBURL(s string) (string, error) {
 
 	return fmt.Sprintf("%s:%s@tcp(%s)%s?charset=utf8&parseTime=True&loc=Local", user, password, host, dbname), nil
 }
+
+// mailSender creates SMTP mail sender, if the url is empty mails are only logged
+func mailSender(surl string) (app.MailSender, error) {
+	if surl == "" {
+		return infra.NewFakeMail(), nil
+	}
+	return infra.NewSMTPMail(surl)
+}
+
+// productSearcher creates the product searcher by SEARCH_BACKEND env.
+// The "memory" backend keeps an in-process index which the catalog service updates
+// on product and category changes, and which is rebuilt periodically.
+func productSearcher(r *gormdb.Repo, cr *gormdb.Catalog) usecases.ProductSearcher {
+	if os.Getenv("SEARCH_BACKEND") != "memory" {
+		return gormdb.NewProductSearch(r)
+	}
+
+	idx := usecases.NewIndexSearcher()
+	reindex := func() {
+		ps, err := cr.FindActiveProducts(&app.DBFilter{Preload: []string{"Categories"}})
+		if err != nil {
+			log.Printf("products can't indexed, err:%s", err)
+			return
+		}
+		idx.Reindex(ps)
+	}
+
+	reindex()
+	go func() {
+		for range time.Tick(5 * time.Minute) {
+			reindex()
+		}
+	}()
+	return idx
+}
+
+// keySet creates the token key set. Tokens are signed with the RSA or EC private key
+// in PEM file at JWT_SIGNING_KEY, or with SECRET_KEY (HS256) if it's empty.
+// Retired keys which are still accepted while rotating are listed in JWT_VERIFY_KEYS
+// as comma separated PEM files like "old.pem,other.pem" or "kid=old.pem".
+// Key ids are the keys' thumbprints unless JWT_SIGNING_KID or "kid=" is given.
+func keySet() (*infra.KeySet, error) {
+	path := os.Getenv("JWT_SIGNING_KEY")
+	if path == "" {
+		secret := os.Getenv("SECRET_KEY")
+		if len(secret) < minSecretKeyLen {
+			return nil, fmt.Errorf("SECRET_KEY must be at least %d characters if JWT_SIGNING_KEY isn't set", minSecretKeyLen)
+		}
+		return infra.NewKeySet(infra.NewHMACKey(getenv("JWT_SIGNING_KID", "secret"), []byte(secret)))
+	}
+
+	signing, err := readKey(os.Getenv("JWT_SIGNING_KID"), path)
+	if err != nil {
+		return nil, err
+	}
+
+	var verify []*infra.Key
+	for _, v := range strings.Split(os.Getenv("JWT_VERIFY_KEYS"), ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		var kid string
+		if i := strings.Index(v, "="); i >= 0 {
+			kid, v = v[:i], v[i+1:]
+		}
+		k, err := readKey(kid, v)
+		if err != nil {
+			return nil, err
+		}
+		verify = append(verify, k)
+	}
+
+	return infra.NewKeySet(signing, verify...)
+}
+
+func readKey(kid, path string) (*infra.Key, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read key file, err:%s", err)
+	}
+	k, err := infra.ParseKeyPEM(kid, b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return k, nil
+}
+
+// tokens creates the token service, token lifetimes can be set by
+// ACCESS_TOKEN_TTL and REFRESH_TOKEN_TTL envs like "15m", "720h".
+// Expired revoked tokens are purged hourly.
+func tokens(r *gormdb.Token, ks app.TokenSigner) (*usecases.Tokens, error) {
+	ts := usecases.NewTokens(r, ks)
+
+	var err error
+	if ts.AccessTTL, err = time.ParseDuration(getenv("ACCESS_TOKEN_TTL", usecases.DefaultAccessTokenTTL.String())); err != nil {
+		return nil, fmt.Errorf("invalid access token ttl, err:%s", err)
+	}
+	if ts.RefreshTTL, err = time.ParseDuration(getenv("REFRESH_TOKEN_TTL", usecases.DefaultRefreshTokenTTL.String())); err != nil {
+		return nil, fmt.Errorf("invalid refresh token ttl, err:%s", err)
+	}
+
+	go func() {
+		for range time.Tick(time.Hour) {
+			if err := ts.PurgeExpired(); err != nil {
+				log.Printf("revoked tokens can't purged, err:%s", err)
+			}
+		}
+	}()
+	return ts, nil
+}
+
+// taxes creates the tax service. Prices include the tax unless PRICES_INCLUDE_TAX is "no",
+// DEFAULT_TAX_RATE is the tax percentage of products which haven't a tax class.
+func taxes(r *gormdb.Tax) (*usecases.Taxes, error) {
+	ts := usecases.NewTaxes(r)
+	ts.PricesIncludeTax = os.Getenv("PRICES_INCLUDE_TAX") != "no"
+
+	rate, err := strconv.Atoi(getenv("DEFAULT_TAX_RATE", strconv.Itoa(usecases.DefaultTaxRate)))
+	if err != nil || rate < 0 || rate > 100 {
+		return nil, fmt.Errorf("invalid default tax rate: %s", os.Getenv("DEFAULT_TAX_RATE"))
+	}
+	ts.DefaultRate = rate
+	return ts, nil
+}
+
+// stock creates the stock service. STOCK_RESERVATION_TTL is how long checkout holds the stock
+// like "30m", STOCK_CONFIRM_STATUSES and STOCK_RELEASE_STATUSES are comma separated order statuses
+// which decrement and give back the stock. Expired reservations are released every minute.
+func stock(r *gormdb.Stock) (*usecases.Stock, error) {
+	ss := usecases.NewStock(r)
+
+	var err error
+	if ss.ReservationTTL, err = time.ParseDuration(getenv("STOCK_RESERVATION_TTL", usecases.DefaultReservationTTL.String())); err != nil {
+		return nil, fmt.Errorf("invalid stock reservation ttl, err:%s", err)
+	}
+	if ss.ConfirmStatuses, err = statusIDs(getenv("STOCK_CONFIRM_STATUSES", defaultStockConfirmStatuses)); err != nil {
+		return nil, err
+	}
+	if ss.ReleaseStatuses, err = statusIDs(getenv("STOCK_RELEASE_STATUSES", defaultStockReleaseStatuses)); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for range time.Tick(time.Minute) {
+			if _, err := ss.Expire(); err != nil {
+				log.Printf("expired stock reservations can't released, err:%s", err)
+			}
+		}
+	}()
+	return ss, nil
+}
+
+func statusIDs(s string) ([]int, error) {
+	var ids []int
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid order status: %s", v)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// attemptGuard creates the guard of login and password reset attempts.
+// Failed attempts are kept in memory, expired ones are purged every 10 minutes.
+func attemptGuard() *usecases.AttemptGuard {
+	s := infra.NewMemoryAttemptStore()
+	go func() {
+		for range time.Tick(10 * time.Minute) {
+			s.PurgeExpired()
+		}
+	}()
+	return usecases.NewAttemptGuard(s)
+}
+
+// socialProviders creates social login providers, their base urls can be set by
+// FACEBOOK_API_URL, GOOGLE_API_URL and GITHUB_API_URL envs.
+// A provider is enabled only if its client is set, so tokens of other apps aren't accepted.
+func socialProviders() *social.Registry {
+	r := social.NewRegistry()
+	if id, secret := os.Getenv("FACEBOOK_APP_ID"), os.Getenv("FACEBOOK_APP_SECRET"); id != "" && secret != "" {
+		r.Register(providers.NewFacebook(os.Getenv("FACEBOOK_API_URL"), id, secret))
+	} else {
+		log.Print("facebook sign in is disabled, FACEBOOK_APP_ID or FACEBOOK_APP_SECRET isn't set")
+	}
+	if id, secret := os.Getenv("GITHUB_CLIENT_ID"), os.Getenv("GITHUB_CLIENT_SECRET"); id != "" && secret != "" {
+		r.Register(providers.NewGitHub(os.Getenv("GITHUB_API_URL"), id, secret))
+	} else {
+		log.Print("github sign in is disabled, GITHUB_CLIENT_ID or GITHUB_CLIENT_SECRET isn't set")
+	}
+	if id := os.Getenv("GOOGLE_CLIENT_ID"); id != "" {
+		r.Register(providers.NewGoogle(os.Getenv("GOOGLE_API_URL"), id))
+	} else {
+		log.Print("google sign in is disabled, GOOGLE_CLIENT_ID isn't set")
+	}
+	return r
+}
+
+// getenv gets env variable, returns fallback if it's empty
+func getenv(k, fallback string) string {
+	if v := os.Getenv(k); v != "" {
+		return v
+	}
+	return fallback
+}
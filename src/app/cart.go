@@ -0,0 +1,83 @@
+package app
+
+// Cart is user's shopping cart. Every user has only one cart.
+type Cart struct {
+	Model
+	UserID int        `json:"-" gorm:"unique_index"`
+	Items  []CartItem `json:"items"`
+	Total  Money      `json:"total" gorm:"-"`
+}
+
+// Item finds the cart item by id
+func (c *Cart) Item(id int) (*CartItem, bool) {
+	for i := range c.Items {
+		if c.Items[i].ID == id {
+			return &c.Items[i], true
+		}
+	}
+	return nil, false
+}
+
+// ItemByProduct finds the cart item that has given product and options
+func (c *Cart) ItemByProduct(productID int, options ProductOptions) (*CartItem, bool) {
+	for i := range c.Items {
+		if c.Items[i].ProductID == productID && c.Items[i].Options.Key() == options.Key() {
+			return &c.Items[i], true
+		}
+	}
+	return nil, false
+}
+
+// ProductQty gets the total quantity of the product in the cart with all options
+func (c *Cart) ProductQty(productID int) int {
+	var n int
+	for _, ci := range c.Items {
+		if ci.ProductID == productID {
+			n += ci.Qty
+		}
+	}
+	return n
+}
+
+// OptionQty gets the total quantity of items which have the option value
+func (c *Cart) OptionQty(valueID int) int {
+	var n int
+	for _, ci := range c.Items {
+		for _, id := range ci.Options.ValueIDs() {
+			if id == valueID {
+				n += ci.Qty
+			}
+		}
+	}
+	return n
+}
+
+// SetTotal sets items' totals and cart's total
+func (c *Cart) SetTotal() {
+	c.Total = Money{}
+	for i := range c.Items {
+		c.Items[i].SetTotal()
+		c.Total = c.Total.Add(c.Items[i].Total)
+	}
+}
+
+type CartItem struct {
+	Model
+	CartID    int `json:"-"`
+	ProductID int `json:"productId"`
+	Qty       int `json:"qty"`
+	// Price is the unit price with the options' price deltas
+	Price   Money          `json:"price"`
+	Total   Money          `json:"total" gorm:"-"`
+	Options ProductOptions `json:"options" gorm:"type:text"`
+
+	Product *Product `json:"product,omitempty"`
+}
+
+func (ci *CartItem) GetTotal() Money {
+	return ci.Price.Mul(ci.Qty)
+}
+
+func (ci *CartItem) SetTotal() {
+	ci.Total = ci.GetTotal()
+}
@@ -0,0 +1,221 @@
+package app
+
+import (
+	"app/interfaces/errs"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrCouponUsedUp is returned when the coupon's usage limit is reached
+	ErrCouponUsedUp = errs.Conflict("coupon usage limit is reached")
+	// ErrCouponUserLimit is returned when the user's usage limit of the coupon is reached
+	ErrCouponUserLimit = errs.Conflict("you've reached the usage limit of the coupon")
+)
+
+// CouponType is the discount rule of a coupon
+type CouponType string
+
+// Coupon types
+const (
+	// CouponPercentage discounts Percent of eligible products
+	CouponPercentage CouponType = "percentage"
+	// CouponFixed discounts Amount from eligible products
+	CouponFixed CouponType = "fixed"
+	// CouponFreeDelivery waives the delivery fee
+	CouponFreeDelivery CouponType = "free_delivery"
+	// CouponBuyXGetY makes GetQty units free for every BuyQty units of eligible products,
+	// the cheapest units are free
+	CouponBuyXGetY CouponType = "buy_x_get_y"
+)
+
+// Coupon is a promotion which is applied to an order by its code.
+// A coupon applies to all products unless it's scoped to products or categories.
+type Coupon struct {
+	Model
+	Code        string     `json:"code" gorm:"unique_index"`
+	Description string     `json:"description"`
+	Type        CouponType `json:"type"`
+	Percent     int        `json:"percent"`
+	Amount      Money      `json:"amount"`
+	BuyQty      int        `json:"buyQty"`
+	GetQty      int        `json:"getQty"`
+	// MinBasket is the minimum total of the order's products
+	MinBasket Money      `json:"minBasket"`
+	StartsAt  *time.Time `json:"startsAt"`
+	EndsAt    *time.Time `json:"endsAt"`
+	// UsageLimit is the maximum number of orders which can use the coupon, zero is unlimited
+	UsageLimit int `json:"usageLimit"`
+	// PerUserLimit is the maximum number of orders of a user which can use the coupon, zero is unlimited
+	PerUserLimit int  `json:"perUserLimit"`
+	UsedCount    int  `json:"usedCount"`
+	IsActive     bool `json:"isActive"`
+
+	Products   []Product  `json:"products,omitempty" gorm:"many2many:pivot_coupon_product"`
+	Categories []Category `json:"categories,omitempty" gorm:"many2many:pivot_coupon_category"`
+}
+
+// CouponUsage records an order which used a coupon
+type CouponUsage struct {
+	Model
+	CouponID int `gorm:"index"`
+	UserID   int `gorm:"index"`
+	OrderID  int
+}
+
+// IsValidAt checks the coupon is active and in its validity window
+func (c *Coupon) IsValidAt(t time.Time) bool {
+	if !c.IsActive {
+		return false
+	}
+	if c.StartsAt != nil && t.Before(*c.StartsAt) {
+		return false
+	}
+	if c.EndsAt != nil && !t.Before(*c.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// IsScoped checks the coupon is limited to some products or categories
+func (c *Coupon) IsScoped() bool {
+	return len(c.Products) > 0 || len(c.Categories) > 0
+}
+
+// Covers checks the coupon applies to the product which is in the categories
+func (c *Coupon) Covers(productID int, categoryIDs []int) bool {
+	if !c.IsScoped() {
+		return true
+	}
+	for _, p := range c.Products {
+		if p.ID == productID {
+			return true
+		}
+	}
+	for _, cat := range c.Categories {
+		for _, id := range categoryIDs {
+			if cat.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Apply sets discounts of the order's eligible products and the order's discount.
+// It reports false if the coupon doesn't apply to any product of the order.
+// Discounts are applied to products' prices before the tax is calculated.
+func (c *Coupon) Apply(o *Order, eligible func(*OrderProduct) bool) bool {
+	var lines []*OrderProduct
+	for i := range o.Products {
+		o.Products[i].Discount = Money{}
+		if eligible(&o.Products[i]) {
+			lines = append(lines, &o.Products[i])
+		}
+	}
+	if len(lines) == 0 {
+		return false
+	}
+
+	switch c.Type {
+	case CouponPercentage:
+		for _, op := range lines {
+			op.Discount = op.Price.Mul(op.Qty).MulRatio(int64(c.Percent), 100)
+		}
+	case CouponFixed:
+		c.allocate(lines)
+	case CouponFreeDelivery:
+		if o.DeliveryFee.IsZero() {
+			return false
+		}
+	case CouponBuyXGetY:
+		if !c.freeUnits(lines) {
+			return false
+		}
+	default:
+		return false
+	}
+
+	o.Discount = Money{}
+	for _, op := range o.Products {
+		o.Discount = o.Discount.Add(op.Discount)
+	}
+	if c.Type == CouponFreeDelivery {
+		o.Discount = o.Discount.Add(o.DeliveryFee)
+		o.DeliveryFee = Money{}
+	}
+	o.CouponID = c.ID
+	o.CouponCode = c.Code
+	return true
+}
+
+// allocate distributes the fixed amount to the lines by their totals,
+// the rounding difference is given to the last line
+func (c *Coupon) allocate(lines []*OrderProduct) {
+	var base Money
+	for _, op := range lines {
+		base = base.Add(op.Price.Mul(op.Qty))
+	}
+	if base.Amount <= 0 {
+		return
+	}
+
+	amount := c.Amount
+	if amount.Cmp(base) > 0 {
+		amount = base
+	}
+
+	rest := amount
+	for i, op := range lines {
+		if i == len(lines)-1 {
+			op.Discount = rest
+			break
+		}
+		op.Discount = op.Price.Mul(op.Qty).MulRatio(amount.Amount, base.Amount)
+		rest = rest.Sub(op.Discount)
+	}
+}
+
+// freeUnits makes the cheapest GetQty units of every BuyQty+GetQty units free,
+// it reports false if there aren't enough units.
+// Units are ordered by price, free units of a line are counted by its position in the order.
+func (c *Coupon) freeUnits(lines []*OrderProduct) bool {
+	group := c.BuyQty + c.GetQty
+	if group <= 0 || c.GetQty <= 0 {
+		return false
+	}
+
+	sorted := make([]*OrderProduct, len(lines))
+	copy(sorted, lines)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Price.Cmp(sorted[j].Price) > 0 })
+
+	var total int
+	for _, op := range sorted {
+		total += op.Qty
+	}
+	if total < group {
+		return false
+	}
+
+	// free counts the free units among the first n units, units of the last partial group aren't free
+	limit := total / group * group
+	free := func(n int) int {
+		if n > limit {
+			n = limit
+		}
+		f := n / group * c.GetQty
+		if r := n%group - c.BuyQty; r > 0 {
+			f += r
+		}
+		return f
+	}
+
+	pos := 0
+	for _, op := range sorted {
+		if n := free(pos+op.Qty) - free(pos); n > 0 {
+			op.Discount = op.Discount.Add(op.Price.Mul(n))
+		}
+		pos += op.Qty
+	}
+	return true
+}
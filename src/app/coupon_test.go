@@ -0,0 +1,122 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func testCouponOrder() *Order {
+	return &Order{PricesIncludeTax: true, Products: []OrderProduct{
+		{ProductID: 1, Qty: 3, Price: MustParseMoney("10")},
+		{ProductID: 2, Qty: 1, Price: MustParseMoney("15")},
+	}}
+}
+
+func allProducts(*OrderProduct) bool { return true }
+
+func TestCoupon_ApplyPercentage(t *testing.T) {
+	c := &Coupon{Model: Model{ID: 7}, Code: "TEN", Type: CouponPercentage, Percent: 10, Products: []Product{{Model: Model{ID: 1}}}}
+	o := testCouponOrder()
+	eligible := func(op *OrderProduct) bool { return c.Covers(op.ProductID, nil) }
+
+	if !c.Apply(o, eligible) {
+		t.Fatal("expected coupon to apply")
+	}
+	if o.Products[0].Discount != MustParseMoney("3") || !o.Products[1].Discount.IsZero() {
+		t.Errorf("expected only the scoped product to be discounted got %s, %s", o.Products[0].Discount, o.Products[1].Discount)
+	}
+	if o.Discount != MustParseMoney("3") || o.CouponID != 7 || o.CouponCode != "TEN" {
+		t.Errorf("unexpected order discount %s %d %s", o.Discount, o.CouponID, o.CouponCode)
+	}
+
+	o.SetTotal()
+	if o.Total != MustParseMoney("42") || o.Products[0].Total != MustParseMoney("27") {
+		t.Errorf("expected discount to be deducted from the totals got %s", o.Total)
+	}
+}
+
+func TestCoupon_ApplyFixed(t *testing.T) {
+	c := &Coupon{Type: CouponFixed, Amount: MustParseMoney("10")}
+	o := testCouponOrder()
+	if !c.Apply(o, allProducts) {
+		t.Fatal("expected coupon to apply")
+	}
+	// 10 is distributed by 30/45 and 15/45
+	if o.Products[0].Discount != MustParseMoney("6.67") || o.Products[1].Discount != MustParseMoney("3.33") {
+		t.Errorf("unexpected allocation %s, %s", o.Products[0].Discount, o.Products[1].Discount)
+	}
+
+	c.Amount = MustParseMoney("100")
+	c.Apply(o, allProducts)
+	if o.Discount != MustParseMoney("45") {
+		t.Errorf("expected discount to be limited by the basket got %s", o.Discount)
+	}
+}
+
+func TestCoupon_ApplyFreeDelivery(t *testing.T) {
+	c := &Coupon{Type: CouponFreeDelivery}
+	o := testCouponOrder()
+	if c.Apply(o, allProducts) {
+		t.Error("expected coupon not to apply without a delivery fee")
+	}
+
+	o.DeliveryFee = MustParseMoney("7.50")
+	if !c.Apply(o, allProducts) {
+		t.Fatal("expected coupon to apply")
+	}
+	o.SetTotal()
+	if !o.DeliveryFee.IsZero() || o.Discount != MustParseMoney("7.50") || o.Total != MustParseMoney("45") {
+		t.Errorf("expected delivery fee to be waived got %s %s %s", o.DeliveryFee, o.Discount, o.Total)
+	}
+}
+
+func TestCoupon_ApplyBuyXGetY(t *testing.T) {
+	c := &Coupon{Type: CouponBuyXGetY, BuyQty: 2, GetQty: 1}
+	o := &Order{Products: []OrderProduct{
+		{ProductID: 1, Qty: 2, Price: MustParseMoney("10")},
+		{ProductID: 2, Qty: 3, Price: MustParseMoney("4")},
+	}}
+	if !c.Apply(o, allProducts) {
+		t.Fatal("expected coupon to apply")
+	}
+	// units 10, 10, 4 | 4, 4 : the cheapest unit of the only full group is free
+	if !o.Products[0].Discount.IsZero() || o.Products[1].Discount != MustParseMoney("4") {
+		t.Errorf("unexpected discounts %s, %s", o.Products[0].Discount, o.Products[1].Discount)
+	}
+
+	// units 10 x4 | 8 x2, 4 | 4 x3 : the cheapest unit of every full group is free
+	o = &Order{Products: []OrderProduct{
+		{ProductID: 1, Qty: 3, Price: MustParseMoney("4")},
+		{ProductID: 2, Qty: 4, Price: MustParseMoney("10")},
+		{ProductID: 3, Qty: 2, Price: MustParseMoney("8")},
+	}}
+	if !c.Apply(o, allProducts) {
+		t.Fatal("expected coupon to apply")
+	}
+	if o.Products[0].Discount != MustParseMoney("4") || o.Products[1].Discount != MustParseMoney("10") || o.Products[2].Discount != MustParseMoney("8") {
+		t.Errorf("unexpected discounts %s, %s, %s", o.Products[0].Discount, o.Products[1].Discount, o.Products[2].Discount)
+	}
+
+	o.Products = o.Products[:1]
+	o.Products[0].Qty = 2
+	if c.Apply(o, allProducts) {
+		t.Error("expected coupon not to apply without enough units")
+	}
+}
+
+func TestCoupon_IsValidAt(t *testing.T) {
+	now := time.Now()
+	start, end := now.Add(-time.Hour), now.Add(time.Hour)
+	c := &Coupon{IsActive: true, StartsAt: &start, EndsAt: &end}
+
+	if !c.IsValidAt(now) {
+		t.Error("expected coupon to be valid in its window")
+	}
+	if c.IsValidAt(start.Add(-time.Second)) || c.IsValidAt(end) {
+		t.Error("expected coupon to be invalid out of its window")
+	}
+	c.IsActive = false
+	if c.IsValidAt(now) {
+		t.Error("expected inactive coupon to be invalid")
+	}
+}
@@ -5,6 +5,8 @@ package app
 import (
 	"net/http"
 	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
 )
 
 // ErrorHandler interface
@@ -17,6 +19,59 @@ type MailSender interface {
 	Send(to []string, subject string, body []byte) error
 }
 
+// MultipartMailSender interface sends mails that have both text and html parts
+type MultipartMailSender interface {
+	MailSender
+	SendMultipart(to []string, subject string, text, html []byte) error
+}
+
+// Attempt is failed attempts of a key like an ip address or an email address
+type Attempt struct {
+	Failures    int
+	LastFailure time.Time
+}
+
+// AttemptStore keeps failed attempts. A shared store is required
+// when the app runs on several instances.
+type AttemptStore interface {
+	// Attempt gets key's failed attempts, zero attempt if there isn't any
+	Attempt(key string) (Attempt, error)
+	// Fail increases key's failures atomically, the attempt expires after ttl
+	Fail(key string, at time.Time, ttl time.Duration) (Attempt, error)
+	// Reset removes key's failed attempts
+	Reset(key string) error
+}
+
+// TokenSigner signs tokens and finds the verification key of a token
+type TokenSigner interface {
+	Sign(jwt.Claims) (string, error)
+	Keyfunc(*jwt.Token) (interface{}, error)
+}
+
+// KeySet is a token signer which publishes its public keys
+type KeySet interface {
+	TokenSigner
+	JWKS() *JWKSet
+}
+
+// JWK is a public JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet is a JSON Web Key Set
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
 // CDNUploader interface
 type CDNUploader interface {
 	Upload(string) (*http.Response, error)
@@ -52,7 +107,7 @@ type Transaction struct {
 	UserID    int              `json:"-"`
 	AccountID int              `json:"-"`
 	Account   Account          `json:"account"`
-	Amount    float32          `json:"amount"`
+	Amount    Money            `json:"amount"`
 	Desc      string           `json:"desc"`
 	Tags      []TransactionTag `json:"tags,omitempty" gorm:"many2many:pivot_transaction_tag;"`
 	Date      time.Time        `json:"date"`
@@ -0,0 +1,71 @@
+package infra
+
+import (
+	"app"
+	"sync"
+	"time"
+)
+
+// NewMemoryAttemptStore creates an attempt store which keeps attempts in memory.
+// It's only for single instance deployments, attempts are lost on restart.
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{attempts: make(map[string]*memoryAttempt)}
+}
+
+type memoryAttempt struct {
+	app.Attempt
+	expiresAt time.Time
+}
+
+// MemoryAttemptStore is in memory app.AttemptStore
+type MemoryAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]*memoryAttempt
+}
+
+func (s *MemoryAttemptStore) Attempt(key string) (app.Attempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.attempts[key]
+	if !ok || !time.Now().Before(a.expiresAt) {
+		return app.Attempt{}, nil
+	}
+	return a.Attempt, nil
+}
+
+func (s *MemoryAttemptStore) Fail(key string, at time.Time, ttl time.Duration) (app.Attempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.attempts[key]
+	if !ok || !at.Before(a.expiresAt) {
+		a = new(memoryAttempt)
+		s.attempts[key] = a
+	}
+	a.Failures++
+	a.LastFailure = at
+	a.expiresAt = at.Add(ttl)
+	return a.Attempt, nil
+}
+
+func (s *MemoryAttemptStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+// PurgeExpired removes expired attempts
+func (s *MemoryAttemptStore) PurgeExpired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, a := range s.attempts {
+		if !now.Before(a.expiresAt) {
+			delete(s.attempts, k)
+		}
+	}
+}
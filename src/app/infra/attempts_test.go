@@ -0,0 +1,46 @@
+package infra
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryAttemptStore(t *testing.T) {
+	s := NewMemoryAttemptStore()
+	now := time.Now()
+
+	for i := 1; i <= 3; i++ {
+		a, err := s.Fail("login:ip:10.0.0.1", now, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.Failures != i || !a.LastFailure.Equal(now) {
+			t.Errorf("expected %d failures got %+v", i, a)
+		}
+	}
+
+	if a, _ := s.Attempt("login:ip:10.0.0.1"); a.Failures != 3 {
+		t.Errorf("expected 3 failures got %d", a.Failures)
+	}
+	if a, _ := s.Attempt("login:ip:10.0.0.2"); a.Failures != 0 {
+		t.Errorf("expected no failures got %d", a.Failures)
+	}
+
+	// failures are forgotten after ttl
+	if a, _ := s.Fail("login:ip:10.0.0.1", now.Add(time.Minute), time.Minute); a.Failures != 1 {
+		t.Errorf("expected failures to be reset after ttl got %d", a.Failures)
+	}
+
+	if err := s.Reset("login:ip:10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := s.Attempt("login:ip:10.0.0.1"); a.Failures != 0 {
+		t.Errorf("expected no failures after reset got %d", a.Failures)
+	}
+
+	s.Fail("expired", now.Add(-time.Hour), time.Minute)
+	s.PurgeExpired()
+	if len(s.attempts) != 0 {
+		t.Errorf("expected expired attempts to be purged got %d", len(s.attempts))
+	}
+}
@@ -0,0 +1,208 @@
+package infra
+
+import (
+	"app"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// Key is a JWT signing and verification key
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	// SignKey is []byte for HMAC, *rsa.PrivateKey or *ecdsa.PrivateKey.
+	// It's nil for verification only keys.
+	SignKey interface{}
+	// VerifyKey is []byte for HMAC, *rsa.PublicKey or *ecdsa.PublicKey
+	VerifyKey interface{}
+}
+
+// NewHMACKey creates HS256 key
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodHS256, SignKey: secret, VerifyKey: secret}
+}
+
+// NewRSAKey creates RS256 key, the private key can be nil for verification only keys.
+// If the id is empty the key's thumbprint is used.
+func NewRSAKey(id string, priv *rsa.PrivateKey, pub *rsa.PublicKey) *Key {
+	if priv != nil {
+		pub = &priv.PublicKey
+	}
+	k := &Key{ID: id, Method: jwt.SigningMethodRS256, VerifyKey: pub}
+	if priv != nil {
+		k.SignKey = priv
+	}
+	if k.ID == "" {
+		k.ID = thumbprint(k.jwk())
+	}
+	return k
+}
+
+// NewECKey creates ES256, ES384 or ES512 key by the curve, the private key can be nil for verification only keys.
+// If the id is empty the key's thumbprint is used.
+func NewECKey(id string, priv *ecdsa.PrivateKey, pub *ecdsa.PublicKey) (*Key, error) {
+	if priv != nil {
+		pub = &priv.PublicKey
+	}
+
+	var m jwt.SigningMethod
+	switch pub.Curve.Params().BitSize {
+	case 256:
+		m = jwt.SigningMethodES256
+	case 384:
+		m = jwt.SigningMethodES384
+	case 521:
+		m = jwt.SigningMethodES512
+	default:
+		return nil, fmt.Errorf("unsupported curve: %s", pub.Curve.Params().Name)
+	}
+
+	k := &Key{ID: id, Method: m, VerifyKey: pub}
+	if priv != nil {
+		k.SignKey = priv
+	}
+	if k.ID == "" {
+		k.ID = thumbprint(k.jwk())
+	}
+	return k, nil
+}
+
+// ParseKeyPEM parses RSA or EC private or public key in PEM format
+func ParseKeyPEM(id string, data []byte) (*Key, error) {
+	if priv, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		return NewRSAKey(id, priv, nil), nil
+	}
+	if priv, err := jwt.ParseECPrivateKeyFromPEM(data); err == nil {
+		return NewECKey(id, priv, nil)
+	}
+	if pub, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return NewRSAKey(id, nil, pub), nil
+	}
+	if pub, err := jwt.ParseECPublicKeyFromPEM(data); err == nil {
+		return NewECKey(id, nil, pub)
+	}
+	return nil, fmt.Errorf("key can't parsed, it must be a RSA or EC key in PEM format")
+}
+
+// jwk returns public JWK of the key, HMAC keys haven't one
+func (k *Key) jwk() *app.JWK {
+	switch pub := k.VerifyKey.(type) {
+	case *rsa.PublicKey:
+		return &app.JWK{
+			Kty: "RSA",
+			Kid: k.ID,
+			Alg: k.Method.Alg(),
+			Use: "sig",
+			N:   b64(pub.N.Bytes()),
+			E:   b64(big.NewInt(int64(pub.E)).Bytes()),
+		}
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		return &app.JWK{
+			Kty: "EC",
+			Kid: k.ID,
+			Alg: k.Method.Alg(),
+			Use: "sig",
+			Crv: pub.Curve.Params().Name,
+			X:   b64(pad(pub.X.Bytes(), size)),
+			Y:   b64(pad(pub.Y.Bytes(), size)),
+		}
+	}
+	return nil
+}
+
+// NewKeySet creates a key set which signs tokens with the signing key
+// and verifies tokens with any key of the set by the token's kid header.
+// Old keys are kept as verification keys while rotating.
+func NewKeySet(signing *Key, verify ...*Key) (*KeySet, error) {
+	if signing.SignKey == nil {
+		return nil, fmt.Errorf("signing key %q hasn't a private key", signing.ID)
+	}
+
+	ks := &KeySet{signing: signing, keys: make(map[string]*Key)}
+	for _, k := range append([]*Key{signing}, verify...) {
+		if _, ok := ks.keys[k.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id: %q", k.ID)
+		}
+		ks.keys[k.ID] = k
+		ks.ids = append(ks.ids, k.ID)
+	}
+	return ks, nil
+}
+
+// KeySet signs and verifies JWTs with rotating keys
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+	ids     []string
+}
+
+// Sign signs the claims with the signing key and sets the kid header
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	t := jwt.NewWithClaims(ks.signing.Method, claims)
+	t.Header["kid"] = ks.signing.ID
+	return t.SignedString(ks.signing.SignKey)
+}
+
+// Keyfunc finds the verification key by the token's kid header.
+// Tokens without kid are verified with the signing key.
+// The token's algorithm must be the key's algorithm.
+func (ks *KeySet) Keyfunc(t *jwt.Token) (interface{}, error) {
+	k := ks.signing
+	if kid, ok := t.Header["kid"]; ok {
+		id, _ := kid.(string)
+		if k, ok = ks.keys[id]; !ok {
+			return nil, fmt.Errorf("unknown key id: %v", kid)
+		}
+	}
+
+	if t.Method.Alg() != k.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	}
+	return k.VerifyKey, nil
+}
+
+// JWKS returns public keys of the set, HMAC keys are never published
+func (ks *KeySet) JWKS() *app.JWKSet {
+	set := &app.JWKSet{Keys: []app.JWK{}}
+	for _, id := range ks.ids {
+		if jwk := ks.keys[id].jwk(); jwk != nil {
+			set.Keys = append(set.Keys, *jwk)
+		}
+	}
+	return set
+}
+
+// thumbprint computes JWK thumbprint (RFC 7638)
+func thumbprint(k *app.JWK) string {
+	var m map[string]string
+	if k.Kty == "RSA" {
+		m = map[string]string{"e": k.E, "kty": k.Kty, "n": k.N}
+	} else {
+		m = map[string]string{"crv": k.Crv, "kty": k.Kty, "x": k.X, "y": k.Y}
+	}
+	// json encodes map keys in lexicographic order as required
+	b, _ := json.Marshal(m)
+	h := sha256.Sum256(b)
+	return b64(h[:])
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func pad(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	p := make([]byte, size)
+	copy(p[size-len(b):], b)
+	return p
+}
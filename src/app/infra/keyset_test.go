@@ -0,0 +1,203 @@
+package infra
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+func testKeys(t *testing.T) (rsaKey, ecKey, hmacKey *Key) {
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err = NewECKey("", ek, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewRSAKey("", rk, nil), ecKey, NewHMACKey("secret", []byte("secret"))
+}
+
+func claims() jwt.MapClaims {
+	return jwt.MapClaims{"userID": "1", "exp": time.Now().Add(time.Minute).Unix()}
+}
+
+func TestKeySet_SignAndVerify(t *testing.T) {
+	rsaKey, ecKey, hmacKey := testKeys(t)
+
+	for _, k := range []*Key{rsaKey, ecKey, hmacKey} {
+		ks, err := NewKeySet(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s, err := ks.Sign(claims())
+		if err != nil {
+			t.Fatalf("%s: %v", k.Method.Alg(), err)
+		}
+
+		token, err := jwt.Parse(s, ks.Keyfunc)
+		if err != nil {
+			t.Fatalf("%s: expected token to be valid got %v", k.Method.Alg(), err)
+		}
+		if token.Header["kid"] != k.ID {
+			t.Errorf("%s: expected kid %q got %v", k.Method.Alg(), k.ID, token.Header["kid"])
+		}
+		if token.Method.Alg() != k.Method.Alg() {
+			t.Errorf("expected alg %s got %s", k.Method.Alg(), token.Method.Alg())
+		}
+	}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	rsaKey, ecKey, _ := testKeys(t)
+
+	old, err := NewKeySet(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := old.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the old key is kept only for verification
+	retired := NewRSAKey(rsaKey.ID, nil, rsaKey.VerifyKey.(*rsa.PublicKey))
+	ks, err := NewKeySet(ecKey, retired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.Parse(s, ks.Keyfunc); err != nil {
+		t.Errorf("expected token of the retired key to be valid got %v", err)
+	}
+
+	// after the old key is removed its tokens are rejected
+	ks, err = NewKeySet(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.Parse(s, ks.Keyfunc); err == nil {
+		t.Error("expected token of the removed key to be invalid")
+	}
+
+	if _, err := NewKeySet(retired); err == nil {
+		t.Error("expected verification only key can't be the signing key")
+	}
+	if _, err := NewKeySet(ecKey, ecKey); err == nil {
+		t.Error("expected duplicate key ids to be rejected")
+	}
+}
+
+func TestKeySet_AlgorithmConfusion(t *testing.T) {
+	rsaKey, _, _ := testKeys(t)
+
+	ks, err := NewKeySet(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a HS256 token signed with the public key as secret
+	pub, err := x509.MarshalPKIXPublicKey(rsaKey.VerifyKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims())
+	forged.Header["kid"] = rsaKey.ID
+	s, err := forged.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := jwt.Parse(s, ks.Keyfunc); err == nil {
+		t.Error("expected token with wrong algorithm to be invalid")
+	}
+}
+
+func TestKeySet_JWKS(t *testing.T) {
+	rsaKey, ecKey, hmacKey := testKeys(t)
+
+	ks, err := NewKeySet(rsaKey, ecKey, hmacKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set := ks.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("expected 2 public keys got %d", len(set.Keys))
+	}
+
+	rk, ek := set.Keys[0], set.Keys[1]
+	if rk.Kty != "RSA" || rk.Kid != rsaKey.ID || rk.Alg != "RS256" || rk.N == "" || rk.E != "AQAB" {
+		t.Errorf("unexpected RSA key %+v", rk)
+	}
+	if ek.Kty != "EC" || ek.Kid != ecKey.ID || ek.Alg != "ES256" || ek.Crv != "P-256" || len(ek.X) != 43 || len(ek.Y) != 43 {
+		t.Errorf("unexpected EC key %+v", ek)
+	}
+}
+
+func TestParseKeyPEM(t *testing.T) {
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecDER, err := x509.MarshalECPrivateKey(ek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(&rk.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		block     *pem.Block
+		alg       string
+		isPrivate bool
+	}{
+		{&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rk)}, "RS256", true},
+		{&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER}, "ES384", true},
+		{&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}, "RS256", false},
+	}
+
+	for _, test := range tests {
+		k, err := ParseKeyPEM("", pem.EncodeToMemory(test.block))
+		if err != nil {
+			t.Fatalf("%s: %v", test.block.Type, err)
+		}
+		if k.Method.Alg() != test.alg {
+			t.Errorf("%s: expected alg %s got %s", test.block.Type, test.alg, k.Method.Alg())
+		}
+		if (k.SignKey != nil) != test.isPrivate {
+			t.Errorf("%s: expected private key %v", test.block.Type, test.isPrivate)
+		}
+		if k.ID == "" {
+			t.Errorf("%s: expected thumbprint as key id", test.block.Type)
+		}
+	}
+
+	// private and public keys have the same thumbprint
+	priv, _ := ParseKeyPEM("", pem.EncodeToMemory(tests[0].block))
+	pub, _ := ParseKeyPEM("", pem.EncodeToMemory(tests[2].block))
+	if priv.ID != pub.ID {
+		t.Errorf("expected same key id got %q and %q", priv.ID, pub.ID)
+	}
+
+	if _, err := ParseKeyPEM("", []byte("not a key")); err == nil {
+		t.Error("expected invalid key to be rejected")
+	}
+}
@@ -2,56 +2,77 @@ package providers
 
 import (
 	"app/infra/social"
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
 	"net/http"
+	"net/url"
 )
 
-func NewFacebook(token string) *facebook {
-	return &facebook{token}
+// FacebookURL is the base url of facebook graph api
+const FacebookURL = "https://graph.facebook.com/v2.7"
+
+// NewFacebook creates facebook provider, uses FacebookURL if the base url is empty.
+// Tokens which are issued to other apps than the app id are rejected,
+// all tokens are rejected if the app id or secret is empty.
+func NewFacebook(baseURL, appID, appSecret string) *facebook {
+	if baseURL == "" {
+		baseURL = FacebookURL
+	}
+	return &facebook{baseURL: baseURL, appID: appID, appSecret: appSecret}
 }
 
 type facebook struct {
-	accessToken string
+	baseURL   string
+	appID     string
+	appSecret string
+	Client    *http.Client
 }
 
-func (fb *facebook) GetUser() (*social.User, error) {
-	fields := "email,first_name,last_name"
-	baseURL := fmt.Sprintf("https://graph.facebook.com/v2.7/me/?access_token=%s&fields=%s", fb.accessToken, fields)
+func (fb *facebook) Name() string {
+	return "facebook"
+}
 
-	resp, err := http.Get(baseURL)
-	if err != nil {
-		return nil, err
+func (fb *facebook) GetUser(token string) (*social.User, error) {
+	if fb.appID == "" || fb.appSecret == "" {
+		return nil, unauthorized("facebook app isn't configured")
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
+	// the token is inspected by the app access token
+	var debug struct {
+		Data struct {
+			AppID   string `json:"app_id"`
+			UserID  string `json:"user_id"`
+			IsValid bool   `json:"is_valid"`
+		} `json:"data"`
+	}
+	dq := url.Values{"input_token": {token}, "access_token": {fb.appID + "|" + fb.appSecret}}
+	if err := getJSON(fb.Client, fb.baseURL+"/debug_token?"+dq.Encode(), nil, &debug); err != nil {
 		return nil, err
 	}
-
-	if resp.StatusCode > 299 || resp.StatusCode < 199 {
-		return nil, social.NewApiErr(resp.StatusCode, body)
+	if !debug.Data.IsValid || debug.Data.AppID != fb.appID {
+		return nil, unauthorized("token app %q isn't the app", debug.Data.AppID)
 	}
 
-	return fb.mapUser(body)
-}
+	q := url.Values{"access_token": {token}, "fields": {"id,email,first_name,last_name"}}
 
-func (fb *facebook) mapUser(body []byte) (*social.User, error) {
-	var u social.User
-	fbUser := struct {
+	var fbUser struct {
+		ID        string `json:"id"`
 		Email     string `json:"email"`
 		FirstName string `json:"first_name"`
 		LastName  string `json:"last_name"`
-	}{}
-	if err := json.Unmarshal(body, fbUser); err != nil {
+	}
+	if err := getJSON(fb.Client, fb.baseURL+"/me?"+q.Encode(), nil, &fbUser); err != nil {
 		return nil, err
 	}
 
-	u.Email = fbUser.Email
-	u.FirstName = fbUser.Email
-	u.LastName = fbUser.LastName
-	return &u, nil
+	if fbUser.ID != debug.Data.UserID {
+		return nil, unauthorized("token user %q isn't the user", debug.Data.UserID)
+	}
 
+	// facebook returns only confirmed email addresses
+	return &social.User{
+		ID:            fbUser.ID,
+		Email:         fbUser.Email,
+		EmailVerified: fbUser.Email != "",
+		FirstName:     fbUser.FirstName,
+		LastName:      fbUser.LastName,
+	}, nil
 }
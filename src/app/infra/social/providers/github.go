@@ -0,0 +1,86 @@
+package providers
+
+import (
+	"app/infra/social"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// GitHubURL is the base url of github api
+const GitHubURL = "https://api.github.com"
+
+// NewGitHub creates github provider, uses GitHubURL if the base url is empty.
+// Tokens which are issued to other apps than the oauth app are rejected,
+// all tokens are rejected if the client id or secret is empty.
+func NewGitHub(baseURL, clientID, clientSecret string) *github {
+	if baseURL == "" {
+		baseURL = GitHubURL
+	}
+	return &github{baseURL: baseURL, clientID: clientID, clientSecret: clientSecret}
+}
+
+type github struct {
+	baseURL      string
+	clientID     string
+	clientSecret string
+	Client       *http.Client
+}
+
+func (gh *github) Name() string {
+	return "github"
+}
+
+func (gh *github) GetUser(token string) (*social.User, error) {
+	if gh.clientID == "" || gh.clientSecret == "" {
+		return nil, unauthorized("github client isn't configured")
+	}
+
+	// the token is checked by the oauth app's credentials, github responds 404 for tokens of other apps
+	var check struct {
+		App struct {
+			ClientID string `json:"client_id"`
+		} `json:"app"`
+	}
+	checkURL := gh.baseURL + "/applications/" + url.PathEscape(gh.clientID) + "/token"
+	body := map[string]string{"access_token": token}
+	if err := postJSON(gh.Client, checkURL, basicAuth(gh.clientID, gh.clientSecret), body, &check); err != nil {
+		return nil, err
+	}
+	if check.App.ClientID != gh.clientID {
+		return nil, unauthorized("token client %q isn't the client", check.App.ClientID)
+	}
+
+	var ghUser struct {
+		ID    int    `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := getJSON(gh.Client, gh.baseURL+"/user", bearer(token), &ghUser); err != nil {
+		return nil, err
+	}
+
+	// public email of the profile may not be verified, so the primary email is used
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(gh.Client, gh.baseURL+"/user/emails", bearer(token), &emails); err != nil {
+		return nil, err
+	}
+
+	u := &social.User{ID: strconv.Itoa(ghUser.ID)}
+	u.FirstName, u.LastName = splitName(ghUser.Name)
+	if u.FirstName == "" {
+		u.FirstName = ghUser.Login
+	}
+	for _, e := range emails {
+		if e.Primary {
+			u.Email = e.Email
+			u.EmailVerified = e.Verified
+			break
+		}
+	}
+	return u, nil
+}
@@ -0,0 +1,67 @@
+package providers
+
+import (
+	"app/infra/social"
+	"net/http"
+	"net/url"
+)
+
+// GoogleURL is the base url of google apis
+const GoogleURL = "https://www.googleapis.com"
+
+// NewGoogle creates google provider, uses GoogleURL if the base url is empty.
+// Tokens which are issued to other clients than the client id are rejected,
+// all tokens are rejected if the client id is empty.
+func NewGoogle(baseURL, clientID string) *google {
+	if baseURL == "" {
+		baseURL = GoogleURL
+	}
+	return &google{baseURL: baseURL, clientID: clientID}
+}
+
+type google struct {
+	baseURL  string
+	clientID string
+	Client   *http.Client
+}
+
+func (g *google) Name() string {
+	return "google"
+}
+
+func (g *google) GetUser(token string) (*social.User, error) {
+	if g.clientID == "" {
+		return nil, unauthorized("google client id isn't configured")
+	}
+
+	var info struct {
+		Aud string `json:"aud"`
+	}
+	q := url.Values{"access_token": {token}}
+	if err := getJSON(g.Client, g.baseURL+"/oauth2/v3/tokeninfo?"+q.Encode(), nil, &info); err != nil {
+		return nil, err
+	}
+	if info.Aud != g.clientID {
+		return nil, unauthorized("token audience %q isn't the client", info.Aud)
+	}
+
+	// OpenID Connect userinfo
+	var gUser struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		GivenName     string `json:"given_name"`
+		FamilyName    string `json:"family_name"`
+	}
+	if err := getJSON(g.Client, g.baseURL+"/oauth2/v3/userinfo", bearer(token), &gUser); err != nil {
+		return nil, err
+	}
+
+	return &social.User{
+		ID:            gUser.Sub,
+		Email:         gUser.Email,
+		EmailVerified: gUser.EmailVerified,
+		FirstName:     gUser.GivenName,
+		LastName:      gUser.FamilyName,
+	}, nil
+}
@@ -1 +1,91 @@
+// Package providers implements social login providers
 package providers
+
+import (
+	"app/infra/social"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultClient is used by providers which haven't a client
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// getJSON gets the url with given headers and decodes the response to v.
+// Responses which aren't successful are returned as social.ApiErr.
+func getJSON(c *http.Client, url string, header http.Header, v interface{}) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	return doJSON(c, req, header, v)
+}
+
+// postJSON posts body as json to the url with given headers and decodes the response to v
+func postJSON(c *http.Client, url string, header http.Header, body, v interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doJSON(c, req, header, v)
+}
+
+func doJSON(c *http.Client, req *http.Request, header http.Header, v interface{}) error {
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Accept", "application/json")
+
+	if c == nil {
+		c = defaultClient
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode > 299 || resp.StatusCode < 199 {
+		return social.NewApiErr(resp.StatusCode, body)
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// splitName splits full name to first and last name
+func splitName(name string) (string, string) {
+	name = strings.TrimSpace(name)
+	i := strings.LastIndex(name, " ")
+	if i < 0 {
+		return name, ""
+	}
+	return strings.TrimSpace(name[:i]), name[i+1:]
+}
+
+func bearer(token string) http.Header {
+	return http.Header{"Authorization": {"Bearer " + token}}
+}
+
+func basicAuth(user, password string) http.Header {
+	return http.Header{"Authorization": {"Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))}}
+}
+
+// unauthorized creates the error of a token which isn't accepted
+func unauthorized(format string, args ...interface{}) error {
+	return social.NewApiErr(http.StatusUnauthorized, []byte(fmt.Sprintf(format, args...)))
+}
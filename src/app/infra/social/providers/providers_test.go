@@ -0,0 +1,148 @@
+package providers
+
+import (
+	"app/infra/social"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newAPI creates a stand-in api which responds given bodies by path for the valid token.
+// Requests having basic auth are accepted only with the client-1 credentials.
+func newAPI(t *testing.T, bodies map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); ok && (id != "client-1" || secret != "secret") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		token := r.URL.Query().Get("input_token")
+		if token == "" {
+			token = r.URL.Query().Get("access_token")
+		}
+		if token == "" {
+			fmt.Sscanf(r.Header.Get("Authorization"), "Bearer %s", &token)
+		}
+		if r.Method == "POST" {
+			var body struct {
+				AccessToken string `json:"access_token"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			token = body.AccessToken
+		}
+		if token != "good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid token"}`)
+			return
+		}
+
+		body, ok := bodies[r.URL.Path]
+		if !ok {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+}
+
+func TestProviders_GetUser(t *testing.T) {
+	fb := newAPI(t, map[string]string{
+		"/debug_token": `{"data":{"app_id":"app-1","user_id":"10","is_valid":true}}`,
+		"/me":          `{"id":"10","email":"mark@facebook.com","first_name":"Mark","last_name":"Zuckerberg"}`,
+	})
+	defer fb.Close()
+
+	g := newAPI(t, map[string]string{
+		"/oauth2/v3/tokeninfo": `{"aud":"client-1"}`,
+		"/oauth2/v3/userinfo":  `{"sub":"20","email":"larry@gmail.com","email_verified":true,"given_name":"Larry","family_name":"Page"}`,
+	})
+	defer g.Close()
+
+	gh := newAPI(t, map[string]string{
+		"/applications/client-1/token": `{"app":{"client_id":"client-1"}}`,
+		"/user":                        `{"id":30,"login":"defunkt","name":"Chris Wanstrath","email":"public@example.com"}`,
+		"/user/emails":                 `[{"email":"other@example.com","primary":false,"verified":true},{"email":"chris@github.com","primary":true,"verified":true}]`,
+	})
+	defer gh.Close()
+
+	var tests = []struct {
+		p        social.Provider
+		expected social.User
+	}{
+		{NewFacebook(fb.URL, "app-1", "secret"), social.User{ID: "10", Email: "mark@facebook.com", EmailVerified: true, FirstName: "Mark", LastName: "Zuckerberg"}},
+		{NewGoogle(g.URL, "client-1"), social.User{ID: "20", Email: "larry@gmail.com", EmailVerified: true, FirstName: "Larry", LastName: "Page"}},
+		{NewGitHub(gh.URL, "client-1", "secret"), social.User{ID: "30", Email: "chris@github.com", EmailVerified: true, FirstName: "Chris", LastName: "Wanstrath"}},
+	}
+
+	for _, test := range tests {
+		u, err := test.p.GetUser("good")
+		if err != nil {
+			t.Errorf("%s: %v", test.p.Name(), err)
+			continue
+		}
+		if *u != test.expected {
+			t.Errorf("%s: expected user %+v got %+v", test.p.Name(), test.expected, *u)
+		}
+
+		_, err = test.p.GetUser("bad")
+		if _, ok := err.(*social.ApiErr); !ok {
+			t.Errorf("%s: expected api error for invalid token got %v", test.p.Name(), err)
+		}
+	}
+
+	// tokens issued to other clients are rejected
+	if _, err := NewGoogle(g.URL, "client-2").GetUser("good"); err == nil {
+		t.Error("google: expected token of other client to be rejected")
+	}
+	if _, err := NewGoogle(g.URL, "").GetUser("good"); err == nil {
+		t.Error("google: expected tokens to be rejected without a client id")
+	}
+	if _, err := NewFacebook(fb.URL, "app-2", "secret").GetUser("good"); err == nil {
+		t.Error("facebook: expected token of other app to be rejected")
+	}
+	if _, err := NewFacebook(fb.URL, "", "").GetUser("good"); err == nil {
+		t.Error("facebook: expected tokens to be rejected without an app")
+	}
+	if _, err := NewGitHub(gh.URL, "client-1", "bad secret").GetUser("good"); err == nil {
+		t.Error("github: expected token to be rejected with wrong client credentials")
+	}
+	if _, err := NewGitHub(gh.URL, "", "").GetUser("good"); err == nil {
+		t.Error("github: expected tokens to be rejected without a client")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := social.NewRegistry(NewGitHub("", "", ""), NewFacebook("", "", ""))
+	r.Register(NewGoogle("", ""))
+
+	if names := fmt.Sprint(r.Names()); names != "[facebook github google]" {
+		t.Errorf("expected providers [facebook github google] got %s", names)
+	}
+	if _, ok := r.Get("github"); !ok {
+		t.Error("expected github provider")
+	}
+	if _, ok := r.Get("myspace"); ok {
+		t.Error("expected unknown provider not found")
+	}
+}
+
+func TestSplitName(t *testing.T) {
+	var tests = []struct {
+		name, first, last string
+	}{
+		{"", "", ""},
+		{"Linus", "Linus", ""},
+		{"Linus Torvalds", "Linus", "Torvalds"},
+		{" Guido van Rossum ", "Guido van", "Rossum"},
+	}
+
+	for _, test := range tests {
+		first, last := splitName(test.name)
+		if first != test.first || last != test.last {
+			t.Errorf("splitName(%q) expected %q, %q got %q, %q", test.name, test.first, test.last, first, last)
+		}
+	}
+}
@@ -1,17 +1,55 @@
 package social
 
-func New(p Provider) Provider {
-	return p
-}
+import "sort"
 
+// Provider gets the user of an access token from a social login provider
 type Provider interface {
-	GetUser() (*User, error)
+	Name() string
+	GetUser(token string) (*User, error)
 }
 
+// User is the provider's user. ID is the user's unique id on the provider.
 type User struct {
-	Email     string
-	FirstName string
-	LastName  string
+	ID            string
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+}
+
+// NewRegistry creates a registry that has given providers
+func NewRegistry(ps ...Provider) *Registry {
+	r := &Registry{providers: make(map[string]Provider)}
+	for _, p := range ps {
+		r.Register(p)
+	}
+	return r
+}
+
+// Registry holds the enabled providers by their names
+type Registry struct {
+	providers map[string]Provider
+}
+
+// Register adds the provider, replaces the provider which has same name
+func (r *Registry) Register(p Provider) {
+	r.providers[p.Name()] = p
+}
+
+// Get gets the provider by name
+func (r *Registry) Get(name string) (Provider, bool) {
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+// Names gets enabled providers' names in order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for n := range r.providers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
 }
 
 func NewApiErr(statusCode int, body []byte) *ApiErr {
@@ -0,0 +1,32 @@
+// Package socialtest provides a social login provider for tests
+package socialtest
+
+import (
+	"app/infra/social"
+	"net/http"
+)
+
+// Token is the only valid token of the provider
+const Token = "valid token"
+
+// NewProvider creates a provider which returns the user for Token
+func NewProvider(name string, u *social.User) social.Provider {
+	return &provider{name, u}
+}
+
+type provider struct {
+	name string
+	user *social.User
+}
+
+func (p *provider) Name() string {
+	return p.name
+}
+
+func (p *provider) GetUser(token string) (*social.User, error) {
+	if token != Token {
+		return nil, social.NewApiErr(http.StatusUnauthorized, []byte("invalid token"))
+	}
+	u := *p.user
+	return &u, nil
+}
@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"app"
+	"database/sql"
 	"fmt"
 	"math/rand"
 	"time"
@@ -12,7 +13,7 @@ import (
 
 // InitDB creates tables
 func InitDB(db *gorm.DB) error {
-	return db.Set("gorm:table_options", "CHARSET=utf8").AutoMigrate(
+	err := db.Set("gorm:table_options", "CHARSET=utf8").AutoMigrate(
 		&app.User{},
 		&app.Address{},
 		&app.Product{},
@@ -24,7 +25,113 @@ func InitDB(db *gorm.DB) error {
 		&app.OrderAddress{},
 		&app.OrderStatus{},
 		&app.PaymentMethod{},
+		&app.Cart{},
+		&app.CartItem{},
+		&app.Session{},
+		&app.RevokedToken{},
+		&app.SocialIdentity{},
+		&app.TaxClass{},
+		&app.OrderTax{},
+		&app.Coupon{},
+		&app.CouponUsage{},
+		&app.StockReservation{},
+		&app.OptionGroup{},
+		&app.OptionValue{},
 	).Error
+	if err != nil {
+		return err
+	}
+	if err := migrateFloatColumns(db); err != nil {
+		return err
+	}
+	return migrateOptionColumns(db)
+}
+
+// floatColumns were float columns, amounts are stored in minor units
+// and tax rates in percent as integers now
+var floatColumns = []struct {
+	table, column string
+	amount        bool
+}{
+	{"products", "price", true},
+	{"cart_items", "price", true},
+	{"orders", "total", true},
+	{"order_products", "price", true},
+	{"order_products", "total", true},
+	{"order_products", "tax_rate", false},
+}
+
+// migrateFloatColumns converts float columns to integers, auto migration doesn't change column types.
+// Amounts are converted to minor units of app.DefaultCurrency, so it must be set before.
+// Converted values are kept in a new column which replaces the float column by a single ALTER,
+// so a failed migration can be run again without converting values twice.
+func migrateFloatColumns(db *gorm.DB) error {
+	for _, c := range floatColumns {
+		typ, err := columnType(db, c.table, c.column)
+		if err != nil {
+			return err
+		}
+		if typ != "float" && typ != "double" {
+			continue
+		}
+
+		factor := int64(1)
+		if c.amount {
+			factor = app.MinorUnits(app.DefaultCurrency)
+		}
+		tmp := c.column + "_minor"
+
+		tmpTyp, err := columnType(db, c.table, tmp)
+		if err != nil {
+			return err
+		}
+		if tmpTyp == "" {
+			if err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD %s BIGINT NOT NULL DEFAULT 0", c.table, tmp)).Error; err != nil {
+				return err
+			}
+		}
+		if err := db.Exec(fmt.Sprintf("UPDATE %s SET %s = ROUND(%s * %d)", c.table, tmp, c.column, factor)).Error; err != nil {
+			return err
+		}
+		if err := db.Exec(fmt.Sprintf("ALTER TABLE %s DROP %s, CHANGE %s %s BIGINT NOT NULL DEFAULT 0", c.table, c.column, tmp, c.column)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columnType gets the data type of the column, empty if the column doesn't exist
+func columnType(db *gorm.DB, table, column string) (string, error) {
+	var typ string
+	row := db.Raw("SELECT DATA_TYPE FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?", table, column).Row()
+	if err := row.Scan(&typ); err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	return typ, nil
+}
+
+// optionColumns were free-form varchar columns, they keep JSON snapshots of chosen options now
+var optionColumns = []struct{ table, column string }{
+	{"cart_items", "options"},
+	{"order_products", "options"},
+}
+
+// migrateOptionColumns converts option columns to TEXT, stored free-form text is read as a single option
+func migrateOptionColumns(db *gorm.DB) error {
+	for _, c := range optionColumns {
+		typ, err := columnType(db, c.table, c.column)
+		if err != nil {
+			return err
+		}
+		if typ != "varchar" {
+			continue
+		}
+
+		if err := db.Exec(fmt.Sprintf("ALTER TABLE %s MODIFY %s TEXT", c.table, c.column)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func SeedDB(db *gorm.DB) (err error) {
@@ -36,6 +143,7 @@ func SeedDB(db *gorm.DB) (err error) {
 		seedImages,
 		seedCategories,
 		seedProducts,
+		seedOptions,
 	}
 
 	for _, fn := range fns {
@@ -51,17 +159,31 @@ func SeedDB(db *gorm.DB) (err error) {
 func truncateTables(db *gorm.DB) (err error) {
 	tables := []string{
 		"addresses",
+		"cart_items",
+		"carts",
 		"categories",
+		"coupon_usages",
+		"coupons",
 		"images",
+		"option_groups",
+		"option_values",
 		"order_addresses",
 		"order_histories",
 		"order_products",
 		"order_statuses",
+		"order_taxes",
 		"orders",
 		"payment_methods",
+		"pivot_coupon_category",
+		"pivot_coupon_product",
 		"pivot_product_category",
 		"pivot_product_image",
 		"products",
+		"revoked_tokens",
+		"sessions",
+		"social_identities",
+		"stock_reservations",
+		"tax_classes",
 		"users",
 	}
 
@@ -77,17 +199,31 @@ func truncateTables(db *gorm.DB) (err error) {
 func dropTables(db *gorm.DB) (err error) {
 	tables := []string{
 		"addresses",
+		"cart_items",
+		"carts",
 		"categories",
+		"coupon_usages",
+		"coupons",
 		"images",
+		"option_groups",
+		"option_values",
 		"order_addresses",
 		"order_histories",
 		"order_products",
 		"order_statuses",
+		"order_taxes",
 		"orders",
 		"payment_methods",
+		"pivot_coupon_category",
+		"pivot_coupon_product",
 		"pivot_product_category",
 		"pivot_product_image",
 		"products",
+		"revoked_tokens",
+		"sessions",
+		"social_identities",
+		"stock_reservations",
+		"tax_classes",
 		"users",
 	}
 
@@ -105,7 +241,7 @@ func seedProducts(db *gorm.DB) (err error) {
 	for i := 0; i < 40; i++ {
 		var p app.Product
 		fako.Fill(&p)
-		p.Price = r.Float32()
+		p.Price = app.NewMoney(int64(r.Intn(10000)+100), app.DefaultCurrency)
 		p.IsActive = true
 		db.Create(&p)
 	}
@@ -133,6 +269,34 @@ func seedProducts(db *gorm.DB) (err error) {
 	return
 }
 
+// seedOptions adds a portion size and extra sauces to the meals
+func seedOptions(db *gorm.DB) (err error) {
+	var products []app.Product
+	if err = db.Find(&products).Error; err != nil {
+		return
+	}
+
+	for _, p := range products {
+		gs := []app.OptionGroup{
+			{ProductID: p.ID, Name: "Porsiyon", Required: true, MaxSelect: 1, SortNumber: 1, Values: []app.OptionValue{
+				{Name: "Normal", SortNumber: 1, IsActive: true},
+				{Name: "1.5 Porsiyon", PriceDelta: app.MustParseMoney("10.00"), SortNumber: 2, IsActive: true},
+			}},
+			{ProductID: p.ID, Name: "Ekstra", SortNumber: 2, Values: []app.OptionValue{
+				{Name: "Acı Sos", PriceDelta: app.MustParseMoney("2.00"), SortNumber: 1, IsActive: true},
+				{Name: "Yoğurt", PriceDelta: app.MustParseMoney("2.00"), SortNumber: 2, IsActive: true},
+				{Name: "Sarımsaklı Sos", PriceDelta: app.MustParseMoney("2.00"), SortNumber: 3, IsActive: true},
+			}},
+		}
+		for _, g := range gs {
+			if err = db.Create(&g).Error; err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
 func seedCategories(db *gorm.DB) (err error) {
 
 	names := []string{
@@ -181,7 +345,11 @@ func seedUsers(db *gorm.DB) error {
 }
 
 func seedMaster(db *gorm.DB) (err error) {
-	oss := []app.OrderStatus{{Name: "Isleme Alindi"}, {Name: "Tamamlandi"}}
+	oss := []app.OrderStatus{
+		{Name: "Isleme Alindi", SortNumber: 1, Status: true},
+		{Name: "Tamamlandi", SortNumber: 2, Status: true},
+		{Name: "Iptal Edildi", SortNumber: 3, Status: true},
+	}
 	pms := []app.PaymentMethod{
 		{Name: "Nakit Ödeme", SortNumber: 1, Status: true},
 		{Name: "Kredi Kartı / Banka Kartı", SortNumber: 2, Status: true},
@@ -197,9 +365,19 @@ func seedMaster(db *gorm.DB) (err error) {
 		db.Create(&os)
 	}
 
+	tcs := []app.TaxClass{
+		{Name: "KDV %1", Rate: 1},
+		{Name: "KDV %8", Rate: 8},
+		{Name: "KDV %18", Rate: 18},
+	}
+
 	for _, pm := range pms {
 		db.Create(&pm)
 	}
 
+	for _, tc := range tcs {
+		db.Create(&tc)
+	}
+
 	return
 }
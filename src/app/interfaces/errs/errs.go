@@ -2,6 +2,8 @@ package errs
 
 import (
 	"fmt"
+	"strconv"
+	"time"
 
 	"net/http"
 
@@ -26,11 +28,31 @@ func New(code uint16, hCode int, msg string, args ...interface{}) *Error {
 }
 
 func Unauthorized(msg string, args ...interface{}) *Error {
-	return New(NotImplementedError, http.StatusUnauthorized, msg, args)
+	return New(NotImplementedError, http.StatusUnauthorized, msg, args...)
 }
 
 func BadRequest(msg string, args ...interface{}) *Error {
-	return New(NotImplementedError, http.StatusBadRequest, msg, args)
+	return New(NotImplementedError, http.StatusBadRequest, msg, args...)
+}
+
+func Forbidden(msg string, args ...interface{}) *Error {
+	return New(NotImplementedError, http.StatusForbidden, msg, args...)
+}
+
+func TooManyRequests(msg string, args ...interface{}) *Error {
+	return New(NotImplementedError, http.StatusTooManyRequests, msg, args...)
+}
+
+func Locked(msg string, args ...interface{}) *Error {
+	return New(NotImplementedError, http.StatusLocked, msg, args...)
+}
+
+func Conflict(msg string, args ...interface{}) *Error {
+	return New(NotImplementedError, http.StatusConflict, msg, args...)
+}
+
+func NotFound(msg string, args ...interface{}) *Error {
+	return New(NotImplementedError, http.StatusNotFound, msg, args...)
 }
 
 // IsTokenExpiredErr checks given error is jwt expired token error
@@ -54,6 +76,8 @@ type Error struct {
 	Code     uint16
 	HTTPCode int
 	Args     []interface{}
+	// RetryAfter is sent as Retry-After header when it's set
+	RetryAfter time.Duration
 }
 
 func (e Error) Error() string {
@@ -65,6 +89,21 @@ func (e Error) SetInner(err error) *Error {
 	return &e
 }
 
+func (e Error) SetRetryAfter(d time.Duration) *Error {
+	e.RetryAfter = d
+	return &e
+}
+
+// SetHeaders sets response headers of the error, it must be called before writing the response
+func SetHeaders(w http.ResponseWriter, err error) {
+	appErr, ok := Cause(err).(*Error)
+	if !ok || appErr.RetryAfter <= 0 {
+		return
+	}
+	secs := int((appErr.RetryAfter + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+}
+
 var NewWithStack = errors.Errorf
 var Wrap = errors.WithStack
 var WrapMsg = errors.Wrapf
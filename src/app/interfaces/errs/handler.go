@@ -21,6 +21,7 @@ type Handler struct {
 
 func (eh *Handler) Handle(w http.ResponseWriter, err error) {
 	appErr, ok := errors.Cause(err).(*Error)
+	SetHeaders(w, err)
 	if eh.Debug == "on" {
 		var code = http.StatusInternalServerError
 		if ok {
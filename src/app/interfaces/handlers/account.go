@@ -2,26 +2,24 @@ package handlers
 
 import (
 	"app"
+	"app/usecases"
 	"net/http"
 
-	"app/interfaces/errs"
-
 	"github.com/alioygur/gores"
 	"github.com/gorilla/mux"
 	"github.com/justinas/alice"
 )
 
-type accountRepo interface {
-	ExistsByEmail(string) (bool, error)
-	UpdateFields(map[string]interface{}) error
+type profileService interface {
+	Update(u *app.User, f *usecases.ProfileForm, locale string) error
 }
 
-func NewAccount(ur accountRepo) *Account {
-	return &Account{ur}
+func NewAccount(ps profileService) *Account {
+	return &Account{ps}
 }
 
 type Account struct {
-	ur accountRepo
+	ps profileService
 }
 
 func (a *Account) SetRoutes(r *mux.Router, mid ...alice.Constructor) {
@@ -33,52 +31,22 @@ func (a *Account) SetRoutes(r *mux.Router, mid ...alice.Constructor) {
 func (a *Account) me(w http.ResponseWriter, r *http.Request) error {
 	u := app.UserMustFromContext(r.Context())
 
-	return gores.JSON(w, http.StatusOK, u)
+	return gores.JSON(w, http.StatusOK, response{newUserRes(u)})
 }
 
+// update updates current user's profile and responds the updated user.
+// Changing password signs out all sessions including the current one.
 func (a *Account) update(w http.ResponseWriter, r *http.Request) error {
-	f := new(updateMeForm)
+	f := new(usecases.ProfileForm)
 	if err := decodeReq(r, f); err != nil {
 		return err
 	}
 
 	me := app.UserMustFromContext(r.Context())
 
-	exists, err := a.ur.ExistsByEmail(f.Email)
-	if err != nil {
+	if err := a.ps.Update(me, f, reqLocale(r)); err != nil {
 		return err
 	}
 
-	if exists {
-		return errEmailExists
-	}
-
-	fields := make(map[string]interface{})
-
-	if f.Email != "" {
-		if err := errs.CheckEmail(f.Email); err != nil {
-			return err
-		}
-		fields["Email"] = f.Email
-	}
-
-	if f.Password != "" {
-		if err := errs.CheckPassword(f.Password); err != nil {
-			return err
-		}
-		me.SetPassword(f.Password)
-		fields["Password"] = f.Password
-	}
-
-	if err := a.ur.UpdateFields(fields); err != nil {
-		return err
-	}
-
-	gores.NoContent(w)
-	return nil
-}
-
-type updateMeForm struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	return gores.JSON(w, http.StatusOK, response{newUserRes(me)})
 }
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"app"
+	"net/http"
+
+	"app/usecases"
+
+	"github.com/alioygur/gores"
+	"github.com/gorilla/mux"
+	"github.com/justinas/alice"
+)
+
+type addressService interface {
+	Addresses(*app.User) ([]app.Address, error)
+	CreateAddress(*app.User, *usecases.AddressForm) (*app.Address, error)
+	UpdateAddress(*app.User, *usecases.AddressForm) (*app.Address, error)
+	SetDefault(u *app.User, id int) (*app.Address, error)
+	DeleteAddress(u *app.User, id int) error
+}
+
+func NewAddress(srv addressService, eh app.ErrorHandler) *Address {
+	return &Address{srv, eh}
+}
+
+type Address struct {
+	srv addressService
+	eh  app.ErrorHandler
+}
+
+func (ah *Address) SetRoutes(r *mux.Router, mid ...alice.Constructor) {
+	h := alice.New(mid...)
+	r.Handle("/v1/me/addresses", h.ThenFunc(ah.getAddresses)).Methods("GET")
+	r.Handle("/v1/me/addresses", h.ThenFunc(ah.createAddress)).Methods("POST")
+	r.Handle("/v1/me/addresses/{id:[0-9]+}", h.ThenFunc(ah.updateAddress)).Methods("PATCH", "PUT")
+	r.Handle("/v1/me/addresses/{id:[0-9]+}", h.ThenFunc(ah.deleteAddress)).Methods("DELETE")
+	r.Handle("/v1/me/addresses/{id:[0-9]+}/default", h.ThenFunc(ah.setDefault)).Methods("POST")
+}
+
+func (ah *Address) getAddresses(w http.ResponseWriter, r *http.Request) {
+	u := app.UserMustFromContext(r.Context())
+
+	as, err := ah.srv.Addresses(u)
+	if err != nil {
+		ah.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newAddressesRes(as)})
+}
+
+func (ah *Address) createAddress(w http.ResponseWriter, r *http.Request) {
+	f := new(usecases.AddressForm)
+	if err := decodeReq(r, f); err != nil {
+		ah.eh.Handle(w, err)
+		return
+	}
+
+	u := app.UserMustFromContext(r.Context())
+
+	a, err := ah.srv.CreateAddress(u, f)
+	if err != nil {
+		ah.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusCreated, response{newAddressRes(a)})
+}
+
+func (ah *Address) updateAddress(w http.ResponseWriter, r *http.Request) {
+	f := new(usecases.AddressForm)
+	if err := decodeReq(r, f); err != nil {
+		ah.eh.Handle(w, err)
+		return
+	}
+
+	f.ID = muxVarMustInt("id", r)
+	u := app.UserMustFromContext(r.Context())
+
+	a, err := ah.srv.UpdateAddress(u, f)
+	if err != nil {
+		ah.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newAddressRes(a)})
+}
+
+func (ah *Address) setDefault(w http.ResponseWriter, r *http.Request) {
+	id := muxVarMustInt("id", r)
+	u := app.UserMustFromContext(r.Context())
+
+	a, err := ah.srv.SetDefault(u, id)
+	if err != nil {
+		ah.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newAddressRes(a)})
+}
+
+func (ah *Address) deleteAddress(w http.ResponseWriter, r *http.Request) {
+	id := muxVarMustInt("id", r)
+	u := app.UserMustFromContext(r.Context())
+
+	if err := ah.srv.DeleteAddress(u, id); err != nil {
+		ah.eh.Handle(w, err)
+		return
+	}
+
+	gores.NoContent(w)
+}
@@ -3,10 +3,13 @@ package handlers
 import (
 	"app"
 	"app/interfaces/errs"
-	"fmt"
+	"app/usecases"
+	"log"
+	"net"
 	"net/http"
 	"net/url"
-	"os"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -14,42 +17,88 @@ import (
 )
 
 var (
-	errEmailExists    = errs.BadRequest("email address already exists")
-	errEmailNotExists = errs.BadRequest("email address not exists")
-	errWrongCred      = errs.Unauthorized("wrong credentials")
-	errInactiveUser   = errs.Unauthorized("inactive user")
-	errInvalidToken   = errs.BadRequest("invalid token")
+	errEmailExists     = errs.BadRequest("email address already exists")
+	errEmailNotExists  = errs.BadRequest("email address not exists")
+	errWrongCred       = errs.Unauthorized("wrong credentials")
+	errInactiveUser    = errs.Unauthorized("inactive user")
+	errInvalidToken    = errs.BadRequest("invalid token")
+	errEmailVerified   = errs.BadRequest("email address already verified")
+	errVerifyLinkEmpty = errs.BadRequest("email verification link isn't configured")
+	errResetLinkEmpty  = errs.BadRequest("password reset link isn't configured")
 )
 
+// verificationResendInterval is the minimum interval between verification emails
+const verificationResendInterval = time.Minute
+
 type userRepo interface {
 	OneByEmail(string) (*app.User, error)
 	ExistsByEmail(string) (bool, error)
-	UpdateFields(map[string]interface{}) error
+	OneByID(int) (*app.User, error)
+	UpdateUser(*app.User, map[string]interface{}) error
 	Create(*app.User) error
 	app.DBNotFoundErrChecker
 }
 
+type emailSender interface {
+	Send(name, locale string, to []string, d *usecases.EmailData) error
+}
+
+type tokenService interface {
+	Issue(*app.User) (*usecases.TokenPair, error)
+	Refresh(refreshToken string) (*usecases.TokenPair, error)
+	Logout(accessToken, refreshToken string) error
+	RevokeAll(*app.User) error
+	VerificationToken(*app.User) (string, error)
+	ParseVerificationToken(string) (*app.VerificationClaims, error)
+	ResetToken(*app.User) (string, error)
+	ValidateResetToken(u *app.User, token string) error
+}
+
+type attemptGuard interface {
+	Check(action, ip, email string) error
+	Fail(action, ip, email string) error
+	Reset(action, email string) error
+}
+
 type socialAuth interface {
-	GetUserFromFacebook(string) (*app.User, error)
+	Providers() []string
+	SignIn(provider, token string) (*app.User, bool, error)
 }
 
 // NewAuthHandler instances new auth handler struct
-func NewAuthHandler(ur userRepo, sa socialAuth) *authHandler {
-	return &authHandler{ur, sa}
+func NewAuthHandler(ur userRepo, sa socialAuth, es emailSender, ts tokenService, ag attemptGuard) *authHandler {
+	return &authHandler{ur: ur, sa: sa, es: es, ts: ts, ag: ag}
 }
 
 // AuthHandler struct
 type authHandler struct {
 	ur userRepo
 	sa socialAuth
-	// sendPasswordResetLink func(m app.MailSender, to, link string) error
+	es emailSender
+	ts tokenService
+	ag attemptGuard
+
+	// SkipEmailVerification activates users on registration without email verification
+	SkipEmailVerification bool
+	// TrustProxyHeaders uses the last X-Forwarded-For address as client's ip address,
+	// it must be set only behind a trusted reverse proxy which appends it
+	TrustProxyHeaders bool
+	// VerifyURL is the link sent with email verification tokens
+	VerifyURL string
+	// ResetURL is the link sent with password reset tokens
+	ResetURL string
 }
 
 // SetRoutes sets this module's routes
 func (ah *authHandler) SetRoutes(r *mux.Router) {
 	r.Handle("/v1/auth/login", appHandler(ah.login)).Methods("POST")
 	r.Handle("/v1/auth/register", appHandler(ah.register)).Methods("POST")
-	r.Handle("/v1/auth/register-fb", appHandler(ah.registerFacebook)).Methods("POST")
+	r.Handle("/v1/auth/social", appHandler(ah.getSocialProviders)).Methods("GET")
+	r.Handle("/v1/auth/social/{provider}", appHandler(ah.socialSignIn)).Methods("POST")
+	r.Handle("/v1/auth/refresh", appHandler(ah.refresh)).Methods("POST")
+	r.Handle("/v1/auth/logout", appHandler(ah.logout)).Methods("POST")
+	r.Handle("/v1/auth/verify", appHandler(ah.verifyEmail)).Methods("POST")
+	r.Handle("/v1/auth/verify/resend", appHandler(ah.resendVerification)).Methods("POST")
 
 	r.Handle("/v1/password/forgot", appHandler(ah.forgotPassword)).Methods("POST")
 	r.Handle("/v1/password/reset", appHandler(ah.resetPassword)).Methods("POST")
@@ -61,28 +110,91 @@ func (ah *authHandler) login(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
+	ip := ah.clientIP(r)
+	if err := ah.ag.Check(usecases.ActionLogin, ip, f.Email); err != nil {
+		return err
+	}
+
 	u, err := ah.ur.OneByEmail(f.Email)
 	if err != nil {
 		if ah.ur.IsNotFoundErr(err) {
-			return errWrongCred
+			return ah.loginFailed(ip, f.Email)
 		}
 		return err
 	}
 
 	if !u.IsCredentialsVerified(f.Password) {
-		return errWrongCred
+		return ah.loginFailed(ip, f.Email)
+	}
+
+	if err := ah.ag.Reset(usecases.ActionLogin, f.Email); err != nil {
+		return err
 	}
 
 	if !u.IsActivated {
 		return errInactiveUser
 	}
 
-	token, err := u.CreateJWT(os.Getenv("SECRET_KEY"))
+	tp, err := ah.ts.Issue(u)
+	if err != nil {
+		return err
+	}
+
+	return gores.JSON(w, http.StatusOK, newTokenRes(tp))
+}
+
+// loginFailed records the failed login attempt and returns wrong credentials error
+func (ah *authHandler) loginFailed(ip, email string) error {
+	if err := ah.ag.Fail(usecases.ActionLogin, ip, email); err != nil {
+		return err
+	}
+	return errWrongCred
+}
+
+// clientIP gets the client's ip address of the request
+func (ah *authHandler) clientIP(r *http.Request) string {
+	if ah.TrustProxyHeaders {
+		// the former addresses are sent by the client, only the one appended by the proxy is trusted
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			ips := strings.Split(xff, ",")
+			return strings.TrimSpace(ips[len(ips)-1])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (ah *authHandler) refresh(w http.ResponseWriter, r *http.Request) error {
+	f := new(refreshTokenForm)
+	if err := decodeReq(r, f); err != nil {
+		return err
+	}
+
+	tp, err := ah.ts.Refresh(f.RefreshToken)
 	if err != nil {
 		return err
 	}
 
-	return gores.JSON(w, http.StatusOK, tokenRes{token})
+	return gores.JSON(w, http.StatusOK, newTokenRes(tp))
+}
+
+// logout revokes the given refresh token's session and the request's access token
+func (ah *authHandler) logout(w http.ResponseWriter, r *http.Request) error {
+	f := new(refreshTokenForm)
+	if err := decodeReq(r, f); err != nil {
+		return err
+	}
+
+	if err := ah.ts.Logout(bearerToken(r), f.RefreshToken); err != nil {
+		return err
+	}
+
+	gores.NoContent(w)
+	return nil
 }
 
 func (ah *authHandler) register(w http.ResponseWriter, r *http.Request) error {
@@ -110,19 +222,158 @@ func (ah *authHandler) register(w http.ResponseWriter, r *http.Request) error {
 	usr.FirstName = f.FirstName
 	usr.LastName = f.LastName
 	usr.Email = f.Email
-	usr.IsActivated = true
+	usr.IsActivated = ah.SkipEmailVerification
 	usr.SetPassword(f.Password)
 
 	if err := ah.ur.Create(&usr); err != nil {
 		return err
 	}
 
-	token, err := usr.CreateJWT(os.Getenv("SECRET_KEY"))
+	// inactive users can't log in, so tokens are issued only after the email is verified
+	if !usr.IsActivated {
+		if err := ah.sendVerification(&usr, reqLocale(r)); err != nil {
+			return err
+		}
+		return gores.JSON(w, http.StatusCreated, response{newUserRes(&usr)})
+	}
+
+	ah.sendWelcome(&usr, reqLocale(r))
+	tp, err := ah.ts.Issue(&usr)
+	if err != nil {
+		return err
+	}
+
+	return gores.JSON(w, http.StatusCreated, newTokenRes(tp))
+}
+
+func (ah *authHandler) verifyEmail(w http.ResponseWriter, r *http.Request) error {
+	f := new(verifyEmailForm)
+	if err := decodeReq(r, f); err != nil {
+		return err
+	}
+
+	c, err := ah.ts.ParseVerificationToken(f.Token)
+	if err != nil {
+		if errs.IsTokenValidationErr(err) {
+			return errInvalidToken.SetInner(err)
+		}
+		return err
+	}
+
+	u, err := ah.ur.OneByID(c.UserID)
 	if err != nil {
+		if ah.ur.IsNotFoundErr(err) {
+			return errInvalidToken
+		}
 		return err
 	}
+	if c.Version != u.TokenVersion {
+		return errInvalidToken
+	}
+
+	switch {
+	case c.Email == u.Email:
+		if !u.IsActivated {
+			if err := ah.ur.UpdateUser(u, map[string]interface{}{"IsActivated": true}); err != nil {
+				return err
+			}
+			ah.sendWelcome(u, reqLocale(r))
+		}
+	case u.PendingEmail != "" && c.Email == u.PendingEmail:
+		if err := ah.confirmEmailChange(u); err != nil {
+			return err
+		}
+	default:
+		// the token is of an email which the user doesn't have anymore
+		return errInvalidToken
+	}
 
-	return gores.JSON(w, http.StatusCreated, tokenRes{token})
+	gores.NoContent(w)
+	return nil
+}
+
+// confirmEmailChange sets user's pending email as its email
+func (ah *authHandler) confirmEmailChange(u *app.User) error {
+	// the email may be taken while the change was pending
+	exists, err := ah.ur.ExistsByEmail(u.PendingEmail)
+	if err != nil {
+		return err
+	} else if exists {
+		return errEmailExists
+	}
+
+	return ah.ur.UpdateUser(u, map[string]interface{}{"Email": u.PendingEmail, "PendingEmail": ""})
+}
+
+func (ah *authHandler) resendVerification(w http.ResponseWriter, r *http.Request) error {
+	f := new(resendVerificationForm)
+	if err := decodeReq(r, f); err != nil {
+		return err
+	}
+
+	if err := errs.CheckEmail(f.Email); err != nil {
+		return err
+	}
+
+	u, err := ah.ur.OneByEmail(f.Email)
+	if err != nil {
+		if ah.ur.IsNotFoundErr(err) {
+			return errEmailNotExists
+		}
+		return err
+	}
+
+	if u.IsActivated {
+		return errEmailVerified
+	}
+
+	if u.VerificationSentAt != nil {
+		if wait := verificationResendInterval - time.Since(*u.VerificationSentAt); wait > 0 {
+			return errs.TooManyRequests("verification email already sent, try again in %d seconds", int(wait.Seconds())+1)
+		}
+	}
+
+	if err := ah.sendVerification(u, reqLocale(r)); err != nil {
+		return err
+	}
+
+	gores.NoContent(w)
+	return nil
+}
+
+// sendVerification sends email verification link and saves the sending time.
+// The link is built from VerifyURL, so the token can't be sent to another host.
+func (ah *authHandler) sendVerification(u *app.User, locale string) error {
+	if ah.VerifyURL == "" {
+		return errVerifyLinkEmpty
+	}
+	verifyURL, err := url.Parse(ah.VerifyURL)
+	if err != nil {
+		return err
+	}
+
+	token, err := ah.ts.VerificationToken(u)
+	if err != nil {
+		return err
+	}
+
+	q := verifyURL.Query()
+	q.Set("token", token)
+	verifyURL.RawQuery = q.Encode()
+
+	now := time.Now()
+	if err := ah.ur.UpdateUser(u, map[string]interface{}{"VerificationSentAt": &now}); err != nil {
+		return err
+	}
+
+	d := &usecases.EmailData{User: u, Link: verifyURL.String()}
+	return ah.es.Send(usecases.EmailVerification, locale, []string{u.Email}, d)
+}
+
+func (ah *authHandler) sendWelcome(u *app.User, locale string) {
+	if err := ah.es.Send(usecases.EmailWelcome, locale, []string{u.Email}, &usecases.EmailData{User: u}); err != nil {
+		log.Printf("welcome mail can't sent, err:%s", err)
+	}
 }
 
 func (ah *authHandler) forgotPassword(w http.ResponseWriter, r *http.Request) error {
@@ -135,12 +386,22 @@ func (ah *authHandler) forgotPassword(w http.ResponseWriter, r *http.Request) er
 		return err
 	}
 
-	if f.Link == "" {
-		f.Link = os.Getenv("PASSWORD_RESET_URL")
+	// every request counts as an attempt, so reset emails can't be flooded
+	ip := ah.clientIP(r)
+	if err := ah.ag.Check(usecases.ActionForgotPassword, ip, f.Email); err != nil {
+		return err
+	}
+	if err := ah.ag.Fail(usecases.ActionForgotPassword, ip, f.Email); err != nil {
+		return err
+	}
+
+	// the token is sent only to the configured url, so it can't be sent to another host
+	if ah.ResetURL == "" {
+		return errResetLinkEmpty
 	}
-	resetURL, err := url.Parse(f.Link)
+	resetURL, err := url.Parse(ah.ResetURL)
 	if err != nil {
-		return errs.BadRequest("invalid url").SetInner(err)
+		return err
 	}
 
 	u, err := ah.ur.OneByEmail(f.Email)
@@ -151,7 +412,7 @@ func (ah *authHandler) forgotPassword(w http.ResponseWriter, r *http.Request) er
 		return err
 	}
 
-	tokenString, err := u.GenResetPasswordToken()
+	tokenString, err := ah.ts.ResetToken(u)
 	if err != nil {
 		return err
 	}
@@ -160,10 +421,10 @@ func (ah *authHandler) forgotPassword(w http.ResponseWriter, r *http.Request) er
 	q.Set("token", tokenString)
 	resetURL.RawQuery = q.Encode()
 
-	body := fmt.Sprintf("Please click below link to reset your password <br/> %s", resetURL.String())
-
-	// todo: Send email here
-	_ = body
+	d := &usecases.EmailData{User: u, Link: resetURL.String()}
+	if err := ah.es.Send(usecases.EmailPasswordReset, reqLocale(r), []string{u.Email}, d); err != nil {
+		return err
+	}
 
 	gores.NoContent(w)
 	return nil
@@ -190,7 +451,7 @@ func (ah *authHandler) resetPassword(w http.ResponseWriter, r *http.Request) err
 		return err
 	}
 
-	if err := u.IsResetPasswordTokenValid(f.Token); err != nil {
+	if err := ah.ts.ValidateResetToken(u, f.Token); err != nil {
 		if errs.IsTokenValidationErr(err) {
 			return errInvalidToken.SetInner(err)
 		}
@@ -198,7 +459,11 @@ func (ah *authHandler) resetPassword(w http.ResponseWriter, r *http.Request) err
 	}
 
 	u.SetPassword(f.Password)
-	if err := ah.ur.UpdateFields(map[string]interface{}{"Password": u.Password}); err != nil {
+	if err := ah.ur.UpdateUser(u, map[string]interface{}{"Password": u.Password}); err != nil {
+		return err
+	}
+
+	if err := ah.ts.RevokeAll(u); err != nil {
 		return err
 	}
 
@@ -206,46 +471,52 @@ func (ah *authHandler) resetPassword(w http.ResponseWriter, r *http.Request) err
 	return nil
 }
 
-func (ah *authHandler) registerFacebook(w http.ResponseWriter, r *http.Request) error {
-	f := new(registerFacebook)
+func (ah *authHandler) getSocialProviders(w http.ResponseWriter, r *http.Request) error {
+	return gores.JSON(w, http.StatusOK, response{ah.sa.Providers()})
+}
+
+// socialSignIn signs the user in by the provider's access token,
+// responds 201 if a new user is created
+func (ah *authHandler) socialSignIn(w http.ResponseWriter, r *http.Request) error {
+	f := new(socialSignInForm)
 	if err := decodeReq(r, f); err != nil {
 		return err
 	}
 
-	u, err := ah.sa.GetUserFromFacebook(f.AccessToken)
+	u, created, err := ah.sa.SignIn(mux.Vars(r)["provider"], f.AccessToken)
 	if err != nil {
 		return err
 	}
 
-	existsUser, err := ah.ur.OneByEmail(u.Email)
-	if err == nil {
-		u = existsUser
-	} else if ah.ur.IsNotFoundErr(err) {
-		u.IsActivated = true
-		if err := ah.ur.Create(u); err != nil {
-			return err
-		}
-	} else {
-		return err
+	if !u.IsActivated {
+		return errInactiveUser
 	}
 
-	token, err := u.CreateJWT(os.Getenv("SECRET_KEY"))
+	tp, err := ah.ts.Issue(u)
 	if err != nil {
 		return err
 	}
 
-	return gores.JSON(w, http.StatusCreated, tokenRes{token})
+	code := http.StatusOK
+	if created {
+		code = http.StatusCreated
+		ah.sendWelcome(u, reqLocale(r))
+	}
+	return gores.JSON(w, code, newTokenRes(tp))
 }
 
 type tokenRes struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int    `json:"expiresIn"`
+}
+
+func newTokenRes(tp *usecases.TokenPair) tokenRes {
+	return tokenRes{tp.AccessToken, tp.RefreshToken, int(tp.ExpiresIn.Seconds())}
 }
 
-type updateUserForm struct {
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-	Email     string `json:"email"`
-	Password  string `json:"password"`
+type refreshTokenForm struct {
+	RefreshToken string `json:"refreshToken"`
 }
 
 type registerForm struct {
@@ -256,12 +527,19 @@ type registerForm struct {
 	IsActivated bool   `json:"isActivated"`
 }
 
-type registerFacebook struct {
+type verifyEmailForm struct {
+	Token string `json:"token"`
+}
+
+type resendVerificationForm struct {
+	Email string `json:"email"`
+}
+
+type socialSignInForm struct {
 	AccessToken string `json:"accessToken"`
 }
 
 type forgotPasswordForm struct {
-	Link  string `json:"link"`
 	Email string `json:"email"`
 }
 
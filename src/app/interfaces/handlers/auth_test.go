@@ -1,16 +1,24 @@
 package handlers
 
 import (
+	"app/infra"
+	"app/infra/social"
+	"app/infra/social/socialtest"
 	"app/interfaces/repos/mockdb"
+	"bytes"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"app"
 
 	"encoding/json"
+	"strings"
+	"time"
 
 	"app/usecases"
 
+	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/gorilla/mux"
 )
 
@@ -37,7 +45,7 @@ func TestAuthHandler_login(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	ah := NewAuthHandler(ur, usecases.NewMockSocialAuth())
+	ah := NewAuthHandler(ur, newTestSocialAuth(ur), usecases.NewEmails(infra.NewFakeMail(), "en"), newTestTokens(ur), newTestGuard())
 	ah.SetRoutes(h)
 
 	var (
@@ -62,7 +70,10 @@ func TestAuthHandler_register(t *testing.T) {
 	// new user repo
 	ur := &mockdb.User{}
 
-	ah := NewAuthHandler(ur, usecases.NewMockSocialAuth())
+	ms := &mailRecorder{}
+	ah := NewAuthHandler(ur, newTestSocialAuth(ur), usecases.NewEmails(ms, "en"), newTestTokens(ur), newTestGuard())
+	ah.VerifyURL = "https://gocart.example/verify"
+	ah.ResetURL = "https://gocart.example/password/reset"
 	ah.SetRoutes(h)
 
 	var (
@@ -76,6 +87,101 @@ func TestAuthHandler_register(t *testing.T) {
 	}
 
 	runHandlerTestCases(testCases, h, t)
+
+	u, err := ur.OneByEmail("newuser@gmail.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.IsActivated {
+		t.Error("expected registered user to be inactive until email verification")
+	}
+	if len(ms.mails) != 1 || ms.mails[0].subject != "Verify your email address" {
+		t.Errorf("expected a verification mail got %+v", ms.mails)
+	}
+
+	// tokens aren't issued until the email is verified
+	body, _ := json.Marshal(registerForm{Email: "otheruser@gmail.com", Password: "new password"})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/v1/auth/register", bytes.NewReader(body)))
+	if w.Code != http.StatusCreated || strings.Contains(w.Body.String(), "refreshToken") {
+		t.Errorf("expected registered user without tokens got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestAuthHandler_loginLockout(t *testing.T) {
+	h := mux.NewRouter()
+
+	ur := &mockdb.User{}
+	u := &app.User{Email: "activeuser@gmail.com", IsActivated: true}
+	u.SetPassword("good password")
+	if err := ur.Create(u); err != nil {
+		t.Fatal(err)
+	}
+
+	g := newTestGuard()
+	g.Policies[usecases.ActionLogin] = usecases.AttemptPolicies{
+		Email: usecases.AttemptPolicy{FreeAttempts: 1, MaxFailures: 2, BaseDelay: time.Minute, MaxDelay: time.Minute, Lockout: time.Hour},
+		IP:    usecases.AttemptPolicy{FreeAttempts: 2, MaxFailures: 10, BaseDelay: time.Minute, MaxDelay: time.Minute, Lockout: time.Hour},
+	}
+	ah := NewAuthHandler(ur, newTestSocialAuth(ur), usecases.NewEmails(infra.NewFakeMail(), "en"), newTestTokens(ur), g)
+	ah.SetRoutes(h)
+
+	post := func(email, password, ip string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(loginForm{email, password})
+		r := httptest.NewRequest("POST", "/v1/auth/login", bytes.NewReader(body))
+		r.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := post(u.Email, "wrong password", "10.0.0.1"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status code %d got %d", http.StatusUnauthorized, w.Code)
+		}
+	}
+
+	// the email is locked out even with the right password and from another ip
+	w := post(u.Email, "good password", "10.0.0.2")
+	if w.Code != http.StatusLocked {
+		t.Fatalf("expected status code %d got %d", http.StatusLocked, w.Code)
+	}
+	if ra := w.Header().Get("Retry-After"); ra != "3600" {
+		t.Errorf("expected Retry-After 3600 got %q", ra)
+	}
+
+	// the ip waits after its failures for any email
+	if w := post("other@gmail.com", "wrong password", "10.0.0.1"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status code %d got %d", http.StatusUnauthorized, w.Code)
+	}
+	w = post("another@gmail.com", "wrong password", "10.0.0.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status code %d got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if ra := w.Header().Get("Retry-After"); ra != "60" {
+		t.Errorf("expected Retry-After 60 got %q", ra)
+	}
+}
+
+func TestAuthHandler_clientIP(t *testing.T) {
+	ah := &authHandler{TrustProxyHeaders: true}
+
+	r := httptest.NewRequest("POST", "/v1/auth/login", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	if ip := ah.clientIP(r); ip != "10.0.0.1" {
+		t.Errorf("expected remote address got %s", ip)
+	}
+
+	// the client can send any address, only the one appended by the proxy is used
+	r.Header.Set("X-Forwarded-For", "1.2.3.4, 203.0.113.7")
+	if ip := ah.clientIP(r); ip != "203.0.113.7" {
+		t.Errorf("expected the address appended by the proxy got %s", ip)
+	}
+
+	ah.TrustProxyHeaders = false
+	if ip := ah.clientIP(r); ip != "10.0.0.1" {
+		t.Errorf("expected remote address got %s", ip)
+	}
 }
 
 func TestAuthHandler_forgotPassword(t *testing.T) {
@@ -93,21 +199,45 @@ func TestAuthHandler_forgotPassword(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	ah := NewAuthHandler(ur, usecases.NewMockSocialAuth())
+	ms := &mailRecorder{}
+	ah := NewAuthHandler(ur, newTestSocialAuth(ur), usecases.NewEmails(ms, "en"), newTestTokens(ur), newTestGuard())
+	ah.VerifyURL = "https://gocart.example/verify"
+	ah.ResetURL = "https://gocart.example/password/reset"
 	ah.SetRoutes(h)
 
 	var (
 		goodLoginCred, _ = json.Marshal(loginForm{"activeuser@gmail.com", "goodpassword"})
 		badLoginCred, _  = json.Marshal(loginForm{"wrong@mail.address", "wrong password"})
+		// the link is ignored, the token is sent only to the configured url
+		linkParams = []byte(`{"email":"activeuser@gmail.com","link":"https://attacker.example/reset"}`)
 	)
 
 	testCases := []testCase{
 		{"forgot password with no params", "/v1/password/forgot", "POST", nil, http.StatusBadRequest, nil},
 		{"forgot password with bad params", "/v1/password/forgot", "POST", badLoginCred, http.StatusBadRequest, nil},
 		{"forgot password with good params", "/v1/password/forgot", "POST", goodLoginCred, http.StatusNoContent, nil},
+		{"forgot password with a link", "/v1/password/forgot", "POST", linkParams, http.StatusNoContent, nil},
 	}
 
 	runHandlerTestCases(testCases, h, t)
+
+	if len(ms.mails) != 2 {
+		t.Fatalf("expected 2 password reset mails got %d", len(ms.mails))
+	}
+	if to := ms.mails[0].to; len(to) != 1 || to[0] != u.Email {
+		t.Errorf("expected password reset mail to %s got %v", u.Email, to)
+	}
+	if body := string(ms.mails[1].body); !strings.Contains(body, "gocart.example/password/reset?token=") || strings.Contains(body, "attacker.example") {
+		t.Errorf("expected password reset mail to contain the configured reset link got %s", body)
+	}
+
+	// without a configured link no token is sent
+	ah.ResetURL = ""
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/v1/password/forgot", bytes.NewReader(goodLoginCred)))
+	if w.Code != http.StatusBadRequest || len(ms.mails) != 2 {
+		t.Errorf("expected an error without a reset link got %d and %d mails", w.Code, len(ms.mails))
+	}
 }
 
 func TestAuthHandler_resetPassword(t *testing.T) {
@@ -125,49 +255,381 @@ func TestAuthHandler_resetPassword(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	ah := NewAuthHandler(ur, usecases.NewMockSocialAuth())
+	// a user who signs in only by a social account has no password
+	socialUser := app.User{Email: "social@gmail.com", IsActivated: true}
+	if err := ur.Create(&socialUser); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestTokens(ur)
+	ah := NewAuthHandler(ur, newTestSocialAuth(ur), usecases.NewEmails(infra.NewFakeMail(), "en"), ts, newTestGuard())
 	ah.SetRoutes(h)
 
-	resetToken, err := u.GenResetPasswordToken()
+	resetToken, err := ts.ResetToken(&u)
 	if err != nil {
 		t.Fatalf("can't generate reset password token: %v", err)
 	}
 
+	// a token signed by the empty password hash must not be accepted
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"email": "social@gmail.com", "exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	var (
 		goodParams, _   = json.Marshal(resetPasswordForm{"activeuser@gmail.com", "my new password", resetToken})
 		invalidEmail, _ = json.Marshal(resetPasswordForm{"invalid@gmail.com", "my new password", resetToken})
 		invalidToken, _ = json.Marshal(resetPasswordForm{"activeuser@gmail.com", "my new password", "bad token"})
+		otherUser, _    = json.Marshal(resetPasswordForm{"social@gmail.com", "my new password", resetToken})
+		forgedToken, _  = json.Marshal(resetPasswordForm{"social@gmail.com", "my new password", forged})
 	)
 
 	testCases := []testCase{
 		{"reset password with no params", "/v1/password/reset", "POST", nil, http.StatusBadRequest, nil},
 		{"reset password with invalid email", "/v1/password/reset", "POST", invalidEmail, http.StatusBadRequest, nil},
 		{"reset password with invalid token", "/v1/password/reset", "POST", invalidToken, http.StatusBadRequest, nil},
+		{"reset password with other user's token", "/v1/password/reset", "POST", otherUser, http.StatusBadRequest, nil},
+		{"reset password with token forged by empty key", "/v1/password/reset", "POST", forgedToken, http.StatusBadRequest, nil},
 		{"reset password good params", "/v1/password/reset", "POST", goodParams, http.StatusNoContent, nil},
+		{"reset password with used token", "/v1/password/reset", "POST", goodParams, http.StatusBadRequest, nil},
+	}
+
+	runHandlerTestCases(testCases, h, t)
+	if u.TokenVersion != 1 {
+		t.Error("expected all sessions to be revoked after password reset")
+	}
+}
+
+func TestAuthHandler_socialSignIn(t *testing.T) {
+	h := mux.NewRouter()
+
+	// new user repo
+	ur := &mockdb.User{}
+
+	ah := NewAuthHandler(ur, newTestSocialAuth(ur), usecases.NewEmails(infra.NewFakeMail(), "en"), newTestTokens(ur), newTestGuard())
+	ah.SetRoutes(h)
+
+	var (
+		goodParams, _   = json.Marshal(socialSignInForm{socialtest.Token})
+		invalidToken, _ = json.Marshal(socialSignInForm{"invalid token"})
+	)
+
+	testCases := []testCase{
+		{"social sign in with no params", "/v1/auth/social/facebook", "POST", nil, http.StatusBadRequest, nil},
+		{"social sign in with invalid token", "/v1/auth/social/facebook", "POST", invalidToken, http.StatusBadRequest, nil},
+		{"social sign in with unknown provider", "/v1/auth/social/myspace", "POST", goodParams, http.StatusNotFound, nil},
+		{"social sign in as new user", "/v1/auth/social/facebook", "POST", goodParams, http.StatusCreated, nil},
+		{"social sign in as linked user", "/v1/auth/social/facebook", "POST", goodParams, http.StatusOK, nil},
+		{"social providers", "/v1/auth/social", "GET", nil, http.StatusOK, nil},
 	}
 
 	runHandlerTestCases(testCases, h, t)
 }
 
-func TestAuthHandler_registerFacebook(t *testing.T) {
+func TestAuthHandler_refresh(t *testing.T) {
 	h := mux.NewRouter()
 
 	// new user repo
 	ur := &mockdb.User{}
 
-	ah := NewAuthHandler(ur, usecases.NewMockSocialAuth())
+	// add a valid user.
+	var u app.User
+	u.Email = "activeuser@gmail.com"
+	u.SetPassword("good password")
+	u.IsActivated = true
+	if err := ur.Create(&u); err != nil {
+		t.Fatal(err)
+	}
+
+	ah := NewAuthHandler(ur, newTestSocialAuth(ur), usecases.NewEmails(infra.NewFakeMail(), "en"), newTestTokens(ur), newTestGuard())
 	ah.SetRoutes(h)
 
+	first := login(t, h, "activeuser@gmail.com", "good password")
+	second := refresh(t, h, first.RefreshToken, http.StatusOK)
+	if second.RefreshToken == "" || second.RefreshToken == first.RefreshToken {
+		t.Fatalf("expected a new refresh token got %q", second.RefreshToken)
+	}
+
 	var (
-		goodParams, _   = json.Marshal(registerFacebook{"valid token"})
-		invalidToken, _ = json.Marshal(registerFacebook{"invalid token"})
+		invalidToken, _ = json.Marshal(refreshTokenForm{"bad token"})
+		firstToken, _   = json.Marshal(refreshTokenForm{first.RefreshToken})
+		secondToken, _  = json.Marshal(refreshTokenForm{second.RefreshToken})
 	)
 
 	testCases := []testCase{
-		{"register facebook with no params", "/v1/auth/register-fb", "POST", nil, http.StatusBadRequest, nil},
-		{"register facebook with invalid token", "/v1/auth/register-fb", "POST", invalidToken, http.StatusBadRequest, nil},
-		{"register facebook good params", "/v1/auth/register-fb", "POST", goodParams, http.StatusCreated, nil},
+		{"refresh with no params", "/v1/auth/refresh", "POST", nil, http.StatusUnauthorized, nil},
+		{"refresh with invalid token", "/v1/auth/refresh", "POST", invalidToken, http.StatusUnauthorized, nil},
+		{"refresh with rotated token", "/v1/auth/refresh", "POST", firstToken, http.StatusUnauthorized, nil},
+		{"refresh with token of revoked family", "/v1/auth/refresh", "POST", secondToken, http.StatusUnauthorized, nil},
 	}
 
 	runHandlerTestCases(testCases, h, t)
 }
+
+func TestAuthHandler_logout(t *testing.T) {
+	h := mux.NewRouter()
+
+	// new user repo
+	ur := &mockdb.User{}
+
+	// add a valid user.
+	var u app.User
+	u.Email = "activeuser@gmail.com"
+	u.SetPassword("good password")
+	u.IsActivated = true
+	if err := ur.Create(&u); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestTokens(ur)
+	ah := NewAuthHandler(ur, newTestSocialAuth(ur), usecases.NewEmails(infra.NewFakeMail(), "en"), ts, newTestGuard())
+	ah.SetRoutes(h)
+
+	tr := login(t, h, "activeuser@gmail.com", "good password")
+	other := login(t, h, "activeuser@gmail.com", "good password")
+
+	body, _ := json.Marshal(refreshTokenForm{tr.RefreshToken})
+	r, err := http.NewRequest("POST", "/v1/auth/logout", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Authorization", "Bearer "+tr.Token)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("logout expected status code %d got %d", http.StatusNoContent, w.Code)
+	}
+
+	if _, err := ts.Authenticate(tr.Token); err == nil {
+		t.Error("expected access token to be revoked after logout")
+	}
+	refresh(t, h, tr.RefreshToken, http.StatusUnauthorized)
+
+	// other sessions aren't affected
+	if _, err := ts.Authenticate(other.Token); err != nil {
+		t.Errorf("expected other session's access token to be valid got %v", err)
+	}
+	refresh(t, h, other.RefreshToken, http.StatusOK)
+}
+
+func newTestTokens(ur *mockdb.User) *usecases.Tokens {
+	ks, err := infra.NewKeySet(infra.NewHMACKey("test", []byte("secret")))
+	if err != nil {
+		panic(err)
+	}
+	return usecases.NewTokens(&mockdb.Token{Users: ur}, ks)
+}
+
+// newTestSocialAuth creates social auth which has a facebook provider accepting only socialtest.Token
+func newTestSocialAuth(ur *mockdb.User) *usecases.SocialAuth {
+	u := &social.User{ID: "1", Email: "user@facebook.com", EmailVerified: true, FirstName: "Mark", LastName: "Zuckerberg"}
+	return usecases.NewSocialAuth(ur, social.NewRegistry(socialtest.NewProvider("facebook", u)))
+}
+
+func newTestGuard() *usecases.AttemptGuard {
+	return usecases.NewAttemptGuard(infra.NewMemoryAttemptStore())
+}
+
+func login(t *testing.T, h http.Handler, email, password string) tokenRes {
+	body, _ := json.Marshal(loginForm{email, password})
+	return postToken(t, h, "/v1/auth/login", body, http.StatusOK)
+}
+
+func refresh(t *testing.T, h http.Handler, refreshToken string, code int) tokenRes {
+	body, _ := json.Marshal(refreshTokenForm{refreshToken})
+	return postToken(t, h, "/v1/auth/refresh", body, code)
+}
+
+func postToken(t *testing.T, h http.Handler, url string, body []byte, code int) tokenRes {
+	r, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != code {
+		t.Fatalf("POST %s expected status code %d got %d, body: %s", url, code, w.Code, w.Body)
+	}
+
+	var tr tokenRes
+	if code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tr
+}
+
+type mailRecorder struct {
+	mails []recordedMail
+}
+
+type recordedMail struct {
+	to      []string
+	subject string
+	body    []byte
+}
+
+func (mr *mailRecorder) Send(to []string, subject string, body []byte) error {
+	mr.mails = append(mr.mails, recordedMail{to, subject, body})
+	return nil
+}
+
+func TestAuthHandler_verifyEmail(t *testing.T) {
+	h := mux.NewRouter()
+
+	// new user repo
+	ur := &mockdb.User{}
+
+	// add a user which isn't verified yet.
+	var u app.User
+	u.Email = "newuser@gmail.com"
+	u.SetPassword("good password")
+	if err := ur.Create(&u); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestTokens(ur)
+	ah := NewAuthHandler(ur, newTestSocialAuth(ur), usecases.NewEmails(infra.NewFakeMail(), "en"), ts, newTestGuard())
+	ah.SetRoutes(h)
+
+	token, err := ts.VerificationToken(&u)
+	if err != nil {
+		t.Fatalf("can't generate verification token: %v", err)
+	}
+	resetToken, err := ts.ResetToken(&u)
+	if err != nil {
+		t.Fatalf("can't generate reset password token: %v", err)
+	}
+
+	var (
+		goodParams, _      = json.Marshal(verifyEmailForm{token})
+		invalidToken, _    = json.Marshal(verifyEmailForm{"bad token"})
+		wrongPurposeTkn, _ = json.Marshal(verifyEmailForm{resetToken})
+	)
+
+	testCases := []testCase{
+		{"verify with no params", "/v1/auth/verify", "POST", nil, http.StatusBadRequest, nil},
+		{"verify with invalid token", "/v1/auth/verify", "POST", invalidToken, http.StatusBadRequest, nil},
+		{"verify with reset password token", "/v1/auth/verify", "POST", wrongPurposeTkn, http.StatusBadRequest, nil},
+		{"verify with good params", "/v1/auth/verify", "POST", goodParams, http.StatusNoContent, nil},
+	}
+
+	runHandlerTestCases(testCases, h, t)
+
+	if !u.IsActivated {
+		t.Error("expected user to be activated after verification")
+	}
+}
+
+func TestAuthHandler_verifyEmailChange(t *testing.T) {
+	h := mux.NewRouter()
+
+	// new user repo
+	ur := &mockdb.User{}
+
+	// add a user which is changing its email.
+	var u app.User
+	u.Email = "old@gmail.com"
+	u.PendingEmail = "new@gmail.com"
+	u.IsActivated = true
+	if err := ur.Create(&u); err != nil {
+		t.Fatal(err)
+	}
+
+	// add another user which set the same pending email.
+	var other app.User
+	other.Email = "other@gmail.com"
+	other.PendingEmail = "new@gmail.com"
+	other.IsActivated = true
+	if err := ur.Create(&other); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestTokens(ur)
+	ah := NewAuthHandler(ur, newTestSocialAuth(ur), usecases.NewEmails(infra.NewFakeMail(), "en"), ts, newTestGuard())
+	ah.SetRoutes(h)
+
+	token, err := ts.VerificationToken(&u)
+	if err != nil {
+		t.Fatalf("can't generate verification token: %v", err)
+	}
+	params, _ := json.Marshal(verifyEmailForm{token})
+
+	revoked := other
+	revoked.TokenVersion--
+	revokedToken, err := ts.VerificationToken(&revoked)
+	if err != nil {
+		t.Fatalf("can't generate verification token: %v", err)
+	}
+	revokedParams, _ := json.Marshal(verifyEmailForm{revokedToken})
+
+	testCases := []testCase{
+		{"verify with revoked token", "/v1/auth/verify", "POST", revokedParams, http.StatusBadRequest, nil},
+		{"verify pending email", "/v1/auth/verify", "POST", params, http.StatusNoContent, nil},
+		{"verify pending email again", "/v1/auth/verify", "POST", params, http.StatusNoContent, nil},
+	}
+
+	runHandlerTestCases(testCases, h, t)
+
+	if u.Email != "new@gmail.com" || u.PendingEmail != "" {
+		t.Errorf("expected email to be changed got email %s, pending %s", u.Email, u.PendingEmail)
+	}
+	if other.Email != "other@gmail.com" || other.PendingEmail != "new@gmail.com" {
+		t.Errorf("expected other user's email not to be changed got email %s, pending %s", other.Email, other.PendingEmail)
+	}
+}
+
+func TestAuthHandler_resendVerification(t *testing.T) {
+	h := mux.NewRouter()
+
+	// new user repo
+	ur := &mockdb.User{}
+
+	// add a user which isn't verified yet.
+	var u app.User
+	u.Email = "newuser@gmail.com"
+	u.SetPassword("good password")
+	if err := ur.Create(&u); err != nil {
+		t.Fatal(err)
+	}
+	// add a verified user.
+	var u2 app.User
+	u2.Email = "activeuser@gmail.com"
+	u2.SetPassword("good password")
+	u2.IsActivated = true
+	if err := ur.Create(&u2); err != nil {
+		t.Fatal(err)
+	}
+
+	ms := &mailRecorder{}
+	ah := NewAuthHandler(ur, newTestSocialAuth(ur), usecases.NewEmails(ms, "en"), newTestTokens(ur), newTestGuard())
+	ah.VerifyURL = "https://gocart.example/verify"
+	ah.ResetURL = "https://gocart.example/password/reset"
+	ah.SetRoutes(h)
+
+	var (
+		goodParams, _     = json.Marshal(resendVerificationForm{Email: "newuser@gmail.com"})
+		verifiedParams, _ = json.Marshal(resendVerificationForm{Email: "activeuser@gmail.com"})
+		unknownParams, _  = json.Marshal(resendVerificationForm{Email: "unknown@gmail.com"})
+	)
+
+	testCases := []testCase{
+		{"resend with no params", "/v1/auth/verify/resend", "POST", nil, http.StatusBadRequest, nil},
+		{"resend to unknown email", "/v1/auth/verify/resend", "POST", unknownParams, http.StatusBadRequest, nil},
+		{"resend to verified email", "/v1/auth/verify/resend", "POST", verifiedParams, http.StatusBadRequest, nil},
+		{"resend with good params", "/v1/auth/verify/resend", "POST", goodParams, http.StatusNoContent, nil},
+		{"resend again too soon", "/v1/auth/verify/resend", "POST", goodParams, http.StatusTooManyRequests, nil},
+	}
+
+	runHandlerTestCases(testCases, h, t)
+
+	if len(ms.mails) != 1 {
+		t.Fatalf("expected 1 verification mail got %d", len(ms.mails))
+	}
+	if !strings.Contains(string(ms.mails[0].body), "token=") {
+		t.Errorf("expected verification mail to contain the link got %s", ms.mails[0].body)
+	}
+}
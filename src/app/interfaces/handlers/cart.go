@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"app"
+	"net/http"
+
+	"app/usecases"
+
+	"github.com/alioygur/gores"
+	"github.com/gorilla/mux"
+	"github.com/justinas/alice"
+)
+
+type cartService interface {
+	Cart(*app.User) (*app.Cart, error)
+	AddItem(*app.User, *usecases.CartItemForm) (*app.Cart, error)
+	UpdateItem(*app.User, *usecases.CartItemForm) (*app.Cart, error)
+	RemoveItem(u *app.User, id int) (*app.Cart, error)
+	Clear(*app.User) error
+}
+
+func NewCart(srv cartService, eh app.ErrorHandler) *Cart {
+	return &Cart{srv, eh}
+}
+
+type Cart struct {
+	srv cartService
+	eh  app.ErrorHandler
+}
+
+func (ch *Cart) SetRoutes(r *mux.Router, mid ...alice.Constructor) {
+	h := alice.New(mid...)
+	r.Handle("/v1/cart", h.ThenFunc(ch.getCart)).Methods("GET")
+	r.Handle("/v1/cart", h.ThenFunc(ch.clearCart)).Methods("DELETE")
+	r.Handle("/v1/cart/items", h.ThenFunc(ch.addItem)).Methods("POST")
+	r.Handle("/v1/cart/items/{id:[0-9]+}", h.ThenFunc(ch.updateItem)).Methods("PATCH", "PUT")
+	r.Handle("/v1/cart/items/{id:[0-9]+}", h.ThenFunc(ch.removeItem)).Methods("DELETE")
+}
+
+func (ch *Cart) getCart(w http.ResponseWriter, r *http.Request) {
+	u := app.UserMustFromContext(r.Context())
+
+	c, err := ch.srv.Cart(u)
+	if err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newCartRes(c)})
+}
+
+func (ch *Cart) addItem(w http.ResponseWriter, r *http.Request) {
+	f := new(usecases.CartItemForm)
+	if err := decodeReq(r, f); err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	u := app.UserMustFromContext(r.Context())
+
+	c, err := ch.srv.AddItem(u, f)
+	if err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusCreated, response{newCartRes(c)})
+}
+
+func (ch *Cart) updateItem(w http.ResponseWriter, r *http.Request) {
+	f := new(usecases.CartItemForm)
+	if err := decodeReq(r, f); err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	f.ID = muxVarMustInt("id", r)
+	u := app.UserMustFromContext(r.Context())
+
+	c, err := ch.srv.UpdateItem(u, f)
+	if err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newCartRes(c)})
+}
+
+func (ch *Cart) removeItem(w http.ResponseWriter, r *http.Request) {
+	id := muxVarMustInt("id", r)
+	u := app.UserMustFromContext(r.Context())
+
+	c, err := ch.srv.RemoveItem(u, id)
+	if err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newCartRes(c)})
+}
+
+func (ch *Cart) clearCart(w http.ResponseWriter, r *http.Request) {
+	u := app.UserMustFromContext(r.Context())
+
+	if err := ch.srv.Clear(u); err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	gores.NoContent(w)
+}
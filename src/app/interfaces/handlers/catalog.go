@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"app"
+	"app/interfaces/errs"
 	"net/http"
+	"strconv"
 
 	"strings"
 
@@ -17,30 +19,50 @@ type catalogService interface {
 	OneActiveProduct(id interface{}) (*app.Product, error)
 	FindActiveProducts(*app.DBFilter) ([]app.Product, error)
 	FindActiveProductsByCategory([]interface{}, *app.DBFilter) ([]app.Product, error)
+	CountActiveProducts([]interface{}) (int, error)
 	FindActiveCategories(*app.DBFilter) ([]app.Category, error)
 	CreateProduct(*usecases.ProductForm) (*app.Product, error)
-	DeleteProduct(id interface{}) error
+	DeleteProduct(id int) error
 	UpdateProduct(*usecases.ProductForm) (*app.Product, error)
+	CreateCategory(*usecases.CategoryForm) (*app.Category, error)
+	UpdateCategory(*usecases.CategoryForm) (*app.Category, error)
+	SetCategoryActive(id int, active bool) (*app.Category, error)
+	DeleteCategory(id int) error
 }
 
-func NewCatalog(srv catalogService, eh app.ErrorHandler) *Catalog {
-	return &Catalog{srv, eh}
+type productSearcher interface {
+	Search(*app.SearchQuery) ([]app.Product, int, error)
+}
+
+func NewCatalog(srv catalogService, ps productSearcher, eh app.ErrorHandler) *Catalog {
+	return &Catalog{srv, ps, eh}
 }
 
 type Catalog struct {
 	srv catalogService
+	ps  productSearcher
 	eh  app.ErrorHandler
 }
 
 func (ch *Catalog) SetRoutes(r *mux.Router, mid ...alice.Constructor) {
 	h := alice.New(mid...)
+	r.Handle("/v1/products/search", h.ThenFunc(ch.searchProducts)).Methods("GET")
 	r.Handle("/v1/products/{id}", h.ThenFunc(ch.getProduct)).Methods("GET")
 	r.Handle("/v1/products", h.ThenFunc(ch.getProducts)).Methods("GET")
 	r.Handle("/v1/categories", h.ThenFunc(ch.getCategories)).Methods("GET")
+}
 
+func (ch *Catalog) SetAdminRoutes(r *mux.Router, mid ...alice.Constructor) {
+	h := alice.New(mid...)
 	r.Handle("/v1/admin/products", h.ThenFunc(ch.createProduct)).Methods("POST")
 	r.Handle("/v1/admin/products/{id}", h.ThenFunc(ch.updateProduct)).Methods("PATCH", "PUT")
 	r.Handle("/v1/admin/products/{id}", h.ThenFunc(ch.deleteProduct)).Methods("DELETE")
+
+	r.Handle("/v1/admin/categories", h.ThenFunc(ch.createCategory)).Methods("POST")
+	r.Handle("/v1/admin/categories/{id:[0-9]+}", h.ThenFunc(ch.updateCategory)).Methods("PATCH", "PUT")
+	r.Handle("/v1/admin/categories/{id:[0-9]+}", h.ThenFunc(ch.deleteCategory)).Methods("DELETE")
+	r.Handle("/v1/admin/categories/{id:[0-9]+}/activate", h.ThenFunc(ch.activateCategory)).Methods("POST")
+	r.Handle("/v1/admin/categories/{id:[0-9]+}/deactivate", h.ThenFunc(ch.deactivateCategory)).Methods("POST")
 }
 
 func (ch *Catalog) createProduct(w http.ResponseWriter, r *http.Request) {
@@ -56,7 +78,7 @@ func (ch *Catalog) createProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	gores.JSON(w, http.StatusCreated, response{p})
+	gores.JSON(w, http.StatusCreated, response{newProductRes(p)})
 }
 
 func (ch *Catalog) getProduct(w http.ResponseWriter, r *http.Request) {
@@ -68,22 +90,80 @@ func (ch *Catalog) getProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	gores.JSON(w, http.StatusOK, response{p})
+	gores.JSON(w, http.StatusOK, response{newProductRes(p)})
+}
+
+// productSortFields maps sortable product fields to their columns
+var productSortFields = map[string]string{
+	"price":     "price",
+	"createdAt": "created_at",
+	"title":     "title",
 }
 
 func (ch *Catalog) getProducts(w http.ResponseWriter, r *http.Request) {
+	f, err := qPagination(r)
+	if err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	if err := qSort(r, f, productSortFields); err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
 	cids := qCategoryParam(r)
-	ps, err := func() ([]app.Product, error) {
-		if len(cids) > 0 {
-			return ch.srv.FindActiveProductsByCategory(cids, nil)
+	ps, err := ch.srv.FindActiveProductsByCategory(cids, f)
+	if err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	total, err := ch.srv.CountActiveProducts(cids)
+	if err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, pagedResponse{newProductsRes(ps), newPagination(f, total)})
+}
+
+func (ch *Catalog) searchProducts(w http.ResponseWriter, r *http.Request) {
+	f, err := qPagination(r)
+	if err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	q := app.SearchQuery{Text: qParam("q", r), Limit: f.Limit, Offset: f.Offset}
+	if err := errs.CheckStringLen(q.Text, 2, 100, "q"); err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+	if q.MinPrice, err = qParamPrice("minPrice", r); err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+	if q.MaxPrice, err = qParamPrice("maxPrice", r); err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+	for _, id := range qCategoryParam(r) {
+		cid, err := strconv.Atoi(id.(string))
+		if err != nil {
+			ch.eh.Handle(w, errs.BadRequest("invalid category param"))
+			return
 		}
-		return ch.srv.FindActiveProducts(nil)
-	}()
+		q.Categories = append(q.Categories, cid)
+	}
+
+	ps, total, err := ch.ps.Search(&q)
 	if err != nil {
 		ch.eh.Handle(w, err)
 		return
 	}
-	gores.JSON(w, http.StatusOK, response{ps})
+
+	gores.JSON(w, http.StatusOK, pagedResponse{newProductsRes(ps), newPagination(f, total)})
 }
 
 func (ch *Catalog) updateProduct(w http.ResponseWriter, r *http.Request) {
@@ -101,7 +181,7 @@ func (ch *Catalog) updateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	gores.JSON(w, http.StatusOK, response{p})
+	gores.JSON(w, http.StatusOK, response{newProductRes(p)})
 }
 
 func (ch *Catalog) deleteProduct(w http.ResponseWriter, r *http.Request) {
@@ -122,7 +202,72 @@ func (ch *Catalog) getCategories(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	gores.JSON(w, http.StatusOK, response{cs})
+	gores.JSON(w, http.StatusOK, response{newCategoriesRes(cs)})
+}
+
+func (ch *Catalog) createCategory(w http.ResponseWriter, r *http.Request) {
+	f := new(usecases.CategoryForm)
+	if err := decodeReq(r, f); err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	c, err := ch.srv.CreateCategory(f)
+	if err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusCreated, response{newCategoryRes(c)})
+}
+
+func (ch *Catalog) updateCategory(w http.ResponseWriter, r *http.Request) {
+	f := new(usecases.CategoryForm)
+	if err := decodeReq(r, f); err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	f.ID = muxVarMustInt("id", r)
+
+	c, err := ch.srv.UpdateCategory(f)
+	if err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newCategoryRes(c)})
+}
+
+func (ch *Catalog) deleteCategory(w http.ResponseWriter, r *http.Request) {
+	id := muxVarMustInt("id", r)
+
+	if err := ch.srv.DeleteCategory(id); err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	gores.NoContent(w)
+}
+
+func (ch *Catalog) activateCategory(w http.ResponseWriter, r *http.Request) {
+	ch.setCategoryActive(w, r, true)
+}
+
+func (ch *Catalog) deactivateCategory(w http.ResponseWriter, r *http.Request) {
+	ch.setCategoryActive(w, r, false)
+}
+
+func (ch *Catalog) setCategoryActive(w http.ResponseWriter, r *http.Request, active bool) {
+	id := muxVarMustInt("id", r)
+
+	c, err := ch.srv.SetCategoryActive(id, active)
+	if err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newCategoryRes(c)})
 }
 
 // qCategoryParam gets category param like 1,2,3 as []interface{1, 2, 3}
@@ -139,3 +284,17 @@ func qCategoryParam(r *http.Request) []interface{} {
 	}
 	return cs
 }
+
+// qParamPrice gets price param, returns nil if the param is empty
+func qParamPrice(k string, r *http.Request) (*app.Money, error) {
+	v := qParam(k, r)
+	if v == "" {
+		return nil, nil
+	}
+
+	p, err := app.ParseMoney(v, app.DefaultCurrency)
+	if err != nil || p.Amount < 0 {
+		return nil, errs.BadRequest("invalid %s param", k)
+	}
+	return &p, nil
+}
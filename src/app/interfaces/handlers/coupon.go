@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"app"
+	"app/usecases"
+	"net/http"
+
+	"github.com/alioygur/gores"
+	"github.com/gorilla/mux"
+	"github.com/justinas/alice"
+)
+
+type couponService interface {
+	Coupons(*app.DBFilter) ([]app.Coupon, int, error)
+	Coupon(id int) (*app.Coupon, error)
+	CreateCoupon(*usecases.CouponForm) (*app.Coupon, error)
+	UpdateCoupon(*usecases.CouponForm) (*app.Coupon, error)
+	DeleteCoupon(id int) error
+}
+
+func NewCoupon(srv couponService, eh app.ErrorHandler) *Coupon {
+	return &Coupon{srv, eh}
+}
+
+// Coupon manages coupons
+type Coupon struct {
+	srv couponService
+	eh  app.ErrorHandler
+}
+
+func (ch *Coupon) SetAdminRoutes(r *mux.Router, mid ...alice.Constructor) {
+	h := alice.New(mid...)
+	r.Handle("/v1/admin/coupons", h.ThenFunc(ch.getCoupons)).Methods("GET")
+	r.Handle("/v1/admin/coupons", h.ThenFunc(ch.createCoupon)).Methods("POST")
+	r.Handle("/v1/admin/coupons/{id:[0-9]+}", h.ThenFunc(ch.getCoupon)).Methods("GET")
+	r.Handle("/v1/admin/coupons/{id:[0-9]+}", h.ThenFunc(ch.updateCoupon)).Methods("PATCH", "PUT")
+	r.Handle("/v1/admin/coupons/{id:[0-9]+}", h.ThenFunc(ch.deleteCoupon)).Methods("DELETE")
+}
+
+var couponSortFields = map[string]string{
+	"createdAt": "created_at",
+	"code":      "code",
+	"usedCount": "used_count",
+	"endsAt":    "ends_at",
+}
+
+func (ch *Coupon) getCoupons(w http.ResponseWriter, r *http.Request) {
+	f, err := qPagination(r)
+	if err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	if err := qSort(r, f, couponSortFields); err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	cs, total, err := ch.srv.Coupons(f)
+	if err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, pagedResponse{newCouponsRes(cs), newPagination(f, total)})
+}
+
+func (ch *Coupon) getCoupon(w http.ResponseWriter, r *http.Request) {
+	c, err := ch.srv.Coupon(muxVarMustInt("id", r))
+	if err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newCouponRes(c)})
+}
+
+func (ch *Coupon) createCoupon(w http.ResponseWriter, r *http.Request) {
+	f := new(usecases.CouponForm)
+	if err := decodeReq(r, f); err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	c, err := ch.srv.CreateCoupon(f)
+	if err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusCreated, response{newCouponRes(c)})
+}
+
+func (ch *Coupon) updateCoupon(w http.ResponseWriter, r *http.Request) {
+	f := new(usecases.CouponForm)
+	if err := decodeReq(r, f); err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	f.ID = muxVarMustInt("id", r)
+
+	c, err := ch.srv.UpdateCoupon(f)
+	if err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newCouponRes(c)})
+}
+
+func (ch *Coupon) deleteCoupon(w http.ResponseWriter, r *http.Request) {
+	if err := ch.srv.DeleteCoupon(muxVarMustInt("id", r)); err != nil {
+		ch.eh.Handle(w, err)
+		return
+	}
+
+	gores.NoContent(w)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"app"
+	"net/http"
+
+	"app/usecases"
+
+	"github.com/alioygur/gores"
+	"github.com/gorilla/mux"
+	"github.com/justinas/alice"
+)
+
+type emailPreviewer interface {
+	Names() []string
+	Preview(name, locale string) (*usecases.RenderedEmail, error)
+}
+
+func NewEmail(srv emailPreviewer, eh app.ErrorHandler) *Email {
+	return &Email{srv, eh}
+}
+
+type Email struct {
+	srv emailPreviewer
+	eh  app.ErrorHandler
+}
+
+func (mh *Email) SetAdminRoutes(r *mux.Router, mid ...alice.Constructor) {
+	h := alice.New(mid...)
+	r.Handle("/v1/admin/emails", h.ThenFunc(mh.getNames)).Methods("GET")
+	r.Handle("/v1/admin/emails/{name}/preview", h.ThenFunc(mh.preview)).Methods("GET")
+}
+
+func (mh *Email) getNames(w http.ResponseWriter, r *http.Request) {
+	gores.JSON(w, http.StatusOK, response{mh.srv.Names()})
+}
+
+// preview renders the email with sample data. format=html or format=text
+// params render the related part as is, otherwise all parts are returned as json.
+func (mh *Email) preview(w http.ResponseWriter, r *http.Request) {
+	locale := qParam("locale", r)
+	if locale == "" {
+		locale = reqLocale(r)
+	}
+
+	e, err := mh.srv.Preview(mux.Vars(r)["name"], locale)
+	if err != nil {
+		mh.eh.Handle(w, err)
+		return
+	}
+
+	switch qParam("format", r) {
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(e.HTML))
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(e.Text))
+	default:
+		gores.JSON(w, http.StatusOK, response{e})
+	}
+}
@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"app"
 	"app/interfaces/errs"
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"fmt"
 
@@ -28,6 +30,24 @@ type response struct {
 	Result interface{} `json:"result"`
 }
 
+type pagedResponse struct {
+	Result interface{} `json:"result"`
+	Meta   pagination  `json:"meta"`
+}
+
+type pagination struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"perPage"`
+	Total      int `json:"total"`
+	TotalPages int `json:"totalPages"`
+}
+
+func newPagination(f *app.DBFilter, total int) pagination {
+	p := pagination{Page: f.Offset/f.Limit + 1, PerPage: f.Limit, Total: total}
+	p.TotalPages = (total + f.Limit - 1) / f.Limit
+	return p
+}
+
 func qParam(k string, r *http.Request) string {
 	values := r.URL.Query()[k]
 
@@ -38,6 +58,102 @@ func qParam(k string, r *http.Request) string {
 	return ""
 }
 
+// reqLocale gets the most preferred locale from Accept-Language header like "tr-TR,tr;q=0.8"
+func reqLocale(r *http.Request) string {
+	al := r.Header.Get("Accept-Language")
+	return strings.TrimSpace(strings.Split(strings.Split(al, ",")[0], ";")[0])
+}
+
+// bearerToken gets the token from Authorization header, returns empty string if it hasn't one
+func bearerToken(r *http.Request) string {
+	parts := strings.Fields(r.Header.Get("Authorization"))
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return ""
+	}
+	return parts[1]
+}
+
+// qParamInt gets query param as int, returns zero if the param is empty
+func qParamInt(k string, r *http.Request) (int, error) {
+	v := qParam(k, r)
+	if v == "" {
+		return 0, nil
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, errs.BadRequest("invalid %s param", k)
+	}
+	return i, nil
+}
+
+// qParamBool gets query param as bool, returns nil if the param is empty
+func qParamBool(k string, r *http.Request) (*bool, error) {
+	v := qParam(k, r)
+	if v == "" {
+		return nil, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, errs.BadRequest("invalid %s param", k)
+	}
+	return &b, nil
+}
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// qPagination gets page and perPage params as db filter
+func qPagination(r *http.Request) (*app.DBFilter, error) {
+	page, perPage := 1, defaultPerPage
+
+	if v := qParam("page", r); v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 1 {
+			return nil, errs.BadRequest("invalid page param")
+		}
+		page = i
+	}
+
+	if v := qParam("perPage", r); v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 1 || i > maxPerPage {
+			return nil, errs.BadRequest("perPage param must between 1 and %d", maxPerPage)
+		}
+		perPage = i
+	}
+
+	return &app.DBFilter{Limit: perPage, Offset: (page - 1) * perPage}, nil
+}
+
+// qSort sets filter's order by sort and order params.
+// fields maps allowed sort params to db columns.
+func qSort(r *http.Request, f *app.DBFilter, fields map[string]string) error {
+	sort := qParam("sort", r)
+	if sort == "" {
+		return nil
+	}
+
+	col, ok := fields[sort]
+	if !ok {
+		return errs.BadRequest("invalid sort param: %s", sort)
+	}
+	f.OrderBy = col
+
+	switch qParam("order", r) {
+	case "", "asc":
+		f.Reverse = false
+	case "desc":
+		f.Reverse = true
+	default:
+		return errs.BadRequest("order param must be asc or desc")
+	}
+	return nil
+}
+
 func muxVarMustInt(k string, r *http.Request) int {
 	i, err := strconv.Atoi(mux.Vars(r)[k])
 	if err != nil {
@@ -55,6 +171,7 @@ func (fn appHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if ok {
 			code = appErr.HTTPCode
 		}
+		errs.SetHeaders(w, err)
 		gores.String(w, code, fmt.Sprintf("%+v", err))
 	}
 }
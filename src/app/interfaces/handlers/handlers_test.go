@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"app"
 	"bytes"
 	"net/http"
 	"testing"
@@ -64,6 +65,109 @@ func TestQparam(t *testing.T) {
 
 }
 
+func TestReqLocale(t *testing.T) {
+	var tests = []struct {
+		header   string
+		expected string
+	}{
+		{"", ""},
+		{"tr", "tr"},
+		{"tr-TR,tr;q=0.9,en;q=0.8", "tr-TR"},
+		{"en;q=0.8", "en"},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept-Language", test.header)
+
+		if l := reqLocale(req); l != test.expected {
+			t.Errorf("Expected reqLocale(%q) to be %q got %q", test.header, test.expected, l)
+		}
+	}
+}
+
+func TestQPagination(t *testing.T) {
+	var tests = []struct {
+		url            string
+		expectedLimit  int
+		expectedOffset int
+		expectedErr    bool
+	}{
+		{"/", defaultPerPage, 0, false},
+		{"/?page=3", defaultPerPage, defaultPerPage * 2, false},
+		{"/?page=2&perPage=5", 5, 5, false},
+		{"/?page=0", 0, 0, true},
+		{"/?page=a", 0, 0, true},
+		{"/?perPage=1000", 0, 0, true},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest("GET", test.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := qPagination(req)
+		if test.expectedErr {
+			if err == nil {
+				t.Errorf("Expected qPagination(%q) to return error", test.url)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Expected qPagination(%q) to return no error got %v", test.url, err)
+			continue
+		}
+		if f.Limit != test.expectedLimit || f.Offset != test.expectedOffset {
+			t.Errorf("Expected qPagination(%q) to be limit %d offset %d got limit %d offset %d", test.url, test.expectedLimit, test.expectedOffset, f.Limit, f.Offset)
+		}
+	}
+}
+
+func TestQSort(t *testing.T) {
+	fields := map[string]string{"price": "price", "createdAt": "created_at"}
+
+	var tests = []struct {
+		url             string
+		expectedOrderBy string
+		expectedReverse bool
+		expectedErr     bool
+	}{
+		{"/", "", false, false},
+		{"/?sort=price", "price", false, false},
+		{"/?sort=createdAt&order=desc", "created_at", true, false},
+		{"/?sort=createdAt&order=asc", "created_at", false, false},
+		{"/?sort=password", "", false, true},
+		{"/?sort=price&order=random", "", false, true},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest("GET", test.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		f := new(app.DBFilter)
+		err = qSort(req, f, fields)
+		if test.expectedErr {
+			if err == nil {
+				t.Errorf("Expected qSort(%q) to return error", test.url)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Expected qSort(%q) to return no error got %v", test.url, err)
+			continue
+		}
+		if f.OrderBy != test.expectedOrderBy || f.Reverse != test.expectedReverse {
+			t.Errorf("Expected qSort(%q) to be %q reverse %v got %q reverse %v", test.url, test.expectedOrderBy, test.expectedReverse, f.OrderBy, f.Reverse)
+		}
+	}
+}
+
 func TestMuxVarMustInt(t *testing.T) {
 	req, err := http.NewRequest("GET", "/users/1", nil)
 	if err != nil {
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"app"
+	"net/http"
+
+	"github.com/alioygur/gores"
+	"github.com/gorilla/mux"
+	"github.com/justinas/alice"
+)
+
+type identityService interface {
+	Identities(*app.User) ([]app.SocialIdentity, error)
+	Link(u *app.User, provider, token string) (*app.SocialIdentity, error)
+	Unlink(u *app.User, id int) error
+}
+
+func NewIdentity(srv identityService, eh app.ErrorHandler) *Identity {
+	return &Identity{srv, eh}
+}
+
+// Identity manages signed in user's linked social accounts
+type Identity struct {
+	srv identityService
+	eh  app.ErrorHandler
+}
+
+func (ih *Identity) SetRoutes(r *mux.Router, mid ...alice.Constructor) {
+	h := alice.New(mid...)
+	r.Handle("/v1/me/identities", h.ThenFunc(ih.getIdentities)).Methods("GET")
+	r.Handle("/v1/me/identities/{provider:[a-z]+}", h.ThenFunc(ih.linkIdentity)).Methods("POST")
+	r.Handle("/v1/me/identities/{id:[0-9]+}", h.ThenFunc(ih.unlinkIdentity)).Methods("DELETE")
+}
+
+func (ih *Identity) getIdentities(w http.ResponseWriter, r *http.Request) {
+	u := app.UserMustFromContext(r.Context())
+
+	is, err := ih.srv.Identities(u)
+	if err != nil {
+		ih.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newIdentitiesRes(is)})
+}
+
+func (ih *Identity) linkIdentity(w http.ResponseWriter, r *http.Request) {
+	f := new(socialSignInForm)
+	if err := decodeReq(r, f); err != nil {
+		ih.eh.Handle(w, err)
+		return
+	}
+
+	u := app.UserMustFromContext(r.Context())
+
+	i, err := ih.srv.Link(u, mux.Vars(r)["provider"], f.AccessToken)
+	if err != nil {
+		ih.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusCreated, response{newIdentityRes(i)})
+}
+
+func (ih *Identity) unlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	id := muxVarMustInt("id", r)
+	u := app.UserMustFromContext(r.Context())
+
+	if err := ih.srv.Unlink(u, id); err != nil {
+		ih.eh.Handle(w, err)
+		return
+	}
+
+	gores.NoContent(w)
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"app"
+	"net/http"
+
+	"github.com/alioygur/gores"
+	"github.com/gorilla/mux"
+)
+
+type jwksProvider interface {
+	JWKS() *app.JWKSet
+}
+
+func NewJWKS(kp jwksProvider) *JWKS {
+	return &JWKS{kp}
+}
+
+// JWKS publishes public keys of tokens so other services can verify them
+type JWKS struct {
+	kp jwksProvider
+}
+
+func (jh *JWKS) SetRoutes(r *mux.Router) {
+	r.HandleFunc("/.well-known/jwks.json", jh.getKeys).Methods("GET")
+}
+
+// getKeys responds the key set as is, not wrapped with result as other responses
+func (jh *JWKS) getKeys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	gores.JSON(w, http.StatusOK, jh.kp.JWKS())
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"app"
+	"app/usecases"
+	"net/http"
+
+	"github.com/alioygur/gores"
+	"github.com/gorilla/mux"
+	"github.com/justinas/alice"
+)
+
+type optionService interface {
+	OptionGroups(productID int) ([]app.OptionGroup, error)
+	CreateOptionGroup(*usecases.OptionGroupForm) (*app.OptionGroup, error)
+	UpdateOptionGroup(*usecases.OptionGroupForm) (*app.OptionGroup, error)
+	DeleteOptionGroup(id int) error
+	CreateOptionValue(*usecases.OptionValueForm) (*app.OptionValue, error)
+	UpdateOptionValue(*usecases.OptionValueForm) (*app.OptionValue, error)
+	DeleteOptionValue(id int) error
+}
+
+func NewOption(srv optionService, eh app.ErrorHandler) *Option {
+	return &Option{srv, eh}
+}
+
+// Option manages products' option groups and values
+type Option struct {
+	srv optionService
+	eh  app.ErrorHandler
+}
+
+func (oh *Option) SetAdminRoutes(r *mux.Router, mid ...alice.Constructor) {
+	h := alice.New(mid...)
+	r.Handle("/v1/admin/products/{id:[0-9]+}/options", h.ThenFunc(oh.getOptionGroups)).Methods("GET")
+	r.Handle("/v1/admin/products/{id:[0-9]+}/options", h.ThenFunc(oh.createOptionGroup)).Methods("POST")
+	r.Handle("/v1/admin/options/{id:[0-9]+}", h.ThenFunc(oh.updateOptionGroup)).Methods("PATCH", "PUT")
+	r.Handle("/v1/admin/options/{id:[0-9]+}", h.ThenFunc(oh.deleteOptionGroup)).Methods("DELETE")
+	r.Handle("/v1/admin/options/{id:[0-9]+}/values", h.ThenFunc(oh.createOptionValue)).Methods("POST")
+	r.Handle("/v1/admin/option-values/{id:[0-9]+}", h.ThenFunc(oh.updateOptionValue)).Methods("PATCH", "PUT")
+	r.Handle("/v1/admin/option-values/{id:[0-9]+}", h.ThenFunc(oh.deleteOptionValue)).Methods("DELETE")
+}
+
+func (oh *Option) getOptionGroups(w http.ResponseWriter, r *http.Request) {
+	gs, err := oh.srv.OptionGroups(muxVarMustInt("id", r))
+	if err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	res := make([]optionGroupRes, len(gs))
+	for i := range gs {
+		res[i] = *newOptionGroupRes(&gs[i])
+	}
+
+	gores.JSON(w, http.StatusOK, response{res})
+}
+
+func (oh *Option) createOptionGroup(w http.ResponseWriter, r *http.Request) {
+	f := new(usecases.OptionGroupForm)
+	if err := decodeReq(r, f); err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	f.ProductID = muxVarMustInt("id", r)
+
+	g, err := oh.srv.CreateOptionGroup(f)
+	if err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusCreated, response{newOptionGroupRes(g)})
+}
+
+func (oh *Option) updateOptionGroup(w http.ResponseWriter, r *http.Request) {
+	f := new(usecases.OptionGroupForm)
+	if err := decodeReq(r, f); err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	f.ID = muxVarMustInt("id", r)
+
+	g, err := oh.srv.UpdateOptionGroup(f)
+	if err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newOptionGroupRes(g)})
+}
+
+func (oh *Option) deleteOptionGroup(w http.ResponseWriter, r *http.Request) {
+	if err := oh.srv.DeleteOptionGroup(muxVarMustInt("id", r)); err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	gores.NoContent(w)
+}
+
+func (oh *Option) createOptionValue(w http.ResponseWriter, r *http.Request) {
+	f := new(usecases.OptionValueForm)
+	if err := decodeReq(r, f); err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	f.GroupID = muxVarMustInt("id", r)
+
+	v, err := oh.srv.CreateOptionValue(f)
+	if err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusCreated, response{newOptionValueRes(v)})
+}
+
+func (oh *Option) updateOptionValue(w http.ResponseWriter, r *http.Request) {
+	f := new(usecases.OptionValueForm)
+	if err := decodeReq(r, f); err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	f.ID = muxVarMustInt("id", r)
+
+	v, err := oh.srv.UpdateOptionValue(f)
+	if err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newOptionValueRes(v)})
+}
+
+func (oh *Option) deleteOptionValue(w http.ResponseWriter, r *http.Request) {
+	if err := oh.srv.DeleteOptionValue(muxVarMustInt("id", r)); err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	gores.NoContent(w)
+}
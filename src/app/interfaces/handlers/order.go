@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"app"
+	"net/http"
+
+	"app/usecases"
+
+	"github.com/alioygur/gores"
+	"github.com/gorilla/mux"
+	"github.com/justinas/alice"
+)
+
+type checkoutService interface {
+	PlaceOrder(*app.User, *usecases.CheckoutForm) (*app.Order, error)
+}
+
+type orderService interface {
+	UserOrders(*app.User, *app.DBFilter) ([]app.Order, error)
+	UserOrder(u *app.User, id int) (*app.Order, error)
+	Orders(*usecases.OrderFilterForm, *app.DBFilter) ([]app.Order, error)
+	Order(id int) (*app.Order, error)
+	ChangeStatus(*app.User, *usecases.OrderStatusForm) (*app.Order, error)
+}
+
+func NewOrder(cs checkoutService, srv orderService, eh app.ErrorHandler) *Order {
+	return &Order{cs, srv, eh}
+}
+
+type Order struct {
+	cs  checkoutService
+	srv orderService
+	eh  app.ErrorHandler
+}
+
+func (oh *Order) SetRoutes(r *mux.Router, mid ...alice.Constructor) {
+	h := alice.New(mid...)
+	r.Handle("/v1/orders", h.ThenFunc(oh.placeOrder)).Methods("POST")
+	r.Handle("/v1/me/orders", h.ThenFunc(oh.getMyOrders)).Methods("GET")
+	r.Handle("/v1/me/orders/{id:[0-9]+}", h.ThenFunc(oh.getMyOrder)).Methods("GET")
+}
+
+func (oh *Order) SetAdminRoutes(r *mux.Router, mid ...alice.Constructor) {
+	h := alice.New(mid...)
+	r.Handle("/v1/admin/orders", h.ThenFunc(oh.getOrders)).Methods("GET")
+	r.Handle("/v1/admin/orders/{id:[0-9]+}", h.ThenFunc(oh.getOrder)).Methods("GET")
+	r.Handle("/v1/admin/orders/{id:[0-9]+}/status", h.ThenFunc(oh.changeStatus)).Methods("POST")
+}
+
+func (oh *Order) placeOrder(w http.ResponseWriter, r *http.Request) {
+	f := new(usecases.CheckoutForm)
+	if err := decodeReq(r, f); err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	u := app.UserMustFromContext(r.Context())
+
+	o, err := oh.cs.PlaceOrder(u, f)
+	if err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusCreated, response{newOrderRes(o)})
+}
+
+func (oh *Order) getMyOrders(w http.ResponseWriter, r *http.Request) {
+	f, err := qPagination(r)
+	if err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	u := app.UserMustFromContext(r.Context())
+
+	os, err := oh.srv.UserOrders(u, f)
+	if err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newOrdersRes(os)})
+}
+
+func (oh *Order) getMyOrder(w http.ResponseWriter, r *http.Request) {
+	id := muxVarMustInt("id", r)
+	u := app.UserMustFromContext(r.Context())
+
+	o, err := oh.srv.UserOrder(u, id)
+	if err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newOrderRes(o)})
+}
+
+func (oh *Order) getOrders(w http.ResponseWriter, r *http.Request) {
+	f, err := qPagination(r)
+	if err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	ff := new(usecases.OrderFilterForm)
+	if ff.StatusID, err = qParamInt("status", r); err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+	if ff.UserID, err = qParamInt("user", r); err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	os, err := oh.srv.Orders(ff, f)
+	if err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newOrdersRes(os)})
+}
+
+func (oh *Order) getOrder(w http.ResponseWriter, r *http.Request) {
+	id := muxVarMustInt("id", r)
+
+	o, err := oh.srv.Order(id)
+	if err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newOrderRes(o)})
+}
+
+func (oh *Order) changeStatus(w http.ResponseWriter, r *http.Request) {
+	f := new(usecases.OrderStatusForm)
+	if err := decodeReq(r, f); err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	f.ID = muxVarMustInt("id", r)
+	u := app.UserMustFromContext(r.Context())
+
+	o, err := oh.srv.ChangeStatus(u, f)
+	if err != nil {
+		oh.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newOrderRes(o)})
+}
@@ -0,0 +1,533 @@
+package handlers
+
+import (
+	"app"
+	"app/usecases"
+	"time"
+)
+
+// Response types of domain models. Handlers respond these instead of the models,
+// so internal fields of the models are never exposed.
+
+type userRes struct {
+	ID           int       `json:"id"`
+	FirstName    string    `json:"firstName"`
+	LastName     string    `json:"lastName"`
+	Email        string    `json:"email"`
+	PendingEmail string    `json:"pendingEmail,omitempty"`
+	IsActivated  bool      `json:"isActivated"`
+	IsAdmin      bool      `json:"isAdmin"`
+	Role         string    `json:"role,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+func newUserRes(u *app.User) *userRes {
+	return &userRes{
+		ID:           u.ID,
+		FirstName:    u.FirstName,
+		LastName:     u.LastName,
+		Email:        u.Email,
+		PendingEmail: u.PendingEmail,
+		IsActivated:  u.IsActivated,
+		IsAdmin:      u.IsAdmin,
+		Role:         u.Role,
+		CreatedAt:    u.CreatedAt,
+	}
+}
+
+type userDetailRes struct {
+	*userRes
+	Addresses []addressRes `json:"addresses"`
+	Orders    []orderRes   `json:"orders"`
+}
+
+func newUserDetailRes(d *usecases.UserDetail) *userDetailRes {
+	return &userDetailRes{userRes: newUserRes(d.User), Addresses: newAddressesRes(d.Addresses), Orders: newOrdersRes(d.Orders)}
+}
+
+type addressRes struct {
+	ID      int  `json:"id"`
+	Default bool `json:"default"`
+	app.AddressBody
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func newAddressRes(a *app.Address) *addressRes {
+	return &addressRes{
+		ID:          a.ID,
+		Default:     a.Default,
+		AddressBody: a.AddressBody,
+		CreatedAt:   a.CreatedAt,
+		UpdatedAt:   a.UpdatedAt,
+	}
+}
+
+func newAddressesRes(as []app.Address) []addressRes {
+	res := make([]addressRes, len(as))
+	for i := range as {
+		res[i] = *newAddressRes(&as[i])
+	}
+	return res
+}
+
+type identityRes struct {
+	ID        int       `json:"id"`
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func newIdentityRes(i *app.SocialIdentity) *identityRes {
+	return &identityRes{ID: i.ID, Provider: i.Provider, Email: i.Email, CreatedAt: i.CreatedAt}
+}
+
+func newIdentitiesRes(is []app.SocialIdentity) []identityRes {
+	res := make([]identityRes, len(is))
+	for i := range is {
+		res[i] = *newIdentityRes(&is[i])
+	}
+	return res
+}
+
+type imageRes struct {
+	ID           int    `json:"id"`
+	PublicID     string `json:"publicId"`
+	ResourceType string `json:"resourceType"`
+}
+
+func newImageRes(i *app.Image) *imageRes {
+	if i == nil {
+		return nil
+	}
+	return &imageRes{ID: i.ID, PublicID: i.PublicID, ResourceType: i.ResourceType}
+}
+
+type productRes struct {
+	ID          int              `json:"id"`
+	Title       string           `json:"title"`
+	Description string           `json:"description"`
+	Price       app.Money        `json:"price"`
+	IsActive    bool             `json:"isActive"`
+	TaxClassID  int              `json:"taxClassId,omitempty"`
+	TrackStock  bool             `json:"trackStock"`
+	InStock     bool             `json:"inStock"`
+	Available   *int             `json:"available,omitempty"`
+	Image       *imageRes        `json:"defaultImage,omitempty"`
+	Categories  []categoryRes    `json:"categories,omitempty"`
+	Options     []optionGroupRes `json:"optionGroups,omitempty"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	UpdatedAt   time.Time        `json:"updatedAt"`
+}
+
+func newProductRes(p *app.Product) *productRes {
+	if p == nil {
+		return nil
+	}
+	pr := &productRes{
+		ID:          p.ID,
+		Title:       p.Title,
+		Description: p.Description,
+		Price:       p.Price,
+		IsActive:    p.IsActive,
+		TaxClassID:  p.TaxClassID,
+		TrackStock:  p.TrackStock,
+		InStock:     !p.TrackStock || p.Available() > 0,
+		Image:       newImageRes(p.Image),
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+	if p.TrackStock {
+		available := p.Available()
+		pr.Available = &available
+	}
+	for i := range p.Categories {
+		pr.Categories = append(pr.Categories, *newCategoryRes(&p.Categories[i]))
+	}
+	for i := range p.OptionGroups {
+		pr.Options = append(pr.Options, *newOptionGroupRes(&p.OptionGroups[i]))
+	}
+	return pr
+}
+
+type optionGroupRes struct {
+	ID         int              `json:"id"`
+	Name       string           `json:"name"`
+	Required   bool             `json:"required"`
+	MinSelect  int              `json:"minSelect"`
+	MaxSelect  int              `json:"maxSelect"`
+	SortNumber int              `json:"sortNumber"`
+	Values     []optionValueRes `json:"values"`
+}
+
+func newOptionGroupRes(g *app.OptionGroup) *optionGroupRes {
+	gr := &optionGroupRes{
+		ID:         g.ID,
+		Name:       g.Name,
+		Required:   g.Required,
+		MinSelect:  g.MinSelect,
+		MaxSelect:  g.MaxSelect,
+		SortNumber: g.SortNumber,
+		Values:     make([]optionValueRes, len(g.Values)),
+	}
+	for i := range g.Values {
+		gr.Values[i] = *newOptionValueRes(&g.Values[i])
+	}
+	return gr
+}
+
+type optionValueRes struct {
+	ID         int       `json:"id"`
+	Name       string    `json:"name"`
+	PriceDelta app.Money `json:"priceDelta"`
+	SortNumber int       `json:"sortNumber"`
+	IsActive   bool      `json:"isActive"`
+	TrackStock bool      `json:"trackStock"`
+	InStock    bool      `json:"inStock"`
+	Available  *int      `json:"available,omitempty"`
+}
+
+func newOptionValueRes(v *app.OptionValue) *optionValueRes {
+	vr := &optionValueRes{
+		ID:         v.ID,
+		Name:       v.Name,
+		PriceDelta: v.PriceDelta,
+		SortNumber: v.SortNumber,
+		IsActive:   v.IsActive,
+		TrackStock: v.TrackStock,
+		InStock:    !v.TrackStock || v.Available() > 0,
+	}
+	if v.TrackStock {
+		available := v.Available()
+		vr.Available = &available
+	}
+	return vr
+}
+
+func newProductsRes(ps []app.Product) []productRes {
+	res := make([]productRes, len(ps))
+	for i := range ps {
+		res[i] = *newProductRes(&ps[i])
+	}
+	return res
+}
+
+type cartItemRes struct {
+	ID        int                `json:"id"`
+	ProductID int                `json:"productId"`
+	Qty       int                `json:"qty"`
+	Price     app.Money          `json:"price"`
+	Total     app.Money          `json:"total"`
+	Options   app.ProductOptions `json:"options"`
+	Product   *productRes        `json:"product,omitempty"`
+}
+
+type cartRes struct {
+	ID        int           `json:"id"`
+	Items     []cartItemRes `json:"items"`
+	Total     app.Money     `json:"total"`
+	CreatedAt time.Time     `json:"createdAt"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+}
+
+func newCartRes(c *app.Cart) *cartRes {
+	cr := &cartRes{
+		ID:        c.ID,
+		Items:     make([]cartItemRes, len(c.Items)),
+		Total:     c.Total,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+	for i, ci := range c.Items {
+		cr.Items[i] = cartItemRes{
+			ID:        ci.ID,
+			ProductID: ci.ProductID,
+			Qty:       ci.Qty,
+			Price:     ci.Price,
+			Total:     ci.Total,
+			Options:   ci.Options,
+			Product:   newProductRes(ci.Product),
+		}
+	}
+	return cr
+}
+
+type categoryRes struct {
+	ID          int          `json:"id"`
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	IsActive    bool         `json:"isActive"`
+	TaxClassID  int          `json:"taxClassId,omitempty"`
+	Image       *imageRes    `json:"image,omitempty"`
+	Products    []productRes `json:"products,omitempty"`
+}
+
+func newCategoryRes(c *app.Category) *categoryRes {
+	cr := &categoryRes{
+		ID:          c.ID,
+		Title:       c.Title,
+		Description: c.Description,
+		IsActive:    c.IsActive,
+		TaxClassID:  c.TaxClassID,
+		Image:       newImageRes(c.Image),
+	}
+	if len(c.Products) > 0 {
+		cr.Products = newProductsRes(c.Products)
+	}
+	return cr
+}
+
+func newCategoriesRes(cs []app.Category) []categoryRes {
+	res := make([]categoryRes, len(cs))
+	for i := range cs {
+		res[i] = *newCategoryRes(&cs[i])
+	}
+	return res
+}
+
+type taxClassRes struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Rate int    `json:"rate"`
+}
+
+func newTaxClassRes(tc *app.TaxClass) *taxClassRes {
+	return &taxClassRes{ID: tc.ID, Name: tc.Name, Rate: tc.Rate}
+}
+
+func newTaxClassesRes(tcs []app.TaxClass) []taxClassRes {
+	res := make([]taxClassRes, len(tcs))
+	for i := range tcs {
+		res[i] = *newTaxClassRes(&tcs[i])
+	}
+	return res
+}
+
+type stockRes struct {
+	ProductID         int    `json:"productId"`
+	Title             string `json:"title"`
+	TrackStock        bool   `json:"trackStock"`
+	Stock             int    `json:"stock"`
+	Reserved          int    `json:"reserved"`
+	Available         int    `json:"available"`
+	LowStockThreshold int    `json:"lowStockThreshold"`
+	IsLow             bool   `json:"isLow"`
+}
+
+func newStockRes(p *app.Product) *stockRes {
+	return &stockRes{
+		ProductID:         p.ID,
+		Title:             p.Title,
+		TrackStock:        p.TrackStock,
+		Stock:             p.Stock,
+		Reserved:          p.Reserved,
+		Available:         p.Available(),
+		LowStockThreshold: p.LowStockThreshold,
+		IsLow:             p.IsLowStock(),
+	}
+}
+
+type optionStockRes struct {
+	OptionValueID int    `json:"optionValueId"`
+	Name          string `json:"name"`
+	TrackStock    bool   `json:"trackStock"`
+	Stock         int    `json:"stock"`
+	Reserved      int    `json:"reserved"`
+	Available     int    `json:"available"`
+}
+
+func newOptionStockRes(v *app.OptionValue) *optionStockRes {
+	return &optionStockRes{
+		OptionValueID: v.ID,
+		Name:          v.Name,
+		TrackStock:    v.TrackStock,
+		Stock:         v.Stock,
+		Reserved:      v.Reserved,
+		Available:     v.Available(),
+	}
+}
+
+type couponRes struct {
+	ID           int            `json:"id"`
+	Code         string         `json:"code"`
+	Description  string         `json:"description"`
+	Type         app.CouponType `json:"type"`
+	Percent      int            `json:"percent,omitempty"`
+	Amount       *app.Money     `json:"amount,omitempty"`
+	BuyQty       int            `json:"buyQty,omitempty"`
+	GetQty       int            `json:"getQty,omitempty"`
+	MinBasket    app.Money      `json:"minBasket"`
+	StartsAt     *time.Time     `json:"startsAt"`
+	EndsAt       *time.Time     `json:"endsAt"`
+	UsageLimit   int            `json:"usageLimit"`
+	PerUserLimit int            `json:"perUserLimit"`
+	UsedCount    int            `json:"usedCount"`
+	IsActive     bool           `json:"isActive"`
+	Products     []int          `json:"products"`
+	Categories   []int          `json:"categories"`
+	CreatedAt    time.Time      `json:"createdAt"`
+	UpdatedAt    time.Time      `json:"updatedAt"`
+}
+
+func newCouponRes(c *app.Coupon) *couponRes {
+	cr := &couponRes{
+		ID:           c.ID,
+		Code:         c.Code,
+		Description:  c.Description,
+		Type:         c.Type,
+		Percent:      c.Percent,
+		BuyQty:       c.BuyQty,
+		GetQty:       c.GetQty,
+		MinBasket:    c.MinBasket,
+		StartsAt:     c.StartsAt,
+		EndsAt:       c.EndsAt,
+		UsageLimit:   c.UsageLimit,
+		PerUserLimit: c.PerUserLimit,
+		UsedCount:    c.UsedCount,
+		IsActive:     c.IsActive,
+		Products:     []int{},
+		Categories:   []int{},
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+	}
+	if c.Type == app.CouponFixed {
+		cr.Amount = &c.Amount
+	}
+	for _, p := range c.Products {
+		cr.Products = append(cr.Products, p.ID)
+	}
+	for _, cat := range c.Categories {
+		cr.Categories = append(cr.Categories, cat.ID)
+	}
+	return cr
+}
+
+func newCouponsRes(cs []app.Coupon) []couponRes {
+	res := make([]couponRes, len(cs))
+	for i := range cs {
+		res[i] = *newCouponRes(&cs[i])
+	}
+	return res
+}
+
+type orderStatusRes struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func newOrderStatusRes(s *app.OrderStatus) *orderStatusRes {
+	if s == nil {
+		return nil
+	}
+	return &orderStatusRes{ID: s.ID, Name: s.Name, Description: s.Description}
+}
+
+type paymentMethodRes struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type orderItemRes struct {
+	ID       int                `json:"id"`
+	Qty      int                `json:"qty"`
+	Price    app.Money          `json:"price"`
+	Discount app.Money          `json:"discount"`
+	Net      app.Money          `json:"net"`
+	Tax      app.Money          `json:"tax"`
+	Total    app.Money          `json:"total"`
+	TaxRate  int                `json:"taxRate"`
+	Options  app.ProductOptions `json:"options"`
+	Product  *productRes        `json:"product"`
+}
+
+type orderTaxRes struct {
+	Rate   int       `json:"rate"`
+	Base   app.Money `json:"base"`
+	Amount app.Money `json:"amount"`
+}
+
+type orderHistoryRes struct {
+	Note      string          `json:"note"`
+	Status    *orderStatusRes `json:"status,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+type orderRes struct {
+	ID               int               `json:"id"`
+	UserID           int               `json:"userId"`
+	Subtotal         app.Money         `json:"subtotal"`
+	TaxTotal         app.Money         `json:"taxTotal"`
+	DeliveryFee      app.Money         `json:"deliveryFee"`
+	Discount         app.Money         `json:"discount"`
+	CouponCode       string            `json:"couponCode,omitempty"`
+	Total            app.Money         `json:"total"`
+	PricesIncludeTax bool              `json:"pricesIncludeTax"`
+	Taxes            []orderTaxRes     `json:"taxes"`
+	CustomerNote     string            `json:"customerNote"`
+	DeliveryTime     *time.Time        `json:"deliveryTime"`
+	Status           *orderStatusRes   `json:"status"`
+	Address          *app.AddressBody  `json:"address"`
+	PaymentMethod    *paymentMethodRes `json:"paymentMethod"`
+	Items            []orderItemRes    `json:"items"`
+	History          []orderHistoryRes `json:"history,omitempty"`
+	CreatedAt        time.Time         `json:"createdAt"`
+	UpdatedAt        time.Time         `json:"updatedAt"`
+}
+
+func newOrderRes(o *app.Order) *orderRes {
+	or := &orderRes{
+		ID:               o.ID,
+		UserID:           o.UserID,
+		Subtotal:         o.Subtotal,
+		TaxTotal:         o.TaxTotal,
+		DeliveryFee:      o.DeliveryFee,
+		Discount:         o.Discount,
+		CouponCode:       o.CouponCode,
+		Total:            o.Total,
+		PricesIncludeTax: o.PricesIncludeTax,
+		Taxes:            []orderTaxRes{},
+		CustomerNote:     o.CustomerNote,
+		DeliveryTime:     o.DeliveryTime,
+		Status:           newOrderStatusRes(o.Status),
+		Items:            []orderItemRes{},
+		CreatedAt:        o.CreatedAt,
+		UpdatedAt:        o.UpdatedAt,
+	}
+	if o.Address != nil {
+		or.Address = &o.Address.AddressBody
+	}
+	if pm := o.PaymentMethod; pm != nil {
+		or.PaymentMethod = &paymentMethodRes{ID: pm.ID, Name: pm.Name, Description: pm.Description}
+	}
+	for _, op := range o.Products {
+		or.Items = append(or.Items, orderItemRes{
+			ID:       op.ID,
+			Qty:      op.Qty,
+			Price:    op.Price,
+			Discount: op.Discount,
+			Net:      op.Net,
+			Tax:      op.Tax,
+			Total:    op.Total,
+			TaxRate:  op.TaxRate,
+			Options:  op.Options,
+			Product:  newProductRes(op.Product),
+		})
+	}
+	for _, t := range o.Taxes {
+		or.Taxes = append(or.Taxes, orderTaxRes{Rate: t.Rate, Base: t.Base, Amount: t.Amount})
+	}
+	for _, h := range o.History {
+		or.History = append(or.History, orderHistoryRes{Note: h.Note, Status: newOrderStatusRes(h.Status), CreatedAt: h.CreatedAt})
+	}
+	return or
+}
+
+func newOrdersRes(os []app.Order) []orderRes {
+	res := make([]orderRes, len(os))
+	for i := range os {
+		res[i] = *newOrderRes(&os[i])
+	}
+	return res
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"app"
+	"app/usecases"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewUserRes(t *testing.T) {
+	u := &app.User{Email: "user@gmail.com", TokenVersion: 3}
+	u.SetPassword("good password")
+
+	b, err := json.Marshal(response{newUserRes(u)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range []string{"password", "Password", u.Password, "tokenVersion", "TokenVersion"} {
+		if strings.Contains(string(b), f) {
+			t.Errorf("expected user response not to contain %q got %s", f, b)
+		}
+	}
+	if !strings.Contains(string(b), `"result":{"id":0,`) {
+		t.Errorf("expected user in result envelope got %s", b)
+	}
+}
+
+func TestNewOrderRes(t *testing.T) {
+	o := &app.Order{
+		UserID:   1,
+		Total:    app.MustParseMoney("10"),
+		Products: []app.OrderProduct{{Qty: 2, Price: app.MustParseMoney("5"), Total: app.MustParseMoney("10"), Product: &app.Product{Title: "book", Categories: []app.Category{{Title: "books"}}}}},
+		History:  []app.OrderHistory{{Note: "received"}},
+	}
+
+	or := newOrderRes(o)
+	if or.Status != nil || or.Address != nil || or.PaymentMethod != nil {
+		t.Errorf("expected missing relations to be nil got %+v", or)
+	}
+	if len(or.Items) != 1 || or.Items[0].Product.Title != "book" || or.Items[0].Product.Categories[0].Title != "books" {
+		t.Errorf("unexpected items %+v", or.Items)
+	}
+	if len(or.History) != 1 || or.History[0].Note != "received" {
+		t.Errorf("unexpected history %+v", or.History)
+	}
+
+	if items := newOrderRes(&app.Order{}).Items; items == nil {
+		t.Error("expected empty items not to be null")
+	}
+}
+
+func TestNewCartRes(t *testing.T) {
+	p := &app.Product{Title: "book", TrackStock: true, Stock: 7, Reserved: 2, LowStockThreshold: 3}
+	c := &app.Cart{Items: []app.CartItem{{ProductID: 1, Qty: 2, Price: app.MustParseMoney("5"), Product: p}}}
+
+	b, err := json.Marshal(response{newCartRes(c)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range []string{`"stock"`, `"reserved"`, `"lowStockThreshold"`} {
+		if strings.Contains(string(b), f) {
+			t.Errorf("expected cart response not to contain %s got %s", f, b)
+		}
+	}
+	if !strings.Contains(string(b), `"available":5`) {
+		t.Errorf("expected cart product's availability got %s", b)
+	}
+	if items := newCartRes(&app.Cart{}).Items; items == nil {
+		t.Error("expected empty items not to be null")
+	}
+}
+
+func TestNewUserDetailRes(t *testing.T) {
+	b, err := json.Marshal(newUserDetailRes(&usecases.UserDetail{User: &app.User{}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"addresses":[]`) {
+		t.Errorf("expected empty addresses not to be null got %s", b)
+	}
+
+	a := &app.Address{UserID: 5, AddressBody: app.AddressBody{City: "İstanbul"}}
+	if b, _ = json.Marshal(newAddressRes(a)); strings.Contains(string(b), "userId") || !strings.Contains(string(b), `"city":"İstanbul"`) {
+		t.Errorf("unexpected address response %s", b)
+	}
+}
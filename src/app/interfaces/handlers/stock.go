@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"app"
+	"app/usecases"
+	"net/http"
+
+	"github.com/alioygur/gores"
+	"github.com/gorilla/mux"
+	"github.com/justinas/alice"
+)
+
+type stockService interface {
+	LowStock(*app.DBFilter) ([]app.Product, int, error)
+	UpdateStock(*usecases.StockForm) (*app.Product, error)
+	UpdateOptionStock(*usecases.OptionStockForm) (*app.OptionValue, error)
+}
+
+func NewStock(srv stockService, eh app.ErrorHandler) *Stock {
+	return &Stock{srv, eh}
+}
+
+// Stock manages products' stock
+type Stock struct {
+	srv stockService
+	eh  app.ErrorHandler
+}
+
+func (sh *Stock) SetAdminRoutes(r *mux.Router, mid ...alice.Constructor) {
+	h := alice.New(mid...)
+	r.Handle("/v1/admin/stock/low", h.ThenFunc(sh.getLowStock)).Methods("GET")
+	r.Handle("/v1/admin/products/{id:[0-9]+}/stock", h.ThenFunc(sh.updateStock)).Methods("PATCH", "PUT")
+	r.Handle("/v1/admin/option-values/{id:[0-9]+}/stock", h.ThenFunc(sh.updateOptionStock)).Methods("PATCH", "PUT")
+}
+
+func (sh *Stock) getLowStock(w http.ResponseWriter, r *http.Request) {
+	f, err := qPagination(r)
+	if err != nil {
+		sh.eh.Handle(w, err)
+		return
+	}
+
+	ps, total, err := sh.srv.LowStock(f)
+	if err != nil {
+		sh.eh.Handle(w, err)
+		return
+	}
+
+	res := make([]stockRes, len(ps))
+	for i := range ps {
+		res[i] = *newStockRes(&ps[i])
+	}
+
+	gores.JSON(w, http.StatusOK, pagedResponse{res, newPagination(f, total)})
+}
+
+func (sh *Stock) updateStock(w http.ResponseWriter, r *http.Request) {
+	f := new(usecases.StockForm)
+	if err := decodeReq(r, f); err != nil {
+		sh.eh.Handle(w, err)
+		return
+	}
+
+	f.ProductID = muxVarMustInt("id", r)
+
+	p, err := sh.srv.UpdateStock(f)
+	if err != nil {
+		sh.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newStockRes(p)})
+}
+
+func (sh *Stock) updateOptionStock(w http.ResponseWriter, r *http.Request) {
+	f := new(usecases.OptionStockForm)
+	if err := decodeReq(r, f); err != nil {
+		sh.eh.Handle(w, err)
+		return
+	}
+
+	f.OptionValueID = muxVarMustInt("id", r)
+
+	v, err := sh.srv.UpdateOptionStock(f)
+	if err != nil {
+		sh.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newOptionStockRes(v)})
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"app"
+	"app/usecases"
+	"net/http"
+
+	"github.com/alioygur/gores"
+	"github.com/gorilla/mux"
+	"github.com/justinas/alice"
+)
+
+type taxService interface {
+	TaxClasses() ([]app.TaxClass, error)
+	CreateTaxClass(*usecases.TaxClassForm) (*app.TaxClass, error)
+	UpdateTaxClass(*usecases.TaxClassForm) (*app.TaxClass, error)
+	DeleteTaxClass(id int) error
+}
+
+func NewTax(srv taxService, eh app.ErrorHandler) *Tax {
+	return &Tax{srv, eh}
+}
+
+// Tax manages tax classes
+type Tax struct {
+	srv taxService
+	eh  app.ErrorHandler
+}
+
+func (th *Tax) SetAdminRoutes(r *mux.Router, mid ...alice.Constructor) {
+	h := alice.New(mid...)
+	r.Handle("/v1/admin/tax-classes", h.ThenFunc(th.getTaxClasses)).Methods("GET")
+	r.Handle("/v1/admin/tax-classes", h.ThenFunc(th.createTaxClass)).Methods("POST")
+	r.Handle("/v1/admin/tax-classes/{id:[0-9]+}", h.ThenFunc(th.updateTaxClass)).Methods("PATCH", "PUT")
+	r.Handle("/v1/admin/tax-classes/{id:[0-9]+}", h.ThenFunc(th.deleteTaxClass)).Methods("DELETE")
+}
+
+func (th *Tax) getTaxClasses(w http.ResponseWriter, r *http.Request) {
+	tcs, err := th.srv.TaxClasses()
+	if err != nil {
+		th.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newTaxClassesRes(tcs)})
+}
+
+func (th *Tax) createTaxClass(w http.ResponseWriter, r *http.Request) {
+	f := new(usecases.TaxClassForm)
+	if err := decodeReq(r, f); err != nil {
+		th.eh.Handle(w, err)
+		return
+	}
+
+	tc, err := th.srv.CreateTaxClass(f)
+	if err != nil {
+		th.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusCreated, response{newTaxClassRes(tc)})
+}
+
+func (th *Tax) updateTaxClass(w http.ResponseWriter, r *http.Request) {
+	f := new(usecases.TaxClassForm)
+	if err := decodeReq(r, f); err != nil {
+		th.eh.Handle(w, err)
+		return
+	}
+
+	f.ID = muxVarMustInt("id", r)
+
+	tc, err := th.srv.UpdateTaxClass(f)
+	if err != nil {
+		th.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newTaxClassRes(tc)})
+}
+
+func (th *Tax) deleteTaxClass(w http.ResponseWriter, r *http.Request) {
+	if err := th.srv.DeleteTaxClass(muxVarMustInt("id", r)); err != nil {
+		th.eh.Handle(w, err)
+		return
+	}
+
+	gores.NoContent(w)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"app"
+	"app/usecases"
+	"net/http"
+
+	"github.com/alioygur/gores"
+	"github.com/gorilla/mux"
+	"github.com/justinas/alice"
+)
+
+type userService interface {
+	Users(*usecases.UserFilterForm, *app.DBFilter) ([]app.User, int, error)
+	User(id int) (*usecases.UserDetail, error)
+	SetActive(by *app.User, id int, active bool) (*app.User, error)
+	SetAdmin(by *app.User, id int, admin bool) (*app.User, error)
+	SendPasswordReset(by *app.User, id int, locale string) error
+	Delete(by *app.User, id int) error
+}
+
+func NewUsers(srv userService, eh app.ErrorHandler) *Users {
+	return &Users{srv, eh}
+}
+
+// Users is admin's customer management
+type Users struct {
+	srv userService
+	eh  app.ErrorHandler
+}
+
+func (uh *Users) SetAdminRoutes(r *mux.Router, mid ...alice.Constructor) {
+	h := alice.New(mid...)
+	r.Handle("/v1/admin/users", h.ThenFunc(uh.getUsers)).Methods("GET")
+	r.Handle("/v1/admin/users/{id:[0-9]+}", h.ThenFunc(uh.getUser)).Methods("GET")
+	r.Handle("/v1/admin/users/{id:[0-9]+}", h.ThenFunc(uh.deleteUser)).Methods("DELETE")
+	r.Handle("/v1/admin/users/{id:[0-9]+}/activate", h.ThenFunc(uh.setActive(true))).Methods("POST")
+	r.Handle("/v1/admin/users/{id:[0-9]+}/deactivate", h.ThenFunc(uh.setActive(false))).Methods("POST")
+	r.Handle("/v1/admin/users/{id:[0-9]+}/promote", h.ThenFunc(uh.setAdmin(true))).Methods("POST")
+	r.Handle("/v1/admin/users/{id:[0-9]+}/demote", h.ThenFunc(uh.setAdmin(false))).Methods("POST")
+	r.Handle("/v1/admin/users/{id:[0-9]+}/password-reset", h.ThenFunc(uh.sendPasswordReset)).Methods("POST")
+}
+
+var userSortFields = map[string]string{
+	"createdAt": "created_at",
+	"email":     "email",
+	"firstName": "first_name",
+	"lastName":  "last_name",
+}
+
+func (uh *Users) getUsers(w http.ResponseWriter, r *http.Request) {
+	f, err := qPagination(r)
+	if err != nil {
+		uh.eh.Handle(w, err)
+		return
+	}
+
+	if err := qSort(r, f, userSortFields); err != nil {
+		uh.eh.Handle(w, err)
+		return
+	}
+
+	ff := &usecases.UserFilterForm{Query: qParam("q", r)}
+	if ff.IsActivated, err = qParamBool("active", r); err != nil {
+		uh.eh.Handle(w, err)
+		return
+	}
+	if ff.IsAdmin, err = qParamBool("admin", r); err != nil {
+		uh.eh.Handle(w, err)
+		return
+	}
+
+	us, total, err := uh.srv.Users(ff, f)
+	if err != nil {
+		uh.eh.Handle(w, err)
+		return
+	}
+
+	res := make([]userRes, len(us))
+	for i := range us {
+		res[i] = *newUserRes(&us[i])
+	}
+
+	gores.JSON(w, http.StatusOK, pagedResponse{res, newPagination(f, total)})
+}
+
+func (uh *Users) getUser(w http.ResponseWriter, r *http.Request) {
+	id := muxVarMustInt("id", r)
+
+	d, err := uh.srv.User(id)
+	if err != nil {
+		uh.eh.Handle(w, err)
+		return
+	}
+
+	gores.JSON(w, http.StatusOK, response{newUserDetailRes(d)})
+}
+
+func (uh *Users) setActive(active bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := muxVarMustInt("id", r)
+		by := app.UserMustFromContext(r.Context())
+
+		u, err := uh.srv.SetActive(by, id, active)
+		if err != nil {
+			uh.eh.Handle(w, err)
+			return
+		}
+
+		gores.JSON(w, http.StatusOK, response{newUserRes(u)})
+	}
+}
+
+func (uh *Users) setAdmin(admin bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := muxVarMustInt("id", r)
+		by := app.UserMustFromContext(r.Context())
+
+		u, err := uh.srv.SetAdmin(by, id, admin)
+		if err != nil {
+			uh.eh.Handle(w, err)
+			return
+		}
+
+		gores.JSON(w, http.StatusOK, response{newUserRes(u)})
+	}
+}
+
+func (uh *Users) sendPasswordReset(w http.ResponseWriter, r *http.Request) {
+	by := app.UserMustFromContext(r.Context())
+
+	if err := uh.srv.SendPasswordReset(by, muxVarMustInt("id", r), reqLocale(r)); err != nil {
+		uh.eh.Handle(w, err)
+		return
+	}
+
+	gores.NoContent(w)
+}
+
+func (uh *Users) deleteUser(w http.ResponseWriter, r *http.Request) {
+	id := muxVarMustInt("id", r)
+	by := app.UserMustFromContext(r.Context())
+
+	if err := uh.srv.Delete(by, id); err != nil {
+		uh.eh.Handle(w, err)
+		return
+	}
+
+	gores.NoContent(w)
+}
@@ -1,16 +1,12 @@
 package interfaces
 
 import (
-	"fmt"
 	"net/http"
-	"os"
 	"strings"
 
 	"app"
 
 	"app/interfaces/errs"
-
-	"github.com/dgrijalva/jwt-go"
 )
 
 type errHandler interface {
@@ -32,7 +28,12 @@ func getToken(r *http.Request) (string, error) {
 	return authHeaderParts[1], nil
 }
 
-func NewSetUserMid(db app.DBFinder, eh errHandler) func(http.Handler) http.Handler {
+type authenticator interface {
+	Authenticate(token string) (*app.User, error)
+}
+
+// NewSetUserMid sets the user of the request's access token to the request's context
+func NewSetUserMid(auth authenticator, eh errHandler) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			tokenStr, err := getToken(r)
@@ -46,29 +47,7 @@ func NewSetUserMid(db app.DBFinder, eh errHandler) func(http.Handler) http.Handl
 				return
 			}
 
-			u, err := func() (*app.User, error) {
-				token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-					return []byte(os.Getenv("SECRET_KEY")), nil
-				})
-				if err != nil {
-					if errs.IsTokenExpiredErr(err) {
-						return nil, errs.ErrTokenExpired
-					}
-					return nil, errs.BadRequest("token string can't parsed.").SetInner(err)
-				}
-
-				userID, ok := token.Claims.(jwt.MapClaims)["userID"].(string)
-				if !ok {
-					return nil, fmt.Errorf("userID can't get from token claims, token: %s", tokenStr)
-				}
-
-				var u app.User
-				if err := db.One(&u, userID); err != nil {
-					return nil, err
-				}
-				return &u, nil
-			}()
-
+			u, err := auth.Authenticate(tokenStr)
 			if err != nil {
 				eh.Handle(w, err)
 				return
@@ -101,3 +80,32 @@ func NewAuthRequiredMid(eh errHandler) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// NewAdminRequiredMid allows only admins or users whose role has all given permissions.
+// If no permission given only admins are allowed.
+func NewAdminRequiredMid(eh errHandler, perms ...app.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			usr, ok := app.UserFromContext(r.Context())
+			if !ok {
+				err := errs.Unauthorized("Auth required")
+				eh.Handle(w, err)
+				return
+			}
+
+			if !usr.IsActivated {
+				err := errs.Unauthorized("Inactive user")
+				eh.Handle(w, err)
+				return
+			}
+
+			if !usr.Can(perms...) {
+				err := errs.Forbidden("Permission denied")
+				eh.Handle(w, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
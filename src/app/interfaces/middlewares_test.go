@@ -0,0 +1,55 @@
+package interfaces
+
+import (
+	"app"
+	"app/interfaces/errs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewAdminRequiredMid(t *testing.T) {
+	eh := &errs.Handler{}
+	okHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	admin := &app.User{IsActivated: true, IsAdmin: true}
+	editor := &app.User{IsActivated: true, Role: "editor"}
+	customer := &app.User{IsActivated: true}
+	inactiveAdmin := &app.User{IsAdmin: true}
+
+	var tests = []struct {
+		name               string
+		user               *app.User
+		perms              []app.Permission
+		expectedStatusCode int
+	}{
+		{"guest", nil, nil, http.StatusUnauthorized},
+		{"inactive admin", inactiveAdmin, nil, http.StatusUnauthorized},
+		{"customer", customer, nil, http.StatusForbidden},
+		{"customer with permission", customer, []app.Permission{app.PermManageCatalog}, http.StatusForbidden},
+		{"editor without permission", editor, nil, http.StatusForbidden},
+		{"editor with wrong permission", editor, []app.Permission{app.PermManageOrders}, http.StatusForbidden},
+		{"editor with permission", editor, []app.Permission{app.PermManageCatalog}, http.StatusOK},
+		{"admin", admin, nil, http.StatusOK},
+		{"admin with permission", admin, []app.Permission{app.PermManageUsers}, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		r, err := http.NewRequest("GET", "/v1/admin", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.user != nil {
+			r = r.WithContext(test.user.NewContext(r.Context()))
+		}
+
+		w := httptest.NewRecorder()
+		NewAdminRequiredMid(eh, test.perms...)(okHandler).ServeHTTP(w, r)
+
+		if w.Code != test.expectedStatusCode {
+			t.Errorf("%s: expected status code %d got %d", test.name, test.expectedStatusCode, w.Code)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package gormdb
+
+import "app"
+
+func NewAddress(r *Repo) *Address {
+	return &Address{r}
+}
+
+type Address struct {
+	*Repo
+}
+
+// FindAddressesByUser gets user's addresses, the default one first
+func (ar *Address) FindAddressesByUser(userID int) ([]app.Address, error) {
+	var as []app.Address
+	if err := ar.db.Order("`default` desc, id").Find(&as, "user_id=?", userID).Error; err != nil {
+		return nil, err
+	}
+	return as, nil
+}
+
+func (ar *Address) OneAddressByUser(userID int, id interface{}) (*app.Address, error) {
+	var a app.Address
+	if err := ar.db.First(&a, "id=? AND user_id=?", id, userID).Error; err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// SetDefaultAddress makes the address user's only default address
+func (ar *Address) SetDefaultAddress(a *app.Address) error {
+	tx := ar.db.Begin()
+
+	if err := tx.Model(&app.Address{}).Where("user_id=? AND id<>?", a.UserID, a.ID).Update("default", false).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Model(a).Update("default", true).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// DeleteAddress deletes the address, if it's the default one
+// user's last added address becomes the default.
+func (ar *Address) DeleteAddress(a *app.Address) error {
+	tx := ar.db.Begin()
+
+	if err := tx.Delete(a).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if a.Default {
+		var next app.Address
+		err := tx.Order("id desc").First(&next, "user_id=?", a.UserID).Error
+		if err != nil && !ar.IsNotFoundErr(err) {
+			tx.Rollback()
+			return err
+		}
+		if err == nil {
+			if err := tx.Model(&next).Update("default", true).Error; err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+
+	return tx.Commit().Error
+}
@@ -0,0 +1,40 @@
+package gormdb
+
+import "app"
+
+func NewCart(r *Repo) *Cart {
+	return &Cart{r}
+}
+
+type Cart struct {
+	*Repo
+}
+
+// OneProduct gets the product with its option groups and all of their values,
+// so inactive values chosen by cart items can be reported
+func (cr *Cart) OneProduct(id int) (*app.Product, error) {
+	var p app.Product
+	return &p, preloadOptions(cr.db, "OptionGroups", false).First(&p, "id=?", id).Error
+}
+
+// OneCartByUser gets user's cart with items, creates an empty one if not exists
+func (cr *Cart) OneCartByUser(userID int) (*app.Cart, error) {
+	var c app.Cart
+	if err := cr.db.FirstOrCreate(&c, app.Cart{UserID: userID}).Error; err != nil {
+		return nil, err
+	}
+
+	qry := preloadOptions(cr.db.Preload("Product").Preload("Product.Image"), "Product.OptionGroups", false)
+	if err := qry.Order("id").Find(&c.Items, "cart_id=?", c.ID).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (cr *Cart) DeleteCartItem(ci *app.CartItem) error {
+	return cr.db.Delete(ci).Error
+}
+
+func (cr *Cart) ClearCart(c *app.Cart) error {
+	return cr.db.Where("cart_id=?", c.ID).Delete(app.CartItem{}).Error
+}
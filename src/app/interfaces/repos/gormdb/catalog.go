@@ -1,6 +1,10 @@
 package gormdb
 
-import "app"
+import (
+	"app"
+
+	"github.com/jinzhu/gorm"
+)
 
 func NewCatalog(r *Repo) *Catalog {
 	return &Catalog{r}
@@ -12,38 +16,46 @@ type Catalog struct {
 
 func (cr *Catalog) OneActiveProduct(id interface{}) (*app.Product, error) {
 	var p app.Product
-	if err := cr.db.Preload("Image").Preload("Categories").First(&p, "id=? AND is_active=?", id, true).Error; err != nil {
+	qry := preloadOptions(cr.db.Preload("Image").Preload("Categories"), "OptionGroups", true)
+	if err := qry.First(&p, "id=? AND is_active=?", id, true).Error; err != nil {
 		return nil, err
 	}
 	return &p, nil
 }
 
 func (cr *Catalog) FindActiveProducts(f *app.DBFilter) ([]app.Product, error) {
+	return cr.FindActiveProductsByCategory(nil, f)
+}
+
+// FindActiveProductsByCategory finds active products in given categories, all if no category given
+func (cr *Catalog) FindActiveProductsByCategory(ids []interface{}, f *app.DBFilter) ([]app.Product, error) {
 	var ps []app.Product
 
-	if err := cr.db.Preload("Image").Find(&ps, "is_active=?", true).Error; err != nil {
+	qry := cr.activeProducts(ids).Preload("Image")
+	if f != nil {
+		qry = cr.filter(qry, f)
+	}
+
+	if err := qry.Find(&ps).Error; err != nil {
 		return nil, err
 	}
 	return ps, nil
 }
 
-func (cr *Catalog) FindActiveProductsByCategory(ids []interface{}, f *app.DBFilter) ([]app.Product, error) {
-	var (
-		pids []int
-		ps   []app.Product
-	)
-
-	if err := cr.db.Table("pivot_product_category").
-		Where("category_id in (?)", ids).
-		Group("product_id").
-		Pluck("product_id", &pids).Error; err != nil {
-		return nil, err
-	}
+// CountActiveProducts counts active products in given categories, all if no category given
+func (cr *Catalog) CountActiveProducts(ids []interface{}) (int, error) {
+	var n int
+	err := cr.activeProducts(ids).Count(&n).Error
+	return n, err
+}
 
-	if err := cr.db.Preload("Image").Where("id in (?) AND is_active=?", pids, true).Find(&ps).Error; err != nil {
-		return nil, err
+func (cr *Catalog) activeProducts(ids []interface{}) *gorm.DB {
+	qry := cr.db.Model(&app.Product{}).Where("is_active=?", true)
+	if len(ids) > 0 {
+		pids := cr.db.Table("pivot_product_category").Select("product_id").Where("category_id in (?)", ids).QueryExpr()
+		qry = qry.Where("id in (?)", pids)
 	}
-	return ps, nil
+	return qry
 }
 
 func (cr *Catalog) FindActiveCategories(f *app.DBFilter) ([]app.Category, error) {
@@ -76,6 +88,11 @@ func (cr *Catalog) DeleteProduct(id interface{}) error {
 		return err
 	}
 
+	if err := deleteOptionGroups(tx, "product_id = ?", p.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	if err := tx.Delete(&p).Error; err != nil {
 		tx.Rollback()
 		return err
@@ -92,3 +109,33 @@ func (cr *Catalog) SetProductCategories(p *app.Product, cs []app.Category) error
 func (cr *Catalog) SetProductImage(p *app.Product, img *app.Image) error {
 	return cr.db.Model(p).Association("Image").Replace(img).Error
 }
+
+func (cr *Catalog) DeleteCategory(id interface{}) error {
+	var c app.Category
+	if err := cr.Repo.One(&c, id); err != nil {
+		return err
+	}
+
+	tx := cr.db.Begin()
+
+	// clear Associations
+	if err := tx.Model(&c).Association("Products").Clear().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Delete(&c).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (cr *Catalog) SetCategoryProducts(c *app.Category, ps []app.Product) error {
+	return cr.db.Model(c).Association("Products").Replace(ps).Error
+}
+
+func (cr *Catalog) SetCategoryImage(c *app.Category, img *app.Image) error {
+	return cr.db.Model(c).Association("Image").Replace(img).Error
+}
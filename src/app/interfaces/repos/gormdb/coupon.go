@@ -0,0 +1,110 @@
+package gormdb
+
+import (
+	"app"
+
+	"github.com/jinzhu/gorm"
+)
+
+func NewCoupon(r *Repo) *Coupon {
+	return &Coupon{r}
+}
+
+type Coupon struct {
+	*Repo
+}
+
+// FindCoupons gets coupons and total count of them
+func (cr *Coupon) FindCoupons(f *app.DBFilter) ([]app.Coupon, int, error) {
+	qry := cr.db.Model(&app.Coupon{})
+
+	var total int
+	if err := qry.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var cs []app.Coupon
+	return cs, total, cr.filter(qry, f).Find(&cs).Error
+}
+
+func (cr *Coupon) OneCoupon(id int) (*app.Coupon, error) {
+	var c app.Coupon
+	return &c, cr.preloadScope(cr.db).First(&c, "id=?", id).Error
+}
+
+func (cr *Coupon) OneCouponByCode(code string) (*app.Coupon, error) {
+	var c app.Coupon
+	return &c, cr.preloadScope(cr.db).First(&c, "code=?", code).Error
+}
+
+func (cr *Coupon) preloadScope(qry *gorm.DB) *gorm.DB {
+	return qry.Preload("Products").Preload("Categories")
+}
+
+// SetCouponScope replaces the coupon's products and categories in a transaction
+func (cr *Coupon) SetCouponScope(c *app.Coupon, ps []app.Product, cs []app.Category) error {
+	tx := cr.db.Begin()
+
+	if err := tx.Model(c).Association("Products").Replace(ps).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Model(c).Association("Categories").Replace(cs).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// CountCouponUsages counts the user's orders which used the coupon
+func (cr *Coupon) CountCouponUsages(couponID, userID int) (int, error) {
+	var n int
+	err := cr.db.Model(&app.CouponUsage{}).Where("coupon_id = ? AND user_id = ?", couponID, userID).Count(&n).Error
+	return n, err
+}
+
+// ProductCategoryIDs gets category ids of the products by product id
+func (cr *Coupon) ProductCategoryIDs(productIDs []int) (map[int][]int, error) {
+	rows, err := cr.db.Table("pivot_product_category").
+		Select("product_id, category_id").
+		Where("product_id IN (?)", productIDs).
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[int][]int)
+	for rows.Next() {
+		var pid, cid int
+		if err := rows.Scan(&pid, &cid); err != nil {
+			return nil, err
+		}
+		ids[pid] = append(ids[pid], cid)
+	}
+	return ids, rows.Err()
+}
+
+// DeleteCoupon deletes the coupon with its products and categories
+func (cr *Coupon) DeleteCoupon(c *app.Coupon) error {
+	tx := cr.db.Begin()
+
+	if err := tx.Model(c).Association("Products").Clear().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Model(c).Association("Categories").Clear().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Delete(c).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
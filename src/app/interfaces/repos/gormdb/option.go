@@ -0,0 +1,66 @@
+package gormdb
+
+import (
+	"app"
+
+	"github.com/jinzhu/gorm"
+)
+
+func NewOption(r *Repo) *Option {
+	return &Option{r}
+}
+
+type Option struct {
+	*Repo
+}
+
+// FindOptionGroups gets the product's option groups with all of their values, sorted
+func (or *Option) FindOptionGroups(productID int) ([]app.OptionGroup, error) {
+	var gs []app.OptionGroup
+	err := preloadOptionValues(or.db, "Values", false).
+		Where("product_id = ?", productID).
+		Order("sort_number, id").
+		Find(&gs).Error
+	return gs, err
+}
+
+func (or *Option) DeleteOptionGroup(g *app.OptionGroup) error {
+	tx := or.db.Begin()
+
+	if err := deleteOptionGroups(tx, "id = ?", g.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (or *Option) DeleteOptionValue(v *app.OptionValue) error {
+	return or.db.Delete(v).Error
+}
+
+// deleteOptionGroups deletes the groups which match the condition with their values
+func deleteOptionGroups(tx *gorm.DB, where string, args ...interface{}) error {
+	ids := tx.Model(&app.OptionGroup{}).Select("id").Where(where, args...).QueryExpr()
+	if err := tx.Where("group_id IN (?)", ids).Delete(app.OptionValue{}).Error; err != nil {
+		return err
+	}
+	return tx.Where(where, args...).Delete(app.OptionGroup{}).Error
+}
+
+// preloadOptions preloads the option groups of the products at the path with their values, sorted
+func preloadOptions(qry *gorm.DB, path string, activeOnly bool) *gorm.DB {
+	qry = qry.Preload(path, func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_number, id")
+	})
+	return preloadOptionValues(qry, path+".Values", activeOnly)
+}
+
+func preloadOptionValues(qry *gorm.DB, path string, activeOnly bool) *gorm.DB {
+	return qry.Preload(path, func(db *gorm.DB) *gorm.DB {
+		if activeOnly {
+			db = db.Where("is_active = ?", true)
+		}
+		return db.Order("sort_number, id")
+	})
+}
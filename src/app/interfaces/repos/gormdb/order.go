@@ -0,0 +1,191 @@
+package gormdb
+
+import (
+	"app"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+func NewOrder(r *Repo) *Order {
+	return &Order{r}
+}
+
+type Order struct {
+	*Repo
+}
+
+// PlaceOrder reserves the order's stock, stores the order with its products, address
+// and initial history, records the order's coupon usage and clears the cart in a single transaction.
+// The stock is reserved only if it's available and the coupon is used only if its limits
+// aren't reached, so concurrent orders can't exceed them.
+func (or *Order) PlaceOrder(o *app.Order, h *app.OrderHistory, c *app.Cart) error {
+	tx := or.db.Begin()
+
+	for _, sr := range o.Reservations {
+		res := stockRow(tx, sr.StockKey).
+			Where("stock - reserved >= ?", sr.Qty).
+			UpdateColumn("reserved", gorm.Expr("reserved + ?", sr.Qty))
+		if res.Error != nil {
+			tx.Rollback()
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			tx.Rollback()
+			return app.ErrOutOfStock
+		}
+	}
+
+	// products, address, taxes and reservations are created with the order
+	if err := tx.Create(o).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if o.CouponID != 0 {
+		if err := useCoupon(tx, o); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	h.OrderID = o.ID
+	if err := tx.Create(h).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Where("cart_id=?", c.ID).Delete(app.CartItem{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// useCoupon records the order's coupon usage if the coupon's usage limits aren't reached.
+// The coupon is locked until the transaction ends, so concurrent orders are counted one by one.
+func useCoupon(tx *gorm.DB, o *app.Order) error {
+	var c app.Coupon
+	if err := forUpdate(tx).First(&c, o.CouponID).Error; err != nil {
+		return err
+	}
+
+	if c.UsageLimit > 0 && c.UsedCount >= c.UsageLimit {
+		return app.ErrCouponUsedUp
+	}
+	if c.PerUserLimit > 0 {
+		var n int
+		if err := tx.Model(&app.CouponUsage{}).Where("coupon_id = ? AND user_id = ?", c.ID, o.UserID).Count(&n).Error; err != nil {
+			return err
+		}
+		if n >= c.PerUserLimit {
+			return app.ErrCouponUserLimit
+		}
+	}
+
+	if err := tx.Model(&c).UpdateColumn("used_count", gorm.Expr("used_count + 1")).Error; err != nil {
+		return err
+	}
+	u := app.CouponUsage{CouponID: c.ID, UserID: o.UserID, OrderID: o.ID}
+	return tx.Create(&u).Error
+}
+
+func (or *Order) FindOrdersByUser(userID int, f *app.DBFilter) ([]app.Order, error) {
+	var os []app.Order
+
+	qry := or.db.Preload("Status").Preload("PaymentMethod").Where("user_id=?", userID)
+	if f != nil {
+		qry = or.filter(qry, f)
+	}
+
+	if err := qry.Find(&os).Error; err != nil {
+		return nil, err
+	}
+	return os, nil
+}
+
+func (or *Order) OneOrderByUser(userID int, id interface{}) (*app.Order, error) {
+	var o app.Order
+	if err := or.preloadOrder(or.db).First(&o, "id=? AND user_id=?", id, userID).Error; err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// preloadOrder preloads order's all associations
+func (or *Order) preloadOrder(qry *gorm.DB) *gorm.DB {
+	return qry.Preload("Status").
+		Preload("Address").
+		Preload("PaymentMethod").
+		Preload("Products").
+		Preload("Products.Product").
+		Preload("History", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).
+		Preload("History.Status").
+		Preload("Taxes", func(db *gorm.DB) *gorm.DB {
+			return db.Order("rate")
+		})
+}
+
+func (or *Order) FindOrders(w app.DBWhere, f *app.DBFilter) ([]app.Order, error) {
+	var os []app.Order
+
+	qry := or.db.Preload("Status").Preload("PaymentMethod").Where(or.where(w))
+	if f != nil {
+		qry = or.filter(qry, f)
+	}
+
+	if err := qry.Find(&os).Error; err != nil {
+		return nil, err
+	}
+	return os, nil
+}
+
+func (or *Order) OneOrder(id interface{}) (*app.Order, error) {
+	var o app.Order
+	if err := or.preloadOrder(or.db).First(&o, "id=?", id).Error; err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// ChangeOrderStatus updates order's status, commits or releases its stock by the action
+// and stores the history in a transaction. The status is updated only if it's still the from status,
+// so concurrent changes can't both pass and the stock isn't changed by a failed change.
+func (or *Order) ChangeOrderStatus(o *app.Order, from int, h *app.OrderHistory, a app.StockAction) error {
+	tx := or.db.Begin()
+
+	res := tx.Model(&app.Order{}).
+		Where("id = ? AND status_id = ?", o.ID, from).
+		UpdateColumns(map[string]interface{}{"status_id": o.StatusID, "updated_at": time.Now()})
+	if res.Error != nil {
+		tx.Rollback()
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		tx.Rollback()
+		return app.ErrOrderStatusChanged
+	}
+
+	var err error
+	switch a {
+	case app.StockCommit:
+		err = commitReservations(tx, o.ID)
+	case app.StockRelease:
+		err = releaseReservations(tx, o.ID)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	h.OrderID = o.ID
+	if err := tx.Create(h).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
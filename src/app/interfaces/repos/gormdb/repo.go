@@ -64,6 +64,15 @@ func (r *Repo) IsNotFoundErr(err error) bool {
 	return errs.Cause(err) == gorm.ErrRecordNotFound
 }
 
+// forUpdate locks the selected rows until the transaction ends.
+// SQLite has no row locks, its write transactions already lock the whole database.
+func forUpdate(tx *gorm.DB) *gorm.DB {
+	if tx.Dialect().GetName() == "sqlite3" {
+		return tx
+	}
+	return tx.Set("gorm:query_option", "FOR UPDATE")
+}
+
 func (r *Repo) where(w app.DBWhere) map[string]interface{} {
 	return w
 }
@@ -79,12 +88,13 @@ func (r *Repo) filter(qry *gorm.DB, fi *app.DBFilter) *gorm.DB {
 		qry = qry.Limit(fi.Limit).Offset(fi.Offset)
 	}
 
-	if fi.OrderBy != "" {
-		if fi.Reverse {
-			qry = qry.Order(fi.OrderBy + " desc")
-		} else {
-			qry = qry.Order(fi.OrderBy)
-		}
+	// id is the default order and breaks ties of the sort column, so pages don't overlap or skip rows
+	dir := ""
+	if fi.Reverse {
+		dir = " desc"
+	}
+	if fi.OrderBy != "" && fi.OrderBy != "id" {
+		qry = qry.Order(fi.OrderBy + dir)
 	}
-	return qry
+	return qry.Order("id" + dir)
 }
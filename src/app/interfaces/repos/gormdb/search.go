@@ -0,0 +1,71 @@
+package gormdb
+
+import (
+	"app"
+	"fmt"
+	"strings"
+)
+
+func NewProductSearch(r *Repo) *ProductSearch {
+	return &ProductSearch{r}
+}
+
+// ProductSearch is database backed product searcher
+type ProductSearch struct {
+	*Repo
+}
+
+func (ps *ProductSearch) Search(q *app.SearchQuery) ([]app.Product, int, error) {
+	terms := q.Terms()
+	if len(terms) == 0 {
+		return nil, 0, nil
+	}
+
+	qry := ps.db.Model(&app.Product{}).Where("products.is_active=?", true)
+
+	var (
+		scores    []string
+		scoreArgs []interface{}
+	)
+	for _, t := range terms {
+		like := "%" + t + "%"
+		categories := ps.db.Table("pivot_product_category").
+			Select("pivot_product_category.product_id").
+			Joins("JOIN categories ON categories.id=pivot_product_category.category_id").
+			Where("categories.title LIKE ?", like).
+			QueryExpr()
+
+		// every term must match one of the fields
+		qry = qry.Where("products.title LIKE ? OR products.description LIKE ? OR products.id IN (?)", like, like, categories)
+
+		scores = append(scores, fmt.Sprintf("(CASE WHEN products.title LIKE ? THEN %d WHEN products.id IN (?) THEN %d ELSE %d END)", app.SearchTitleWeight, app.SearchCategoryWeight, app.SearchDescriptionWeight))
+		scoreArgs = append(scoreArgs, like, categories)
+	}
+
+	if q.MinPrice != nil {
+		qry = qry.Where("products.price >= ?", *q.MinPrice)
+	}
+	if q.MaxPrice != nil {
+		qry = qry.Where("products.price <= ?", *q.MaxPrice)
+	}
+	if len(q.Categories) > 0 {
+		pids := ps.db.Table("pivot_product_category").Select("product_id").Where("category_id in (?)", q.Categories).QueryExpr()
+		qry = qry.Where("products.id IN (?)", pids)
+	}
+
+	var total int
+	if err := qry.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var res []app.Product
+	qry = qry.Select("products.*, ("+strings.Join(scores, " + ")+") AS score", scoreArgs...).
+		Preload("Image").
+		Order("score desc, products.id")
+	qry = ps.filter(qry, &app.DBFilter{Limit: q.Limit, Offset: q.Offset})
+
+	if err := qry.Find(&res).Error; err != nil {
+		return nil, 0, err
+	}
+	return res, total, nil
+}
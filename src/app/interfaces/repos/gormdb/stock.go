@@ -0,0 +1,200 @@
+package gormdb
+
+import (
+	"app"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+func NewStock(r *Repo) *Stock {
+	return &Stock{r}
+}
+
+// Stock changes products' and option values' stock with conditional updates. An order's reservations are locked
+// while they're committed or released, and they move between statuses only by updates which are
+// conditional on their current status, so a reservation can't be committed, expired or released twice.
+type Stock struct {
+	*Repo
+}
+
+func (sr *Stock) SetStock(k app.StockKey, stock int) (bool, error) {
+	res := stockRow(sr.db, k).Where("reserved <= ?", stock).UpdateColumn("stock", stock)
+	if res.Error != nil || res.RowsAffected > 0 {
+		return res.Error == nil, res.Error
+	}
+
+	// no row is affected if the stock is already the same
+	var n int
+	err := stockRow(sr.db, k).Where("stock = ?", stock).Count(&n).Error
+	return n > 0, err
+}
+
+func (sr *Stock) AdjustStock(k app.StockKey, delta int) (bool, error) {
+	res := stockRow(sr.db, k).
+		Where("stock + ? >= reserved", delta).
+		UpdateColumn("stock", gorm.Expr("stock + ?", delta))
+	return res.RowsAffected > 0, res.Error
+}
+
+// FindLowStockProducts gets products which track stock and are at or below their thresholds, least available first
+func (sr *Stock) FindLowStockProducts(f *app.DBFilter) ([]app.Product, int, error) {
+	qry := sr.db.Model(&app.Product{}).Where("track_stock = ? AND stock - reserved <= low_stock_threshold", true)
+
+	var total int
+	if err := qry.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var ps []app.Product
+	return ps, total, sr.filter(qry.Order("stock - reserved"), f).Find(&ps).Error
+}
+
+// commitReservations decrements the stock by the order's reservations in the transaction.
+// Expired reservations are committed only if the stock is still available.
+// Reservations of deleted products or option values are committed without changing any stock.
+func commitReservations(tx *gorm.DB, orderID int) error {
+	rs, err := lockReservations(tx, orderID, app.ReservationReserved, app.ReservationExpired)
+	if err != nil {
+		return err
+	}
+
+	for i := range rs {
+		r := &rs[i]
+		qry := stockRow(tx, r.StockKey)
+
+		var res *gorm.DB
+		if r.Status == app.ReservationReserved {
+			res = qry.UpdateColumns(map[string]interface{}{
+				"stock":    gorm.Expr("stock - ?", r.Qty),
+				"reserved": gorm.Expr("reserved - ?", r.Qty),
+			})
+		} else {
+			res = qry.Where("stock - reserved >= ?", r.Qty).UpdateColumn("stock", gorm.Expr("stock - ?", r.Qty))
+		}
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			// the stock of a deleted product or option value can't be decremented, its reservation is committed as it is
+			exists, err := stockRowExists(tx, r.StockKey)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return app.ErrOutOfStock
+			}
+		}
+
+		if _, err := transitReservation(tx, r, r.Status, app.ReservationCommitted); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// releaseReservations gives the order's reserved or committed stock back in the transaction
+func releaseReservations(tx *gorm.DB, orderID int) error {
+	rs, err := lockReservations(tx, orderID, app.ReservationReserved, app.ReservationCommitted, app.ReservationExpired)
+	if err != nil {
+		return err
+	}
+
+	for i := range rs {
+		r := &rs[i]
+		qry := stockRow(tx, r.StockKey)
+
+		switch r.Status {
+		case app.ReservationReserved:
+			err = qry.UpdateColumn("reserved", gorm.Expr("reserved - ?", r.Qty)).Error
+		case app.ReservationCommitted:
+			err = qry.UpdateColumn("stock", gorm.Expr("stock + ?", r.Qty)).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := transitReservation(tx, r, r.Status, app.ReservationReleased); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExpireReservations releases the reserved stock of expired reservations, each one in its own transaction
+func (sr *Stock) ExpireReservations(before time.Time) (int, error) {
+	var rs []app.StockReservation
+	err := sr.db.Where("status = ? AND expires_at <= ?", app.ReservationReserved, before).Order("id").Find(&rs).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for i := range rs {
+		r := &rs[i]
+		tx := sr.db.Begin()
+
+		ok, err := transitReservation(tx, r, app.ReservationReserved, app.ReservationExpired)
+		if err != nil {
+			tx.Rollback()
+			return n, err
+		}
+		if !ok {
+			tx.Rollback()
+			continue
+		}
+
+		err = stockRow(tx, r.StockKey).UpdateColumn("reserved", gorm.Expr("reserved - ?", r.Qty)).Error
+		if err != nil {
+			tx.Rollback()
+			return n, err
+		}
+		if err := tx.Commit().Error; err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// lockReservations gets the order's reservations in the statuses and locks them until the transaction ends
+func lockReservations(tx *gorm.DB, orderID int, statuses ...string) ([]app.StockReservation, error) {
+	var rs []app.StockReservation
+	err := forUpdate(tx).
+		Where("order_id = ? AND status IN (?)", orderID, statuses).
+		Order("product_id, option_value_id").
+		Find(&rs).Error
+	return rs, err
+}
+
+// stockRow selects the row which keeps the stock of the key
+func stockRow(tx *gorm.DB, k app.StockKey) *gorm.DB {
+	if k.OptionValueID != 0 {
+		return tx.Model(&app.OptionValue{}).Where("id = ?", k.OptionValueID)
+	}
+	return tx.Model(&app.Product{}).Where("id = ?", k.ProductID)
+}
+
+// stockRowExists reports whether the row which keeps the stock of the key still exists
+func stockRowExists(tx *gorm.DB, k app.StockKey) (bool, error) {
+	var n int
+	err := stockRow(tx, k).Count(&n).Error
+	return n > 0, err
+}
+
+// transitReservation changes the reservation's status if it's still in the from status
+func transitReservation(tx *gorm.DB, r *app.StockReservation, from, to string) (bool, error) {
+	res := tx.Model(&app.StockReservation{}).
+		Where("id = ? AND status = ?", r.ID, from).
+		UpdateColumn("status", to)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return false, nil
+	}
+	r.Status = to
+	return true, nil
+}
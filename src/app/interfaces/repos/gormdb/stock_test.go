@@ -0,0 +1,219 @@
+package gormdb
+
+import (
+	"app"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+// newTestDB opens a sqlite database in a temporary file. Transactions begin immediately,
+// so concurrent ones wait for each other instead of failing to upgrade their locks.
+func newTestDB(t *testing.T) (*gorm.DB, func()) {
+	dir, err := ioutil.TempDir("", "gocart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open("sqlite3", filepath.Join(dir, "test.db")+"?_busy_timeout=10000&_txlock=immediate")
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	cleanup := func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+
+	err = db.AutoMigrate(
+		&app.Product{},
+		&app.OptionGroup{},
+		&app.OptionValue{},
+		&app.Order{},
+		&app.OrderProduct{},
+		&app.OrderHistory{},
+		&app.OrderAddress{},
+		&app.OrderTax{},
+		&app.StockReservation{},
+		&app.CartItem{},
+	).Error
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	return db, cleanup
+}
+
+func newTestProduct(t *testing.T, db *gorm.DB, stock int) *app.Product {
+	p := &app.Product{Title: "Simit", Price: app.MustParseMoney("5"), IsActive: true, TrackStock: true, Stock: stock}
+	if err := db.Create(p).Error; err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// placeTestOrder places an order of the product's qty like checkout does
+func placeTestOrder(or *Order, userID, productID, qty int) (*app.Order, error) {
+	o := &app.Order{
+		UserID:   userID,
+		StatusID: 1,
+		Products: []app.OrderProduct{{ProductID: productID, Qty: qty, Price: app.MustParseMoney("5")}},
+		Reservations: []app.StockReservation{{
+			StockKey:  app.StockKey{ProductID: productID},
+			Qty:       qty,
+			Status:    app.ReservationReserved,
+			ExpiresAt: time.Now().Add(time.Hour),
+		}},
+	}
+	return o, or.PlaceOrder(o, &app.OrderHistory{}, &app.Cart{})
+}
+
+func checkTestStock(t *testing.T, db *gorm.DB, name string, productID, stock, reserved int) {
+	var p app.Product
+	if err := db.First(&p, productID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if p.Stock != stock || p.Reserved != reserved {
+		t.Errorf("%s: expected stock %d reserved %d got %d %d", name, stock, reserved, p.Stock, p.Reserved)
+	}
+}
+
+func TestOrder_PlaceOrderConcurrentReservation(t *testing.T) {
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+	or := NewOrder(NewRepo(db))
+	p := newTestProduct(t, db, 5)
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		placed int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(userID int) {
+			defer wg.Done()
+			_, err := placeTestOrder(or, userID, p.ID, 1)
+			if err != nil {
+				if err != app.ErrOutOfStock {
+					t.Errorf("expected %v got %v", app.ErrOutOfStock, err)
+				}
+				return
+			}
+			mu.Lock()
+			placed++
+			mu.Unlock()
+		}(i + 1)
+	}
+	wg.Wait()
+
+	if placed != 5 {
+		t.Errorf("expected 5 orders to reserve all stock got %d", placed)
+	}
+	checkTestStock(t, db, "placed", p.ID, 5, 5)
+
+	var n int
+	if err := db.Model(&app.StockReservation{}).Where("product_id = ?", p.ID).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 {
+		t.Errorf("expected 5 reservations got %d", n)
+	}
+}
+
+func TestOrder_ChangeOrderStatus(t *testing.T) {
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+	or := NewOrder(NewRepo(db))
+	sr := NewStock(NewRepo(db))
+	p := newTestProduct(t, db, 3)
+
+	var orders []*app.Order
+	for i := 0; i < 3; i++ {
+		o, err := placeTestOrder(or, 1, p.ID, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		orders = append(orders, o)
+	}
+	change := func(o *app.Order, from, to int, a app.StockAction) error {
+		o.StatusID = to
+		return or.ChangeOrderStatus(o, from, &app.OrderHistory{}, a)
+	}
+	checkTestStock(t, db, "reserved", p.ID, 3, 3)
+
+	if err := change(orders[0], 1, 2, app.StockCommit); err != nil {
+		t.Fatal(err)
+	}
+	checkTestStock(t, db, "confirmed", p.ID, 2, 2)
+
+	// a change which checked the status before the order is confirmed doesn't release its stock
+	if err := change(orders[0], 1, 3, app.StockRelease); err != app.ErrOrderStatusChanged {
+		t.Errorf("expected %v got %v", app.ErrOrderStatusChanged, err)
+	}
+	checkTestStock(t, db, "stale cancel", p.ID, 2, 2)
+
+	if err := change(orders[1], 1, 3, app.StockRelease); err != nil {
+		t.Fatal(err)
+	}
+	checkTestStock(t, db, "cancelled", p.ID, 2, 1)
+
+	if err := change(orders[0], 2, 3, app.StockRelease); err != nil {
+		t.Fatal(err)
+	}
+	checkTestStock(t, db, "confirmed order cancelled", p.ID, 3, 1)
+
+	if n, err := sr.ExpireReservations(time.Now().Add(2 * time.Hour)); err != nil || n != 1 {
+		t.Fatalf("expected 1 expired reservation got %d, err: %v", n, err)
+	}
+	checkTestStock(t, db, "expired", p.ID, 3, 0)
+
+	// an expired reservation is committed only if the stock is still available
+	if ok, err := sr.SetStock(app.StockKey{ProductID: p.ID}, 0); err != nil || !ok {
+		t.Fatalf("expected stock to be set, err: %v", err)
+	}
+	if err := change(orders[2], 1, 2, app.StockCommit); err != app.ErrOutOfStock {
+		t.Errorf("expected %v got %v", app.ErrOutOfStock, err)
+	}
+	if ok, err := sr.SetStock(app.StockKey{ProductID: p.ID}, 3); err != nil || !ok {
+		t.Fatalf("expected stock to be set, err: %v", err)
+	}
+	if err := change(orders[2], 1, 2, app.StockCommit); err != nil {
+		t.Fatal(err)
+	}
+	checkTestStock(t, db, "expired confirmed", p.ID, 2, 0)
+}
+
+func TestOrder_ChangeOrderStatusDeletedProduct(t *testing.T) {
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+	or := NewOrder(NewRepo(db))
+	p := newTestProduct(t, db, 3)
+
+	o, err := placeTestOrder(or, 1, p.ID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(p).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// the order of a deleted product can still be confirmed
+	o.StatusID = 2
+	if err := or.ChangeOrderStatus(o, 1, &app.OrderHistory{}, app.StockCommit); err != nil {
+		t.Fatalf("expected the order to be confirmed got %v", err)
+	}
+
+	var r app.StockReservation
+	if err := db.First(&r, "order_id = ?", o.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != app.ReservationCommitted {
+		t.Errorf("expected reservation to be committed got %s", r.Status)
+	}
+}
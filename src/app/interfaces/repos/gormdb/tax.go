@@ -0,0 +1,42 @@
+package gormdb
+
+import (
+	"app"
+
+	"github.com/jinzhu/gorm"
+)
+
+func NewTax(r *Repo) *Tax {
+	return &Tax{r}
+}
+
+type Tax struct {
+	*Repo
+}
+
+func (tr *Tax) FindTaxClasses() ([]app.TaxClass, error) {
+	var tcs []app.TaxClass
+	return tcs, tr.db.Order("rate, id").Find(&tcs).Error
+}
+
+// ProductTaxClass gets the product's tax class, or the class of its first category which has one
+func (tr *Tax) ProductTaxClass(productID int) (*app.TaxClass, error) {
+	var tc app.TaxClass
+	err := tr.db.Joins("JOIN products ON products.tax_class_id = tax_classes.id").
+		Where("products.id = ?", productID).
+		First(&tc).Error
+	if !gorm.IsRecordNotFoundError(err) {
+		return &tc, err
+	}
+
+	err = tr.db.Joins("JOIN categories ON categories.tax_class_id = tax_classes.id").
+		Joins("JOIN pivot_product_category ON pivot_product_category.category_id = categories.id").
+		Where("pivot_product_category.product_id = ?", productID).
+		Order("categories.id").
+		First(&tc).Error
+	return &tc, err
+}
+
+func (tr *Tax) DeleteTaxClass(tc *app.TaxClass) error {
+	return tr.db.Delete(tc).Error
+}
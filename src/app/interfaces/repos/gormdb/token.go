@@ -0,0 +1,95 @@
+package gormdb
+
+import (
+	"app"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+func NewToken(r *Repo) *Token {
+	return &Token{r}
+}
+
+type Token struct {
+	*Repo
+}
+
+func (tr *Token) OneUser(id int) (*app.User, error) {
+	var u app.User
+	return &u, tr.One(&u, id)
+}
+
+func (tr *Token) CreateSession(s *app.Session) error {
+	return tr.Store(s)
+}
+
+func (tr *Token) OneSessionByHash(hash string) (*app.Session, error) {
+	var s app.Session
+	return &s, tr.OneBy(&s, app.DBWhere{"token_hash": hash})
+}
+
+// RotateSession revokes the old session only if it isn't revoked yet,
+// so a refresh token can't be rotated twice by concurrent requests
+func (tr *Token) RotateSession(old, new *app.Session) (bool, error) {
+	tx := tr.db.Begin()
+
+	now := time.Now()
+	res := tx.Model(&app.Session{}).Where("id=? AND revoked_at IS NULL", old.ID).Update("revoked_at", now)
+	if res.Error != nil {
+		tx.Rollback()
+		return false, res.Error
+	}
+	if res.RowsAffected == 0 {
+		tx.Rollback()
+		return false, nil
+	}
+
+	if err := tx.Create(new).Error; err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return false, err
+	}
+	old.RevokedAt = &now
+	return true, nil
+}
+
+func (tr *Token) RevokeSessionFamily(family string) error {
+	return tr.db.Model(&app.Session{}).Where("family=? AND revoked_at IS NULL", family).Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUserSessions revokes user's sessions and increases its token version in a single transaction
+func (tr *Token) RevokeUserSessions(u *app.User) error {
+	tx := tr.db.Begin()
+
+	if err := tx.Model(u).UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Model(&app.Session{}).Where("user_id=? AND revoked_at IS NULL", u.ID).Update("revoked_at", time.Now()).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	u.TokenVersion++
+	return nil
+}
+
+func (tr *Token) RevokeToken(t *app.RevokedToken) error {
+	return tr.Store(t)
+}
+
+func (tr *Token) IsTokenRevoked(jti string) (bool, error) {
+	return tr.ExistsBy(&app.RevokedToken{}, app.DBWhere{"jti": jti})
+}
+
+func (tr *Token) DeleteExpiredTokens(before time.Time) error {
+	return tr.db.Where("expires_at < ?", before).Delete(app.RevokedToken{}).Error
+}
@@ -20,10 +20,76 @@ func (ur *User) ExistsByEmail(email string) (bool, error) {
 	return ur.ExistsBy(&u, app.DBWhere{"Email": email})
 }
 
-func (ur *User) UpdateFields(kv map[string]interface{}) error {
-	return ur.Repo.UpdateFields(&app.User{}, kv)
-}
-
 func (ur *User) Create(u *app.User) error {
 	return ur.Store(u)
 }
+
+// UpdateUser updates only given user's fields
+func (ur *User) UpdateUser(u *app.User, kv map[string]interface{}) error {
+	return ur.Repo.UpdateFields(u, kv)
+}
+
+func (ur *User) OneByID(id int) (*app.User, error) {
+	var u app.User
+	return &u, ur.One(&u, id)
+}
+
+func (ur *User) OneIdentity(provider, subject string) (*app.SocialIdentity, error) {
+	var i app.SocialIdentity
+	return &i, ur.OneBy(&i, app.DBWhere{"provider": provider, "subject": subject})
+}
+
+func (ur *User) CreateIdentity(i *app.SocialIdentity) error {
+	return ur.Store(i)
+}
+
+// CreateWithIdentity creates the user and its social identity in a single transaction
+func (ur *User) CreateWithIdentity(u *app.User, i *app.SocialIdentity) error {
+	tx := ur.db.Begin()
+
+	if err := tx.Create(u).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	i.UserID = u.ID
+	if err := tx.Create(i).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (ur *User) FindIdentitiesByUser(userID int) ([]app.SocialIdentity, error) {
+	var is []app.SocialIdentity
+	return is, ur.FindBy(&is, app.DBWhere{"user_id": userID}, &app.DBFilter{OrderBy: "id"})
+}
+
+func (ur *User) DeleteIdentity(i *app.SocialIdentity) error {
+	return ur.db.Delete(i).Error
+}
+
+// FindUsers gets users matching the query and the conditions and total count of them.
+// The query is searched in email, first name and last name.
+func (ur *User) FindUsers(query string, w app.DBWhere, f *app.DBFilter) ([]app.User, int, error) {
+	qry := ur.db.Model(&app.User{}).Where(ur.where(w))
+
+	if query != "" {
+		like := "%" + query + "%"
+		qry = qry.Where("email LIKE ? OR first_name LIKE ? OR last_name LIKE ?", like, like, like)
+	}
+
+	var total int
+	if err := qry.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var us []app.User
+	return us, total, ur.filter(qry, f).Find(&us).Error
+}
+
+// DeleteUser soft deletes the user
+func (ur *User) DeleteUser(u *app.User) error {
+	return ur.db.Delete(u).Error
+}
@@ -0,0 +1,171 @@
+package mockdb
+
+import (
+	"app"
+	"time"
+)
+
+// Stock keeps products' and option values' stock and reservations in memory for use cases' tests.
+// It places orders to reserve their stock and changes their statuses to commit or release it.
+// It isn't safe for concurrent use, concurrent reservations are tested against the database by gormdb.
+type Stock struct {
+	*Repo
+	products     map[int]*app.Product
+	values       map[int]*app.OptionValue
+	reservations []*app.StockReservation
+	orders       int
+}
+
+// AddProduct adds the product with its option groups and values, ids are assigned in order
+func (sr *Stock) AddProduct(p *app.Product) {
+	if sr.products == nil {
+		sr.products = make(map[int]*app.Product)
+		sr.values = make(map[int]*app.OptionValue)
+	}
+	p.ID = len(sr.products) + 1
+	sr.products[p.ID] = p
+
+	for i := range p.OptionGroups {
+		g := &p.OptionGroups[i]
+		g.ID, g.ProductID = i+1, p.ID
+		for j := range g.Values {
+			v := &g.Values[j]
+			v.ID, v.GroupID = len(sr.values)+1, g.ID
+			sr.values[v.ID] = v
+		}
+	}
+}
+
+// Product gets a copy of the product with its option groups and values
+func (sr *Stock) Product(id int) (*app.Product, error) {
+	p, ok := sr.products[id]
+	if !ok {
+		return nil, errNotFound
+	}
+	cp := *p
+	cp.OptionGroups = make([]app.OptionGroup, len(p.OptionGroups))
+	for i, g := range p.OptionGroups {
+		g.Values = append([]app.OptionValue(nil), g.Values...)
+		cp.OptionGroups[i] = g
+	}
+	return &cp, nil
+}
+
+// stockCount points to the stock and the reserved quantity of a product or an option value
+type stockCount struct {
+	stock, reserved *int
+}
+
+func (c stockCount) available() int {
+	return *c.stock - *c.reserved
+}
+
+func (sr *Stock) count(k app.StockKey) (stockCount, bool) {
+	if k.OptionValueID != 0 {
+		v, ok := sr.values[k.OptionValueID]
+		if !ok {
+			return stockCount{}, false
+		}
+		return stockCount{&v.Stock, &v.Reserved}, true
+	}
+	p, ok := sr.products[k.ProductID]
+	if !ok {
+		return stockCount{}, false
+	}
+	return stockCount{&p.Stock, &p.Reserved}, true
+}
+
+// PlaceOrder reserves the order's stock if all of it is available
+func (sr *Stock) PlaceOrder(o *app.Order, h *app.OrderHistory, c *app.Cart) error {
+	for _, r := range o.Reservations {
+		if sc, ok := sr.count(r.StockKey); !ok || sc.available() < r.Qty {
+			return app.ErrOutOfStock
+		}
+	}
+
+	sr.orders++
+	o.ID = sr.orders
+	for i := range o.Reservations {
+		r := o.Reservations[i]
+		r.ID = len(sr.reservations) + 1
+		r.OrderID = o.ID
+		sr.reservations = append(sr.reservations, &r)
+		sc, _ := sr.count(r.StockKey)
+		*sc.reserved += r.Qty
+	}
+	return nil
+}
+
+func (sr *Stock) SetStock(k app.StockKey, stock int) (bool, error) {
+	sc, ok := sr.count(k)
+	if !ok || *sc.reserved > stock {
+		return false, nil
+	}
+	*sc.stock = stock
+	return true, nil
+}
+
+func (sr *Stock) AdjustStock(k app.StockKey, delta int) (bool, error) {
+	sc, ok := sr.count(k)
+	if !ok || *sc.stock+delta < *sc.reserved {
+		return false, nil
+	}
+	*sc.stock += delta
+	return true, nil
+}
+
+func (sr *Stock) FindLowStockProducts(f *app.DBFilter) ([]app.Product, int, error) {
+	var ps []app.Product
+	for id := 1; id <= len(sr.products); id++ {
+		if p := sr.products[id]; p.IsLowStock() {
+			ps = append(ps, *p)
+		}
+	}
+	return ps, len(ps), nil
+}
+
+// ChangeOrderStatus commits or releases the order's stock by the action
+func (sr *Stock) ChangeOrderStatus(o *app.Order, from int, h *app.OrderHistory, a app.StockAction) error {
+	for _, r := range sr.orderReservations(o.ID) {
+		sc, _ := sr.count(r.StockKey)
+		switch {
+		case a == app.StockCommit && r.Status == app.ReservationReserved:
+			*sc.stock -= r.Qty
+			*sc.reserved -= r.Qty
+			r.Status = app.ReservationCommitted
+		case a == app.StockCommit && r.Status == app.ReservationExpired:
+			*sc.stock -= r.Qty
+			r.Status = app.ReservationCommitted
+		case a == app.StockRelease && r.Status == app.ReservationReserved:
+			*sc.reserved -= r.Qty
+			r.Status = app.ReservationReleased
+		case a == app.StockRelease && r.Status == app.ReservationCommitted:
+			*sc.stock += r.Qty
+			r.Status = app.ReservationReleased
+		}
+	}
+	return nil
+}
+
+func (sr *Stock) ExpireReservations(before time.Time) (int, error) {
+	var n int
+	for _, r := range sr.reservations {
+		if r.Status == app.ReservationReserved && !r.ExpiresAt.After(before) {
+			sc, _ := sr.count(r.StockKey)
+			*sc.reserved -= r.Qty
+			r.Status = app.ReservationExpired
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (sr *Stock) orderReservations(orderID int) []*app.StockReservation {
+	var rs []*app.StockReservation
+	for _, r := range sr.reservations {
+		if r.OrderID == orderID && r.Status != app.ReservationReleased {
+			rs = append(rs, r)
+		}
+	}
+	return rs
+}
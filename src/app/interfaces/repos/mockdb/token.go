@@ -0,0 +1,87 @@
+package mockdb
+
+import (
+	"app"
+	"time"
+)
+
+type Token struct {
+	*Repo
+	Users    *User
+	sessions []*app.Session
+	revoked  []*app.RevokedToken
+}
+
+func (tr *Token) OneUser(id int) (*app.User, error) {
+	return tr.Users.OneByID(id)
+}
+
+func (tr *Token) CreateSession(s *app.Session) error {
+	s.ID = len(tr.sessions) + 1
+	tr.sessions = append(tr.sessions, s)
+	return nil
+}
+
+func (tr *Token) OneSessionByHash(hash string) (*app.Session, error) {
+	for _, s := range tr.sessions {
+		if s.TokenHash == hash {
+			return s, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+func (tr *Token) RotateSession(old, new *app.Session) (bool, error) {
+	if old.IsRevoked() {
+		return false, nil
+	}
+	now := time.Now()
+	old.RevokedAt = &now
+	return true, tr.CreateSession(new)
+}
+
+func (tr *Token) RevokeSessionFamily(family string) error {
+	now := time.Now()
+	for _, s := range tr.sessions {
+		if s.Family == family && !s.IsRevoked() {
+			s.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (tr *Token) RevokeUserSessions(u *app.User) error {
+	now := time.Now()
+	for _, s := range tr.sessions {
+		if s.UserID == u.ID && !s.IsRevoked() {
+			s.RevokedAt = &now
+		}
+	}
+	u.TokenVersion++
+	return nil
+}
+
+func (tr *Token) RevokeToken(t *app.RevokedToken) error {
+	tr.revoked = append(tr.revoked, t)
+	return nil
+}
+
+func (tr *Token) IsTokenRevoked(jti string) (bool, error) {
+	for _, t := range tr.revoked {
+		if t.JTI == jti {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (tr *Token) DeleteExpiredTokens(before time.Time) error {
+	revoked := tr.revoked[:0]
+	for _, t := range tr.revoked {
+		if !t.ExpiresAt.Before(before) {
+			revoked = append(revoked, t)
+		}
+	}
+	tr.revoked = revoked
+	return nil
+}
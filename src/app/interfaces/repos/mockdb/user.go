@@ -1,19 +1,27 @@
 package mockdb
 
-import "app"
+import (
+	"app"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
 
 type User struct {
 	*Repo
-	users []*app.User
+	users      []*app.User
+	identities []*app.SocialIdentity
 }
 
 func (ur *User) Create(u *app.User) error {
+	u.ID = len(ur.users) + 1
 	ur.users = append(ur.users, u)
 	return nil
 }
 
 func (ur *User) OneByEmail(email string) (*app.User, error) {
-	for _, u := range ur.users {
+	for _, u := range ur.live() {
 		if u.Email == email {
 			return u, nil
 		}
@@ -22,7 +30,7 @@ func (ur *User) OneByEmail(email string) (*app.User, error) {
 }
 
 func (ur *User) ExistsByEmail(email string) (bool, error) {
-	for _, u := range ur.users {
+	for _, u := range ur.live() {
 		if u.Email == email {
 			return true, nil
 		}
@@ -30,6 +38,109 @@ func (ur *User) ExistsByEmail(email string) (bool, error) {
 	return false, nil
 }
 
-func (ur *User) UpdateFields(kv map[string]interface{}) error {
+func (ur *User) UpdateUser(u *app.User, kv map[string]interface{}) error {
+	v := reflect.ValueOf(u).Elem()
+	for k, val := range kv {
+		f := v.FieldByName(k)
+		if !f.IsValid() {
+			return fmt.Errorf("user has no field: %s", k)
+		}
+		f.Set(reflect.ValueOf(val))
+	}
+	return nil
+}
+
+func (ur *User) OneByID(id int) (*app.User, error) {
+	for _, u := range ur.live() {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+func (ur *User) OneIdentity(provider, subject string) (*app.SocialIdentity, error) {
+	for _, i := range ur.identities {
+		if i.Provider == provider && i.Subject == subject {
+			return i, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+func (ur *User) CreateIdentity(i *app.SocialIdentity) error {
+	i.ID = len(ur.identities) + 1
+	ur.identities = append(ur.identities, i)
+	return nil
+}
+
+func (ur *User) CreateWithIdentity(u *app.User, i *app.SocialIdentity) error {
+	if err := ur.Create(u); err != nil {
+		return err
+	}
+	i.UserID = u.ID
+	return ur.CreateIdentity(i)
+}
+
+func (ur *User) FindIdentitiesByUser(userID int) ([]app.SocialIdentity, error) {
+	var is []app.SocialIdentity
+	for _, i := range ur.identities {
+		if i.UserID == userID {
+			is = append(is, *i)
+		}
+	}
+	return is, nil
+}
+
+func (ur *User) DeleteIdentity(i *app.SocialIdentity) error {
+	for k, v := range ur.identities {
+		if v.ID == i.ID {
+			ur.identities = append(ur.identities[:k], ur.identities[k+1:]...)
+			return nil
+		}
+	}
+	return errNotFound
+}
+
+func (ur *User) FindUsers(query string, w app.DBWhere, f *app.DBFilter) ([]app.User, int, error) {
+	var us []app.User
+	for _, u := range ur.live() {
+		if query != "" && !strings.Contains(u.Email+" "+u.FirstName+" "+u.LastName, query) {
+			continue
+		}
+		if v, ok := w["is_activated"]; ok && u.IsActivated != v {
+			continue
+		}
+		if v, ok := w["is_admin"]; ok && u.IsAdmin != v {
+			continue
+		}
+		us = append(us, *u)
+	}
+
+	total := len(us)
+	if f.Offset > len(us) {
+		f.Offset = len(us)
+	}
+	us = us[f.Offset:]
+	if f.Limit > 0 && f.Limit < len(us) {
+		us = us[:f.Limit]
+	}
+	return us, total, nil
+}
+
+func (ur *User) DeleteUser(u *app.User) error {
+	now := time.Now()
+	u.DeletedAt = &now
 	return nil
 }
+
+// live gets users which aren't deleted
+func (ur *User) live() []*app.User {
+	var us []*app.User
+	for _, u := range ur.users {
+		if u.DeletedAt == nil {
+			us = append(us, u)
+		}
+	}
+	return us
+}
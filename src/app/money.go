@@ -0,0 +1,269 @@
+package app
+
+import (
+	"app/interfaces/errs"
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the shop's currency. Amounts are stored in minor units
+// without their currency, so the shop must use a single currency.
+var DefaultCurrency = "TRY"
+
+// minorDigits are the number of minor unit digits of currencies, 2 if it isn't listed
+var minorDigits = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+	"BHD": 3,
+	"KWD": 3,
+}
+
+// Money is an exact amount in minor units of its currency, {1250 TRY} is 12.50 TRY.
+// The zero Money has no currency, it takes the currency of the money it's added to.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// NewMoney creates money of the amount in minor units
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ParseMoney parses a decimal amount like "12.5" in major units of the currency.
+// Amounts that have more fraction digits than the currency are rejected.
+func ParseMoney(s, currency string) (Money, error) {
+	s = strings.TrimSpace(s)
+	digits := currencyDigits(currency)
+
+	neg := strings.HasPrefix(s, "-")
+	parts := strings.SplitN(strings.TrimPrefix(s, "-"), ".", 2)
+	if parts[0] == "" || strings.Trim(parts[0], "0123456789") != "" {
+		return Money{}, errs.BadRequest("invalid amount: %s", s)
+	}
+
+	frac := ""
+	if len(parts) == 2 {
+		frac = parts[1]
+		if frac == "" || strings.Trim(frac, "0123456789") != "" {
+			return Money{}, errs.BadRequest("invalid amount: %s", s)
+		}
+		if len(strings.TrimRight(frac, "0")) > digits {
+			return Money{}, errs.BadRequest("amount can have at most %d fraction digits: %s", digits, s)
+		}
+	}
+	frac = (frac + strings.Repeat("0", digits))[:digits]
+
+	amount, err := strconv.ParseInt(parts[0]+frac, 10, 64)
+	if err != nil {
+		return Money{}, errs.BadRequest("invalid amount: %s", s)
+	}
+	if neg {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// MustParseMoney parses the amount in DefaultCurrency, it panics on error
+func MustParseMoney(s string) Money {
+	m, err := ParseMoney(s, DefaultCurrency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// MinorUnits gets the number of minor units in a major unit of the currency, 100 for TRY
+func MinorUnits(currency string) int64 {
+	u := int64(1)
+	for i := 0; i < currencyDigits(currency); i++ {
+		u *= 10
+	}
+	return u
+}
+
+func currencyDigits(currency string) int {
+	if d, ok := minorDigits[currency]; ok {
+		return d
+	}
+	return 2
+}
+
+// IsZero checks the amount is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Add adds the money, both must have the same currency
+func (m Money) Add(o Money) Money {
+	c := m.currency(o)
+	return Money{Amount: m.Amount + o.Amount, Currency: c}
+}
+
+// Sub subtracts the money, both must have the same currency
+func (m Money) Sub(o Money) Money {
+	c := m.currency(o)
+	return Money{Amount: m.Amount - o.Amount, Currency: c}
+}
+
+// Mul multiplies the amount by n, like price by quantity
+func (m Money) Mul(n int) Money {
+	return Money{Amount: m.Amount * int64(n), Currency: m.Currency}
+}
+
+// MulRatio multiplies the amount by num/den, the result is rounded
+// half away from zero to the currency's minor unit
+func (m Money) MulRatio(num, den int64) Money {
+	return Money{Amount: divRound(m.Amount*num, den), Currency: m.Currency}
+}
+
+// Cmp compares the amounts, it returns -1, 0 or 1. Both must have the same currency.
+func (m Money) Cmp(o Money) int {
+	m.currency(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	}
+	return 0
+}
+
+// currency gets the common currency of the moneys, it panics if they're different
+func (m Money) currency(o Money) string {
+	switch {
+	case m.Currency == "":
+		return o.Currency
+	case o.Currency == "" || o.Currency == m.Currency:
+		return m.Currency
+	}
+	panic(fmt.Sprintf("money currencies are different: %s, %s", m.Currency, o.Currency))
+}
+
+// Decimal formats the amount in major units like "12.50"
+func (m Money) Decimal() string {
+	digits := currencyDigits(m.Currency)
+
+	a := m.Amount
+	sign := ""
+	if a < 0 {
+		sign, a = "-", -a
+	}
+
+	s := strconv.FormatInt(a, 10)
+	if digits == 0 {
+		return sign + s
+	}
+	if len(s) <= digits {
+		s = strings.Repeat("0", digits-len(s)+1) + s
+	}
+	return sign + s[:len(s)-digits] + "." + s[len(s)-digits:]
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+type moneyJSON struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON encodes money like {"amount":"12.50","currency":"TRY"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	c := m.Currency
+	if c == "" {
+		c = DefaultCurrency
+	}
+	return json.Marshal(moneyJSON{Amount: Money{m.Amount, c}.Decimal(), Currency: c})
+}
+
+// UnmarshalJSON decodes money encoded by MarshalJSON, or a decimal amount
+// like 12.5 or "12.5" in DefaultCurrency. Numbers are parsed exactly, not as floats.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '{' {
+		var mj moneyJSON
+		if err := json.Unmarshal(b, &mj); err != nil {
+			return errs.BadRequest("invalid money").SetInner(err)
+		}
+		if mj.Currency == "" {
+			mj.Currency = DefaultCurrency
+		}
+		// amounts are stored without their currency, so only the shop's currency is accepted
+		if mj.Currency != DefaultCurrency {
+			return errs.BadRequest("currency must be %s: %s", DefaultCurrency, mj.Currency)
+		}
+		v, err := ParseMoney(mj.Amount, mj.Currency)
+		if err != nil {
+			return err
+		}
+		*m = v
+		return nil
+	}
+
+	s := string(b)
+	if len(b) > 0 && b[0] == '"' {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return errs.BadRequest("invalid money").SetInner(err)
+		}
+	}
+	v, err := ParseMoney(s, DefaultCurrency)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value stores the amount in minor units
+func (m Money) Value() (driver.Value, error) {
+	return m.Amount, nil
+}
+
+// Scan reads the amount in minor units, the currency is DefaultCurrency
+func (m *Money) Scan(v interface{}) error {
+	var (
+		a   int64
+		err error
+	)
+	switch v := v.(type) {
+	case nil:
+	case int64:
+		a = v
+	case []byte:
+		a, err = strconv.ParseInt(string(v), 10, 64)
+	case string:
+		a, err = strconv.ParseInt(v, 10, 64)
+	default:
+		err = fmt.Errorf("unsupported money type: %T", v)
+	}
+	if err != nil {
+		return errs.WrapMsg(err, "money can't scanned")
+	}
+	*m = Money{Amount: a, Currency: DefaultCurrency}
+	return nil
+}
+
+// divRound divides rounding half away from zero
+func divRound(a, b int64) int64 {
+	if b < 0 {
+		a, b = -a, -b
+	}
+	q, r := a/b, a%b
+	if r < 0 {
+		r = -r
+	}
+	if 2*r >= b {
+		if a < 0 {
+			q--
+		} else {
+			q++
+		}
+	}
+	return q
+}
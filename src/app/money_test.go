@@ -0,0 +1,167 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in       string
+		currency string
+		amount   int64
+		ok       bool
+	}{
+		{"12.5", "TRY", 1250, true},
+		{"12.50", "TRY", 1250, true},
+		{"0.1", "TRY", 10, true},
+		{"-3.07", "TRY", -307, true},
+		{"7", "TRY", 700, true},
+		{"1.500", "TRY", 150, true},
+		{"100", "JPY", 100, true},
+		{"1.005", "TRY", 0, false},
+		{"1.5", "JPY", 0, false},
+		{"", "TRY", 0, false},
+		{"1.", "TRY", 0, false},
+		{"1e3", "TRY", 0, false},
+		{"abc", "TRY", 0, false},
+	}
+	for _, tt := range tests {
+		m, err := ParseMoney(tt.in, tt.currency)
+		if (err == nil) != tt.ok {
+			t.Errorf("%q: expected ok %v got err %v", tt.in, tt.ok, err)
+			continue
+		}
+		if tt.ok && (m.Amount != tt.amount || m.Currency != tt.currency) {
+			t.Errorf("%q: expected %d %s got %+v", tt.in, tt.amount, tt.currency, m)
+		}
+	}
+}
+
+func TestMoney_Arithmetic(t *testing.T) {
+	a, b := NewMoney(1050, "TRY"), NewMoney(325, "TRY")
+
+	if m := a.Add(b); m.Amount != 1375 || m.Currency != "TRY" {
+		t.Errorf("unexpected sum %s", m)
+	}
+	if m := b.Sub(a); m.Amount != -725 {
+		t.Errorf("unexpected difference %s", m)
+	}
+	if m := b.Mul(3); m.Amount != 975 {
+		t.Errorf("unexpected product %s", m)
+	}
+	if m := (Money{}).Add(a); m != a {
+		t.Errorf("expected zero money to take the currency got %s", m)
+	}
+	if a.Cmp(b) != 1 || b.Cmp(a) != -1 || a.Cmp(a) != 0 {
+		t.Error("unexpected comparison")
+	}
+
+	// rounding half away from zero
+	tests := []struct {
+		amount, num, den, expected int64
+	}{
+		{1000, 18, 100, 180},
+		{1005, 1, 2, 503},
+		{1003, 1, 2, 502},
+		{-1005, 1, 2, -503},
+		{999, 18, 118, 152},
+	}
+	for _, tt := range tests {
+		if m := NewMoney(tt.amount, "TRY").MulRatio(tt.num, tt.den); m.Amount != tt.expected {
+			t.Errorf("%d * %d/%d: expected %d got %d", tt.amount, tt.num, tt.den, tt.expected, m.Amount)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected different currencies to panic")
+		}
+	}()
+	a.Add(NewMoney(1, "USD"))
+}
+
+func TestMoney_Decimal(t *testing.T) {
+	tests := []struct {
+		m        Money
+		expected string
+	}{
+		{NewMoney(1250, "TRY"), "12.50"},
+		{NewMoney(5, "TRY"), "0.05"},
+		{NewMoney(-5, "TRY"), "-0.05"},
+		{NewMoney(0, "TRY"), "0.00"},
+		{NewMoney(100, "JPY"), "100"},
+		{NewMoney(1234, "KWD"), "1.234"},
+	}
+	for _, tt := range tests {
+		if s := tt.m.Decimal(); s != tt.expected {
+			t.Errorf("expected %s got %s", tt.expected, s)
+		}
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	b, err := json.Marshal(NewMoney(1250, "TRY"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"amount":"12.50","currency":"TRY"}` {
+		t.Errorf("unexpected json %s", b)
+	}
+
+	for _, in := range []string{`{"amount":"12.50","currency":"TRY"}`, `"12.5"`, `12.50`} {
+		var m Money
+		if err := json.Unmarshal([]byte(in), &m); err != nil {
+			t.Errorf("%s: %v", in, err)
+			continue
+		}
+		if m != NewMoney(1250, DefaultCurrency) {
+			t.Errorf("%s: unexpected money %+v", in, m)
+		}
+	}
+
+	var m Money
+	if err := json.Unmarshal([]byte(`12.345`), &m); err == nil {
+		t.Error("expected too many fraction digits to be rejected")
+	}
+	if err := json.Unmarshal([]byte(`{"amount":"1000","currency":"JPY"}`), &m); err == nil {
+		t.Error("expected other currencies to be rejected")
+	}
+}
+
+func TestMinorUnits(t *testing.T) {
+	for c, expected := range map[string]int64{"TRY": 100, "JPY": 1, "KWD": 1000} {
+		if u := MinorUnits(c); u != expected {
+			t.Errorf("%s: expected %d got %d", c, expected, u)
+		}
+	}
+}
+
+func TestMoney_SQL(t *testing.T) {
+	v, err := NewMoney(1250, "TRY").Value()
+	if err != nil || v != int64(1250) {
+		t.Errorf("expected 1250 got %v, err: %v", v, err)
+	}
+
+	var m Money
+	for _, v := range []interface{}{int64(1250), []byte("1250"), "1250"} {
+		if err := m.Scan(v); err != nil || m != NewMoney(1250, DefaultCurrency) {
+			t.Errorf("%T: unexpected money %+v, err: %v", v, m, err)
+		}
+	}
+	if err := m.Scan(1.5); err == nil {
+		t.Error("expected float to be rejected")
+	}
+}
+
+func TestOrder_SetTotal(t *testing.T) {
+	o := Order{Products: []OrderProduct{
+		{Qty: 3, Price: NewMoney(10, "TRY")},
+		{Qty: 1, Price: NewMoney(20, "TRY")},
+	}}
+	o.SetTotal()
+	o.SetTotal()
+	if o.Total != NewMoney(50, "TRY") {
+		t.Errorf("expected idempotent total of 0.50 TRY got %s", o.Total)
+	}
+}
@@ -0,0 +1,231 @@
+package app
+
+import (
+	"app/interfaces/errs"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OptionGroup is a group of a product's options like portion size or extra sauces
+type OptionGroup struct {
+	Model
+	ProductID int    `json:"-" gorm:"index"`
+	Name      string `json:"name"`
+	// Required groups must have at least one selected value
+	Required bool `json:"required"`
+	// MinSelect and MaxSelect limit the number of selected values, zero MaxSelect is unlimited
+	MinSelect  int `json:"minSelect"`
+	MaxSelect  int `json:"maxSelect"`
+	SortNumber int `json:"sortNumber"`
+
+	Values []OptionValue `json:"values" gorm:"foreignkey:GroupID"`
+}
+
+// OptionValue is a choice of an option group, its stock is tracked like products' stock
+type OptionValue struct {
+	Model
+	GroupID int    `json:"-" gorm:"index"`
+	Name    string `json:"name"`
+	// PriceDelta is added to the product's price, it can be negative
+	PriceDelta Money `json:"priceDelta"`
+	SortNumber int   `json:"sortNumber"`
+	IsActive   bool  `json:"isActive"`
+	TrackStock bool  `json:"trackStock"`
+	// Stock and Reserved are shown only to admins by stock responses
+	Stock    int `json:"-"`
+	Reserved int `json:"-"`
+}
+
+// Available gets the quantity which can be ordered, it's meaningful only if the value tracks stock
+func (v *OptionValue) Available() int {
+	return v.Stock - v.Reserved
+}
+
+// checkSelection checks n values of the group can be selected.
+// Optional groups can be skipped, MinSelect applies only if any value is selected.
+func (g *OptionGroup) checkSelection(n int) error {
+	if n == 0 && !g.Required {
+		return nil
+	}
+
+	min := g.MinSelect
+	if g.Required && min < 1 {
+		min = 1
+	}
+	if n < min {
+		return errs.BadRequest("select at least %d of %s", min, g.Name)
+	}
+	if g.MaxSelect > 0 && n > g.MaxSelect {
+		return errs.BadRequest("select at most %d of %s", g.MaxSelect, g.Name)
+	}
+	return nil
+}
+
+// OptionValue finds the product's option value, option groups must be loaded
+func (p *Product) OptionValue(id int) (*OptionValue, bool) {
+	for i := range p.OptionGroups {
+		for j := range p.OptionGroups[i].Values {
+			if v := &p.OptionGroups[i].Values[j]; v.ID == id {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// SelectOptions validates the chosen option values by the product's option groups
+// and gets a snapshot of them, the price with the options can't be negative.
+// Option groups and their values must be loaded.
+func (p *Product) SelectOptions(valueIDs []int) (ProductOptions, error) {
+	chosen := make(map[int]bool)
+	for _, id := range valueIDs {
+		if chosen[id] {
+			return nil, errs.BadRequest("option %d is selected more than once", id)
+		}
+		chosen[id] = true
+	}
+
+	var po ProductOptions
+	for _, g := range p.OptionGroups {
+		n := 0
+		for _, v := range g.Values {
+			if !chosen[v.ID] {
+				continue
+			}
+			delete(chosen, v.ID)
+			if !v.IsActive {
+				return nil, errs.BadRequest("%s isn't available", v.Name)
+			}
+			n++
+			po = append(po, SelectedOption{GroupID: g.ID, Group: g.Name, ValueID: v.ID, Value: v.Name, PriceDelta: v.PriceDelta})
+		}
+		if err := g.checkSelection(n); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(chosen) > 0 {
+		return nil, errs.BadRequest("invalid options for %s", p.Title)
+	}
+	// negative price deltas can't make the unit price negative
+	if p.Price.Add(po.PriceDelta()).Amount < 0 {
+		return nil, errs.BadRequest("price of %s can't be negative with the chosen options", p.Title)
+	}
+	return po, nil
+}
+
+// SelectedOption is a snapshot of a chosen option value
+type SelectedOption struct {
+	GroupID    int    `json:"groupId,omitempty"`
+	Group      string `json:"group,omitempty"`
+	ValueID    int    `json:"valueId,omitempty"`
+	Value      string `json:"value"`
+	PriceDelta Money  `json:"priceDelta"`
+}
+
+// ProductOptions are the chosen options of a cart item or an order product, they're stored as JSON.
+// Options which were stored as free-form text are read as a single option without ids.
+type ProductOptions []SelectedOption
+
+// ValueIDs gets ids of the chosen values
+func (po ProductOptions) ValueIDs() []int {
+	var ids []int
+	for _, o := range po {
+		if o.ValueID != 0 {
+			ids = append(ids, o.ValueID)
+		}
+	}
+	return ids
+}
+
+// Key identifies the chosen values regardless of their order, it's used to merge cart items
+func (po ProductOptions) Key() string {
+	ids := po.ValueIDs()
+	sort.Ints(ids)
+
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.Itoa(id)
+	}
+	return strings.Join(s, ",")
+}
+
+// PriceDelta gets the total price change of the options
+func (po ProductOptions) PriceDelta() Money {
+	var m Money
+	for _, o := range po {
+		m = m.Add(o.PriceDelta)
+	}
+	return m
+}
+
+// Equal checks the options are the same snapshot
+func (po ProductOptions) Equal(o ProductOptions) bool {
+	if len(po) != len(o) {
+		return false
+	}
+	for i := range po {
+		if po[i] != o[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the options like "Size: Large, Extra: Sauce"
+func (po ProductOptions) String() string {
+	s := make([]string, len(po))
+	for i, o := range po {
+		if o.Group == "" {
+			s[i] = o.Value
+			continue
+		}
+		s[i] = o.Group + ": " + o.Value
+	}
+	return strings.Join(s, ", ")
+}
+
+// Value stores the options as JSON, no options are stored as an empty string
+func (po ProductOptions) Value() (driver.Value, error) {
+	if len(po) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(po)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan reads options stored as JSON or as free-form text
+func (po *ProductOptions) Scan(v interface{}) error {
+	var s string
+	switch v := v.(type) {
+	case nil:
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("unsupported product options type: %T", v)
+	}
+
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		*po = nil
+	case strings.HasPrefix(s, "["):
+		var o ProductOptions
+		if err := json.Unmarshal([]byte(s), &o); err != nil {
+			return errs.WrapMsg(err, "product options can't scanned")
+		}
+		*po = o
+	default:
+		*po = ProductOptions{{Value: s}}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package app
+
+import "testing"
+
+func testOptionProduct() *Product {
+	return &Product{Title: "Köfte", Price: MustParseMoney("40"), OptionGroups: []OptionGroup{
+		{Model: Model{ID: 1}, Name: "Porsiyon", Required: true, MaxSelect: 1, Values: []OptionValue{
+			{Model: Model{ID: 1}, Name: "Normal", IsActive: true},
+			{Model: Model{ID: 2}, Name: "1.5 Porsiyon", PriceDelta: MustParseMoney("10"), IsActive: true},
+		}},
+		{Model: Model{ID: 2}, Name: "Ekstra", MinSelect: 2, MaxSelect: 3, Values: []OptionValue{
+			{Model: Model{ID: 3}, Name: "Acı Sos", PriceDelta: MustParseMoney("2"), IsActive: true},
+			{Model: Model{ID: 4}, Name: "Yoğurt", PriceDelta: MustParseMoney("2"), IsActive: true},
+			{Model: Model{ID: 5}, Name: "Ketçap", PriceDelta: MustParseMoney("1.50"), IsActive: true},
+			{Model: Model{ID: 6}, Name: "Mayonez", PriceDelta: MustParseMoney("1.50")},
+		}},
+	}}
+}
+
+func TestProduct_SelectOptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		ids   []int
+		valid bool
+	}{
+		{"required", []int{1}, true},
+		{"required missing", nil, false},
+		{"max one", []int{1, 2}, false},
+		{"optional group min", []int{2, 3}, false},
+		{"optional group", []int{2, 4, 3}, true},
+		{"optional group max", []int{1, 3, 4, 5, 6}, false},
+		{"inactive", []int{1, 3, 6}, false},
+		{"duplicate", []int{1, 1}, false},
+		{"unknown", []int{1, 7}, false},
+	}
+	for _, tt := range tests {
+		_, err := testOptionProduct().SelectOptions(tt.ids)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid %v got err: %v", tt.name, tt.valid, err)
+		}
+	}
+}
+
+func TestProduct_SelectOptionsNegativePrice(t *testing.T) {
+	p := &Product{Title: "Pide", Price: MustParseMoney("5"), OptionGroups: []OptionGroup{
+		{Model: Model{ID: 1}, Name: "Boy", Values: []OptionValue{
+			{Model: Model{ID: 1}, Name: "Küçük", PriceDelta: MustParseMoney("-5"), IsActive: true},
+			{Model: Model{ID: 2}, Name: "Mini", PriceDelta: MustParseMoney("-6"), IsActive: true},
+		}},
+	}}
+
+	if _, err := p.SelectOptions([]int{1}); err != nil {
+		t.Errorf("expected zero price to be valid got %v", err)
+	}
+	if _, err := p.SelectOptions([]int{2}); err == nil {
+		t.Error("expected negative price to be rejected")
+	}
+}
+
+func TestProductOptions_snapshot(t *testing.T) {
+	po, err := testOptionProduct().SelectOptions([]int{4, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if po.PriceDelta() != MustParseMoney("14") {
+		t.Errorf("expected price delta 14.00 got %s", po.PriceDelta())
+	}
+	if po.Key() != "2,3,4" {
+		t.Errorf("expected key 2,3,4 got %s", po.Key())
+	}
+	if s := po.String(); s != "Porsiyon: 1.5 Porsiyon, Ekstra: Acı Sos, Ekstra: Yoğurt" {
+		t.Errorf("unexpected string %s", s)
+	}
+
+	v, err := po.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scanned ProductOptions
+	if err := scanned.Scan([]byte(v.(string))); err != nil {
+		t.Fatal(err)
+	}
+	if !scanned.Equal(po) {
+		t.Errorf("expected %v got %v", po, scanned)
+	}
+}
+
+func TestProductOptions_ScanLegacy(t *testing.T) {
+	var po ProductOptions
+	if err := po.Scan([]byte("acısız")); err != nil {
+		t.Fatal(err)
+	}
+	if len(po) != 1 || po.String() != "acısız" || po.Key() != "" {
+		t.Errorf("expected free-form text as a single option got %v", po)
+	}
+
+	if err := po.Scan(""); err != nil || po != nil {
+		t.Errorf("expected no options got %v, err: %v", po, err)
+	}
+}
@@ -1,48 +1,96 @@
 package app
 
-import "time"
+import (
+	"app/interfaces/errs"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrOrderStatusChanged is returned when the order's status is changed by another request meanwhile
+var ErrOrderStatusChanged = errs.Conflict("order status is changed meanwhile, try again")
 
 type Order struct {
 	Model
-	UserID          int        `json:"-"`
-	StatusID        int        `json:"-"`
-	PaymentMethodID int        `json:"-"`
-	PatmentDetails  string     `json:"-"`
-	Total           float32    `json:"total"`
-	CustomerNote    string     `json:"customerNote"`
-	DeliveryTime    *time.Time `json:"deliveryTime"`
+	UserID          int    `json:"-"`
+	StatusID        int    `json:"-"`
+	PaymentMethodID int    `json:"-"`
+	PatmentDetails  string `json:"-"`
+	// Subtotal is the total without tax
+	Subtotal Money `json:"subtotal"`
+	TaxTotal Money `json:"taxTotal"`
+	// DeliveryFee is added to the total after the tax
+	DeliveryFee Money `json:"deliveryFee"`
+	// Discount is the coupon's discount of the products and the delivery fee,
+	// it's already deducted from the totals
+	Discount   Money  `json:"discount"`
+	CouponID   int    `json:"-"`
+	CouponCode string `json:"couponCode"`
+	Total      Money  `json:"total"`
+	// PricesIncludeTax reports whether products' prices include the tax
+	PricesIncludeTax bool       `json:"pricesIncludeTax"`
+	CustomerNote     string     `json:"customerNote"`
+	DeliveryTime     *time.Time `json:"deliveryTime"`
 
 	Status        *OrderStatus   `json:"status"`
 	Address       *OrderAddress  `json:"address"`
 	PaymentMethod *PaymentMethod `json:"paymentMethod"`
 	Products      []OrderProduct `json:"items"`
+	History       []OrderHistory `json:"history,omitempty"`
+	Taxes         []OrderTax     `json:"taxes"`
+	// Reservations hold the stock of the order's products
+	Reservations []StockReservation `json:"-"`
 }
 
+// SetTotal sets products' totals by their tax rates,
+// and order's totals and tax breakdown from them
 func (o *Order) SetTotal() {
-	for _, op := range o.Products {
-		o.Total += op.Total
+	o.Subtotal, o.TaxTotal, o.Total = Money{}, Money{}, Money{}
+	for i := range o.Products {
+		op := &o.Products[i]
+		op.SetTotal(o.PricesIncludeTax)
+
+		o.Subtotal = o.Subtotal.Add(op.Net)
+		o.TaxTotal = o.TaxTotal.Add(op.Tax)
+		o.Total = o.Total.Add(op.Total)
 	}
+	o.Total = o.Total.Add(o.DeliveryFee)
+	o.setTaxes()
 }
 
 type OrderProduct struct {
 	Model
-	OrderID   int     `json:"-"`
-	ProductID int     `json:"-"`
-	Qty       int     `json:"qty"`
-	Price     float32 `json:"price"`
-	Total     float32 `json:"total"`
-	TaxRate   float32 `json:"taxRate"`
-	Options   string  `json:"options"`
+	OrderID   int   `json:"-"`
+	ProductID int   `json:"-"`
+	Qty       int   `json:"qty"`
+	Price     Money `json:"price"`
+	// Discount is deducted from the line before the tax is calculated
+	Discount Money `json:"discount"`
+	// Net is the line total without tax
+	Net   Money `json:"net"`
+	Tax   Money `json:"tax"`
+	Total Money `json:"total"`
+	// TaxRate is the tax percentage, 18 is 18%
+	TaxRate int `json:"taxRate"`
+	// Options is the snapshot of the chosen options, Price includes their price deltas
+	Options ProductOptions `json:"options" gorm:"type:text"`
 
 	Product *Product `json:"product"`
 }
 
-func (op *OrderProduct) GetTotal() float32 {
-	return float32(op.Qty) * op.Price
-}
-
-func (op *OrderProduct) SetTotal() {
-	op.Total = float32(op.Qty) * op.Price
+// SetTotal sets line's net, tax and total amounts by its tax rate.
+// The tax is rounded per line, half away from zero.
+func (op *OrderProduct) SetTotal(pricesIncludeTax bool) {
+	line := op.Price.Mul(op.Qty).Sub(op.Discount)
+	if pricesIncludeTax {
+		op.Total = line
+		op.Tax = line.MulRatio(int64(op.TaxRate), int64(100+op.TaxRate))
+		op.Net = line.Sub(op.Tax)
+		return
+	}
+	op.Net = line
+	op.Tax = line.MulRatio(int64(op.TaxRate), 100)
+	op.Total = line.Add(op.Tax)
 }
 
 type OrderAddress struct {
@@ -53,10 +101,12 @@ type OrderAddress struct {
 
 type OrderHistory struct {
 	Model
-	OrderID  int
-	UserID   int
-	StatusID int16
-	Note     string
+	OrderID  int    `json:"-"`
+	UserID   int    `json:"-"`
+	StatusID int16  `json:"-"`
+	Note     string `json:"note"`
+
+	Status *OrderStatus `json:"status,omitempty"`
 }
 
 type OrderStatus struct {
@@ -67,6 +117,65 @@ type OrderStatus struct {
 	Status      bool   `json:"-"`
 }
 
+// OrderWorkflow is order status state machine
+type OrderWorkflow struct {
+	Initial     int
+	Transitions map[int][]int
+}
+
+// CanTransition checks order's status can change from a status to another one
+func (wf *OrderWorkflow) CanTransition(from, to int) bool {
+	for _, s := range wf.Transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseOrderWorkflow parses workflow definition like "1:2,3;2:3"
+// that means status 1 can go to 2 or 3, and 2 can go to 3.
+// The first status is the initial one.
+func ParseOrderWorkflow(s string) (*OrderWorkflow, error) {
+	wf := OrderWorkflow{Transitions: make(map[int][]int)}
+
+	for i, rule := range strings.Split(s, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		parts := strings.SplitN(rule, ":", 2)
+		from, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, errs.WrapMsg(err, "invalid order workflow rule: %s", rule)
+		}
+		if i == 0 {
+			wf.Initial = from
+		}
+
+		wf.Transitions[from] = nil
+		if len(parts) == 1 {
+			continue
+		}
+		for _, v := range strings.Split(parts[1], ",") {
+			if strings.TrimSpace(v) == "" {
+				continue
+			}
+			to, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return nil, errs.WrapMsg(err, "invalid order workflow rule: %s", rule)
+			}
+			wf.Transitions[from] = append(wf.Transitions[from], to)
+		}
+	}
+
+	if wf.Initial == 0 {
+		return nil, errs.NewWithStack("order workflow has no initial status: %s", s)
+	}
+	return &wf, nil
+}
+
 type PaymentMethod struct {
 	Model
 	Name        string `json:"name"`
@@ -97,14 +206,24 @@ type Address struct {
 
 type Product struct {
 	Model
-	Title       string  `json:"title" fako:"product_name"`
-	Description string  `json:"description" gorm:"size:1024" fako:"paragraph"`
-	Price       float32 `json:"price"`
-	IsActive    bool    `json:"isActive"`
-
-	Categories []Category `gorm:"many2many:pivot_product_category" json:"categories,omitempty"`
-	Image      *Image     `json:"defaultImage,omitempty"`
-	ImageID    int        `json:"-"`
+	Title       string `json:"title" fako:"product_name"`
+	Description string `json:"description" gorm:"size:1024" fako:"paragraph"`
+	Price       Money  `json:"price"`
+	IsActive    bool   `json:"isActive"`
+	// TaxClassID is the product's tax class, zero if it uses its category's class
+	TaxClassID int `json:"taxClassId"`
+	// TrackStock reports whether orders are limited by the stock
+	TrackStock bool `json:"trackStock"`
+	// Stock is the quantity on hand, Reserved is the part of it held by unconfirmed orders.
+	// They're shown only to admins by stock responses.
+	Stock             int `json:"-"`
+	Reserved          int `json:"-"`
+	LowStockThreshold int `json:"-"`
+
+	Categories   []Category    `gorm:"many2many:pivot_product_category" json:"categories,omitempty"`
+	Image        *Image        `json:"defaultImage,omitempty"`
+	ImageID      int           `json:"-"`
+	OptionGroups []OptionGroup `json:"optionGroups,omitempty"`
 }
 
 func (p *Product) AddCategory(c Category) {
@@ -121,6 +240,8 @@ type Category struct {
 	Title       string `json:"title" fako:"title"`
 	Description string `json:"description" gorm:"size:1024" fako:"paragraph"`
 	IsActive    bool   `json:"isActive"`
+	// TaxClassID is the tax class of the category's products which haven't one
+	TaxClassID int `json:"taxClassId"`
 
 	Image    *Image    `json:"image,omitempty"`
 	ImageID  int       `json:"-"`
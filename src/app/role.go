@@ -0,0 +1,30 @@
+package app
+
+// Permission is an ability that is required to use admin routes
+type Permission string
+
+// Permissions
+const (
+	PermManageCatalog    Permission = "catalog.manage"
+	PermManageOrders     Permission = "orders.manage"
+	PermManageUsers      Permission = "users.manage"
+	PermManagePromotions Permission = "promotions.manage"
+)
+
+// Roles maps role names to their permissions.
+// Admin users have all permissions regardless of their role.
+var Roles = map[string][]Permission{
+	"editor":  {PermManageCatalog, PermManagePromotions},
+	"support": {PermManageOrders},
+	"manager": {PermManageCatalog, PermManageOrders, PermManageUsers, PermManagePromotions},
+}
+
+// HasPermission checks the role has the permission
+func HasPermission(role string, p Permission) bool {
+	for _, v := range Roles[role] {
+		if v == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Search ranking weights by the field where the term is found
+const (
+	SearchTitleWeight       = 3
+	SearchCategoryWeight    = 2
+	SearchDescriptionWeight = 1
+)
+
+// SearchQuery searches active products by text, filtered by price and categories
+type SearchQuery struct {
+	Text       string
+	MinPrice   *Money
+	MaxPrice   *Money
+	Categories []int
+	Limit      int
+	Offset     int
+}
+
+// Terms splits query text into lower case search terms
+func (q *SearchQuery) Terms() []string {
+	return SearchTerms(q.Text)
+}
+
+// SearchTerms splits text into lower case words, ignores one letter words
+func SearchTerms(s string) []string {
+	var ts []string
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, w := range words {
+		if len([]rune(w)) > 1 {
+			ts = append(ts, w)
+		}
+	}
+	return ts
+}
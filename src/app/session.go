@@ -0,0 +1,33 @@
+package app
+
+import "time"
+
+// Session is a refresh token issued to a user, only the token's hash is stored.
+// Sessions created by rotating a refresh token share the same family,
+// so reusing an already rotated token revokes the whole family.
+type Session struct {
+	Model
+	UserID    int        `json:"-" gorm:"index"`
+	Family    string     `json:"-" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"unique_index"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	RevokedAt *time.Time `json:"-"`
+}
+
+// IsRevoked checks the session is revoked or rotated
+func (s *Session) IsRevoked() bool {
+	return s.RevokedAt != nil
+}
+
+// IsExpired checks the session is expired at given time
+func (s *Session) IsExpired(t time.Time) bool {
+	return !t.Before(s.ExpiresAt)
+}
+
+// RevokedToken is an access token which is revoked before its expiry.
+// It can be removed after ExpiresAt.
+type RevokedToken struct {
+	Model
+	JTI       string    `gorm:"unique_index"`
+	ExpiresAt time.Time `gorm:"index"`
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"app/interfaces/errs"
+	"time"
+)
+
+// ErrOutOfStock is returned when the stock isn't enough while reserving or committing it
+var ErrOutOfStock = errs.Conflict("some products are out of stock")
+
+// Stock reservation statuses
+const (
+	// ReservationReserved holds the stock until the order is confirmed or the reservation expires
+	ReservationReserved = "reserved"
+	// ReservationCommitted has decremented the stock
+	ReservationCommitted = "committed"
+	// ReservationExpired doesn't hold the stock anymore, it's committed again if the stock is still available
+	ReservationExpired = "expired"
+	// ReservationReleased is given back to the stock
+	ReservationReleased = "released"
+)
+
+// StockAction is how an order's stock changes when its status changes
+type StockAction int
+
+// Stock actions
+const (
+	// StockUnchanged keeps the order's stock as it is
+	StockUnchanged StockAction = iota
+	// StockCommit decrements the stock by the order's reservations
+	StockCommit
+	// StockRelease gives the order's reserved or committed stock back
+	StockRelease
+)
+
+// StockKey identifies stock of a product, or of its option value if OptionValueID isn't zero
+type StockKey struct {
+	ProductID     int `gorm:"index"`
+	OptionValueID int `gorm:"index"`
+}
+
+// StockReservation holds stock of a product or an option value for an order
+type StockReservation struct {
+	Model
+	StockKey
+	OrderID   int `gorm:"index"`
+	Qty       int
+	Status    string    `gorm:"size:16;index"`
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+// Available gets the quantity which can be ordered, it's meaningful only if the product tracks stock
+func (p *Product) Available() int {
+	return p.Stock - p.Reserved
+}
+
+// IsLowStock checks the available quantity of the product is at or below its threshold
+func (p *Product) IsLowStock() bool {
+	return p.TrackStock && p.Available() <= p.LowStockThreshold
+}
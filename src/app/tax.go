@@ -0,0 +1,41 @@
+package app
+
+import "sort"
+
+// TaxClass is a tax rate which is assigned to products or categories, like KDV 18%.
+// Products without a tax class use their first category's class.
+type TaxClass struct {
+	Model
+	Name string `json:"name"`
+	// Rate is the tax percentage, 18 is 18%
+	Rate int `json:"rate"`
+}
+
+// OrderTax is the order's tax total of a rate
+type OrderTax struct {
+	Model
+	OrderID int   `json:"-" gorm:"index"`
+	Rate    int   `json:"rate"`
+	Base    Money `json:"base"`
+	Amount  Money `json:"amount"`
+}
+
+// setTaxes sets the order's tax breakdown by rates of its products
+func (o *Order) setTaxes() {
+	byRate := make(map[int]*OrderTax)
+	for _, op := range o.Products {
+		t, ok := byRate[op.TaxRate]
+		if !ok {
+			t = &OrderTax{Rate: op.TaxRate}
+			byRate[op.TaxRate] = t
+		}
+		t.Base = t.Base.Add(op.Net)
+		t.Amount = t.Amount.Add(op.Tax)
+	}
+
+	o.Taxes = make([]OrderTax, 0, len(byRate))
+	for _, t := range byRate {
+		o.Taxes = append(o.Taxes, *t)
+	}
+	sort.Slice(o.Taxes, func(i, j int) bool { return o.Taxes[i].Rate < o.Taxes[j].Rate })
+}
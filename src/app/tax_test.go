@@ -0,0 +1,57 @@
+package app
+
+import "testing"
+
+func testTaxOrder(pricesIncludeTax bool) *Order {
+	o := &Order{PricesIncludeTax: pricesIncludeTax, Products: []OrderProduct{
+		{Qty: 2, Price: MustParseMoney("11.80"), TaxRate: 18},
+		{Qty: 1, Price: MustParseMoney("10.80"), TaxRate: 8},
+		{Qty: 3, Price: MustParseMoney("1.01"), TaxRate: 18},
+	}}
+	o.SetTotal()
+	return o
+}
+
+func TestOrder_SetTotalTaxIncluded(t *testing.T) {
+	o := testTaxOrder(true)
+
+	ops := o.Products
+	if ops[0].Net != MustParseMoney("20.00") || ops[0].Tax != MustParseMoney("3.60") || ops[0].Total != MustParseMoney("23.60") {
+		t.Errorf("unexpected line %+v", ops[0])
+	}
+	// 3.03 * 18/118 = 0.4622 is rounded to 0.46
+	if ops[2].Tax != MustParseMoney("0.46") || ops[2].Net != MustParseMoney("2.57") {
+		t.Errorf("unexpected line %+v", ops[2])
+	}
+
+	if o.Total != MustParseMoney("37.43") || o.TaxTotal != MustParseMoney("4.86") || o.Subtotal != MustParseMoney("32.57") {
+		t.Errorf("unexpected totals %s %s %s", o.Subtotal, o.TaxTotal, o.Total)
+	}
+
+	if len(o.Taxes) != 2 {
+		t.Fatalf("expected 2 tax rates got %d", len(o.Taxes))
+	}
+	if t8 := o.Taxes[0]; t8.Rate != 8 || t8.Base != MustParseMoney("10.00") || t8.Amount != MustParseMoney("0.80") {
+		t.Errorf("unexpected tax %+v", t8)
+	}
+	if t18 := o.Taxes[1]; t18.Rate != 18 || t18.Base != MustParseMoney("22.57") || t18.Amount != MustParseMoney("4.06") {
+		t.Errorf("unexpected tax %+v", t18)
+	}
+}
+
+func TestOrder_SetTotalTaxExcluded(t *testing.T) {
+	o := testTaxOrder(false)
+
+	ops := o.Products
+	if ops[0].Net != MustParseMoney("23.60") || ops[0].Tax != MustParseMoney("4.25") || ops[0].Total != MustParseMoney("27.85") {
+		t.Errorf("unexpected line %+v", ops[0])
+	}
+	if o.Subtotal != MustParseMoney("37.43") || o.TaxTotal != MustParseMoney("5.66") || o.Total != MustParseMoney("43.09") {
+		t.Errorf("unexpected totals %s %s %s", o.Subtotal, o.TaxTotal, o.Total)
+	}
+
+	o.SetTotal()
+	if o.Total != MustParseMoney("43.09") || len(o.Taxes) != 2 {
+		t.Errorf("expected idempotent totals got %s, %d taxes", o.Total, len(o.Taxes))
+	}
+}
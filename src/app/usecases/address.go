@@ -0,0 +1,145 @@
+package usecases
+
+import (
+	"app"
+	"app/interfaces/errs"
+)
+
+type addressRepo interface {
+	app.Databaser
+	FindAddressesByUser(userID int) ([]app.Address, error)
+	OneAddressByUser(userID int, id interface{}) (*app.Address, error)
+	SetDefaultAddress(*app.Address) error
+	DeleteAddress(*app.Address) error
+}
+
+func NewAddress(r addressRepo) *Address {
+	return &Address{r}
+}
+
+// Address manages user's address book.
+// A user who has any address always has exactly one default address.
+type Address struct {
+	addressRepo
+}
+
+func (as *Address) Addresses(u *app.User) ([]app.Address, error) {
+	return as.FindAddressesByUser(u.ID)
+}
+
+func (as *Address) CreateAddress(u *app.User, f *AddressForm) (*app.Address, error) {
+	if err := checkAddressBody(&f.AddressBody); err != nil {
+		return nil, err
+	}
+
+	hasAny, err := as.ExistsBy(&app.Address{}, app.DBWhere{"user_id": u.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	var a app.Address
+	a.UserID = u.ID
+	a.AddressBody = f.AddressBody
+
+	if err := as.Store(&a); err != nil {
+		return nil, err
+	}
+
+	if !hasAny || f.Default {
+		if err := as.SetDefaultAddress(&a); err != nil {
+			return nil, err
+		}
+		a.Default = true
+	}
+
+	return &a, nil
+}
+
+func (as *Address) UpdateAddress(u *app.User, f *AddressForm) (*app.Address, error) {
+	if err := checkAddressBody(&f.AddressBody); err != nil {
+		return nil, err
+	}
+
+	a, err := as.address(u, f.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	a.AddressBody = f.AddressBody
+	if err := as.Save(a); err != nil {
+		return nil, err
+	}
+
+	if f.Default && !a.Default {
+		if err := as.SetDefaultAddress(a); err != nil {
+			return nil, err
+		}
+		a.Default = true
+	}
+
+	return a, nil
+}
+
+func (as *Address) SetDefault(u *app.User, id int) (*app.Address, error) {
+	a, err := as.address(u, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := as.SetDefaultAddress(a); err != nil {
+		return nil, err
+	}
+	a.Default = true
+
+	return a, nil
+}
+
+func (as *Address) DeleteAddress(u *app.User, id int) error {
+	a, err := as.address(u, id)
+	if err != nil {
+		return err
+	}
+
+	return as.addressRepo.DeleteAddress(a)
+}
+
+func (as *Address) address(u *app.User, id int) (*app.Address, error) {
+	a, err := as.OneAddressByUser(u.ID, id)
+	if err != nil {
+		if as.IsNotFoundErr(err) {
+			return nil, errAddressNotFound
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+func checkAddressBody(b *app.AddressBody) error {
+	if err := errs.CheckName(b.Name); err != nil {
+		return err
+	}
+	if err := errs.CheckStringLen(b.Tel, 7, 20, "tel"); err != nil {
+		return err
+	}
+	if err := errs.CheckRequired(b.City, "city"); err != nil {
+		return err
+	}
+	if err := errs.CheckRequired(b.District, "district"); err != nil {
+		return err
+	}
+	if err := errs.CheckStringLen(b.Address, 5, 255, "address"); err != nil {
+		return err
+	}
+	if b.Email != "" {
+		if err := errs.CheckEmail(b.Email); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type AddressForm struct {
+	ID      int  `json:"-"`
+	Default bool `json:"default"`
+	app.AddressBody
+}
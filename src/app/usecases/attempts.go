@@ -0,0 +1,162 @@
+package usecases
+
+import (
+	"app"
+	"app/interfaces/errs"
+	"strings"
+	"time"
+)
+
+// Guarded actions
+const (
+	ActionLogin          = "login"
+	ActionForgotPassword = "forgot_password"
+)
+
+// AttemptPolicy limits failed attempts of a key. After FreeAttempts failures
+// every attempt waits BaseDelay doubled by each failure up to MaxDelay,
+// after MaxFailures failures the key is locked out for Lockout.
+// Failures are forgotten Lockout after the last failure.
+type AttemptPolicy struct {
+	FreeAttempts int
+	MaxFailures  int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	Lockout      time.Duration
+}
+
+// wait gets the duration until the next attempt is allowed and whether the key is locked out
+func (p AttemptPolicy) wait(a app.Attempt, now time.Time) (time.Duration, bool) {
+	if a.Failures <= p.FreeAttempts {
+		return 0, false
+	}
+
+	if a.Failures >= p.MaxFailures {
+		return a.LastFailure.Add(p.Lockout).Sub(now), true
+	}
+
+	d := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < a.Failures && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return a.LastFailure.Add(d).Sub(now), false
+}
+
+// AttemptPolicies are the policies of an action, failures are tracked
+// separately for the email address and the ip address.
+type AttemptPolicies struct {
+	Email AttemptPolicy
+	IP    AttemptPolicy
+}
+
+// DefaultAttemptPolicies are the default policies of guarded actions
+var DefaultAttemptPolicies = map[string]AttemptPolicies{
+	ActionLogin: {
+		Email: AttemptPolicy{FreeAttempts: 3, MaxFailures: 10, BaseDelay: time.Second, MaxDelay: time.Minute, Lockout: 15 * time.Minute},
+		IP:    AttemptPolicy{FreeAttempts: 10, MaxFailures: 50, BaseDelay: time.Second, MaxDelay: time.Minute, Lockout: 15 * time.Minute},
+	},
+	ActionForgotPassword: {
+		Email: AttemptPolicy{FreeAttempts: 2, MaxFailures: 5, BaseDelay: time.Minute, MaxDelay: 10 * time.Minute, Lockout: time.Hour},
+		IP:    AttemptPolicy{FreeAttempts: 5, MaxFailures: 20, BaseDelay: time.Second, MaxDelay: time.Minute, Lockout: time.Hour},
+	},
+}
+
+func NewAttemptGuard(s app.AttemptStore) *AttemptGuard {
+	ps := make(map[string]AttemptPolicies)
+	for k, v := range DefaultAttemptPolicies {
+		ps[k] = v
+	}
+	return &AttemptGuard{s: s, Policies: ps, now: time.Now}
+}
+
+// AttemptGuard tracks failed attempts of actions like login by email and ip address,
+// and rejects attempts with exponential backoff and temporary lockout.
+type AttemptGuard struct {
+	s        app.AttemptStore
+	Policies map[string]AttemptPolicies
+	now      func() time.Time
+}
+
+// Check checks whether an attempt of the action is allowed.
+// It returns 423 if the email is locked out and 429 if it must wait.
+func (g *AttemptGuard) Check(action, ip, email string) error {
+	ps, ok := g.Policies[action]
+	if !ok {
+		return nil
+	}
+	now := g.now()
+
+	a, err := g.s.Attempt(attemptKey(action, "email", email))
+	if err != nil {
+		return err
+	}
+	if d, locked := ps.Email.wait(a, now); d > 0 {
+		if locked {
+			return lockedErr(d)
+		}
+		return waitErr(d)
+	}
+
+	if a, err = g.s.Attempt(attemptKey(action, "ip", ip)); err != nil {
+		return err
+	}
+	if d, _ := ps.IP.wait(a, now); d > 0 {
+		return waitErr(d)
+	}
+	return nil
+}
+
+// Fail records a failed attempt of the action. Concurrent attempts can all pass Check,
+// so the attempt is rejected if the failures counted before it reached the lockout.
+func (g *AttemptGuard) Fail(action, ip, email string) error {
+	ps, ok := g.Policies[action]
+	if !ok {
+		return nil
+	}
+	now := g.now()
+
+	a, err := g.s.Fail(attemptKey(action, "email", email), now, ps.Email.Lockout)
+	if err != nil {
+		return err
+	}
+	ia, err := g.s.Fail(attemptKey(action, "ip", ip), now, ps.IP.Lockout)
+	if err != nil {
+		return err
+	}
+
+	if a.Failures > ps.Email.MaxFailures {
+		d, _ := ps.Email.wait(a, now)
+		return lockedErr(d)
+	}
+	if ia.Failures > ps.IP.MaxFailures {
+		d, _ := ps.IP.wait(ia, now)
+		return waitErr(d)
+	}
+	return nil
+}
+
+// Reset forgets failed attempts of the email after a successful attempt.
+// Attempts of the ip address aren't reset, otherwise an attacker could
+// reset them by signing in to its own account.
+func (g *AttemptGuard) Reset(action, email string) error {
+	return g.s.Reset(attemptKey(action, "email", email))
+}
+
+func attemptKey(action, kind, v string) string {
+	return action + ":" + kind + ":" + strings.ToLower(strings.TrimSpace(v))
+}
+
+func lockedErr(d time.Duration) error {
+	return errs.Locked("too many failed attempts, account is locked for %d seconds", seconds(d)).SetRetryAfter(d)
+}
+
+func waitErr(d time.Duration) error {
+	return errs.TooManyRequests("too many failed attempts, try again in %d seconds", seconds(d)).SetRetryAfter(d)
+}
+
+func seconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
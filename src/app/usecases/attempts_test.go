@@ -0,0 +1,92 @@
+package usecases
+
+import (
+	"app"
+	"app/infra"
+	"app/interfaces/errs"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAttemptPolicy_wait(t *testing.T) {
+	p := AttemptPolicy{FreeAttempts: 2, MaxFailures: 6, BaseDelay: time.Second, MaxDelay: 4 * time.Second, Lockout: time.Minute}
+	now := time.Now()
+
+	tests := []struct {
+		failures int
+		wait     time.Duration
+		locked   bool
+	}{
+		{0, 0, false},
+		{2, 0, false},
+		{3, time.Second, false},
+		{4, 2 * time.Second, false},
+		{5, 4 * time.Second, false},
+		{6, time.Minute, true},
+		{9, time.Minute, true},
+	}
+	for _, tt := range tests {
+		a := app.Attempt{Failures: tt.failures, LastFailure: now}
+		wait, locked := p.wait(a, now)
+		if wait != tt.wait || locked != tt.locked {
+			t.Errorf("%d failures: expected wait %s locked %v got %s %v", tt.failures, tt.wait, tt.locked, wait, locked)
+		}
+	}
+}
+
+func TestAttemptGuard(t *testing.T) {
+	g := NewAttemptGuard(infra.NewMemoryAttemptStore())
+	g.Policies[ActionLogin] = AttemptPolicies{
+		Email: AttemptPolicy{FreeAttempts: 1, MaxFailures: 3, BaseDelay: time.Second, MaxDelay: time.Second, Lockout: time.Minute},
+		IP:    AttemptPolicy{FreeAttempts: 10, MaxFailures: 20, BaseDelay: time.Second, MaxDelay: time.Second, Lockout: time.Minute},
+	}
+	now := time.Now()
+	g.now = func() time.Time { return now }
+
+	check := func(code int) {
+		t.Helper()
+		err := g.Check(ActionLogin, "10.0.0.1", "User@Gmail.com ")
+		if code == 0 {
+			if err != nil {
+				t.Fatalf("expected no error got %v", err)
+			}
+			return
+		}
+		if e, ok := err.(*errs.Error); !ok || e.HTTPCode != code || e.RetryAfter <= 0 {
+			t.Fatalf("expected %d error with retry after got %v", code, err)
+		}
+	}
+
+	check(0)
+	g.Fail(ActionLogin, "10.0.0.1", "user@gmail.com")
+	check(0)
+	g.Fail(ActionLogin, "10.0.0.1", "user@gmail.com")
+	check(http.StatusTooManyRequests)
+
+	now = now.Add(time.Second)
+	check(0)
+	g.Fail(ActionLogin, "10.0.0.1", "user@gmail.com")
+	check(http.StatusLocked)
+
+	// an attempt which passed the check concurrently is rejected by its count
+	err := g.Fail(ActionLogin, "10.0.0.1", "user@gmail.com")
+	if e, ok := err.(*errs.Error); !ok || e.HTTPCode != http.StatusLocked {
+		t.Fatalf("expected %d error got %v", http.StatusLocked, err)
+	}
+
+	now = now.Add(time.Minute)
+	check(0)
+
+	g.Fail(ActionLogin, "10.0.0.1", "user@gmail.com")
+	g.Fail(ActionLogin, "10.0.0.1", "user@gmail.com")
+	if err := g.Reset(ActionLogin, "user@gmail.com"); err != nil {
+		t.Fatal(err)
+	}
+	check(0)
+
+	// other actions aren't affected
+	if err := g.Check(ActionForgotPassword, "10.0.0.1", "user@gmail.com"); err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package usecases
+
+import (
+	"app"
+	"app/interfaces/errs"
+)
+
+var (
+	errCartItemNotFound = errs.NotFound("cart item not found")
+	errProductNotFound  = errs.NotFound("product not found")
+	errInvalidQty       = errs.BadRequest("qty must be between 1 and %d", maxItemQty)
+)
+
+// maxItemQty is the maximum quantity of a cart item
+const maxItemQty = 100
+
+type cartRepo interface {
+	app.Databaser
+	// OneProduct gets the product with its option groups and values
+	OneProduct(id int) (*app.Product, error)
+	// OneCartByUser gets the cart with its items' products and their options
+	OneCartByUser(userID int) (*app.Cart, error)
+	DeleteCartItem(*app.CartItem) error
+	ClearCart(*app.Cart) error
+}
+
+func NewCart(r cartRepo) *Cart {
+	return &Cart{r}
+}
+
+type Cart struct {
+	cartRepo
+}
+
+// Cart gets user's cart. Items' prices and options are re-validated against products,
+// items which have inactive or deleted products or invalid options are removed from the cart.
+func (cs *Cart) Cart(u *app.User) (*app.Cart, error) {
+	c, err := cs.OneCartByUser(u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := c.Items[:0]
+	for _, ci := range c.Items {
+		var po app.ProductOptions
+		if ci.Product != nil && ci.Product.IsActive {
+			po, err = ci.Product.SelectOptions(ci.Options.ValueIDs())
+		}
+		if ci.Product == nil || !ci.Product.IsActive || err != nil {
+			if err := cs.DeleteCartItem(&ci); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		price := ci.Product.Price.Add(po.PriceDelta())
+		if ci.Price != price || !ci.Options.Equal(po) {
+			if err := cs.UpdateFields(&ci, map[string]interface{}{"Price": price, "Options": po}); err != nil {
+				return nil, err
+			}
+			ci.Price, ci.Options = price, po
+		}
+		items = append(items, ci)
+	}
+	c.Items = items
+	c.SetTotal()
+
+	return c, nil
+}
+
+func (cs *Cart) AddItem(u *app.User, f *CartItemForm) (*app.Cart, error) {
+	if f.Qty == 0 {
+		f.Qty = 1
+	}
+	if f.Qty < 0 || f.Qty > maxItemQty {
+		return nil, errInvalidQty
+	}
+
+	p, err := cs.OneProduct(f.ProductID)
+	if err != nil {
+		if cs.IsNotFoundErr(err) {
+			return nil, errProductNotFound
+		}
+		return nil, err
+	}
+	if !p.IsActive {
+		return nil, errProductNotFound
+	}
+
+	po, err := p.SelectOptions(f.Options)
+	if err != nil {
+		return nil, err
+	}
+	price := p.Price.Add(po.PriceDelta())
+
+	c, err := cs.OneCartByUser(u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkStock(c, p, po, f.Qty); err != nil {
+		return nil, err
+	}
+
+	if ci, ok := c.ItemByProduct(p.ID, po); ok {
+		if ci.Qty+f.Qty > maxItemQty {
+			return nil, errInvalidQty
+		}
+		kv := map[string]interface{}{"Qty": ci.Qty + f.Qty, "Price": price, "Options": po}
+		if err := cs.UpdateFields(ci, kv); err != nil {
+			return nil, err
+		}
+		return cs.Cart(u)
+	}
+
+	ci := app.CartItem{CartID: c.ID, ProductID: p.ID, Qty: f.Qty, Price: price, Options: po}
+	if err := cs.Store(&ci); err != nil {
+		return nil, err
+	}
+
+	return cs.Cart(u)
+}
+
+func (cs *Cart) UpdateItem(u *app.User, f *CartItemForm) (*app.Cart, error) {
+	if f.Qty < 1 || f.Qty > maxItemQty {
+		return nil, errInvalidQty
+	}
+
+	c, err := cs.OneCartByUser(u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ci, ok := c.Item(f.ID)
+	if !ok {
+		return nil, errCartItemNotFound
+	}
+	if ci.Product != nil {
+		if err := checkStock(c, ci.Product, ci.Options, f.Qty-ci.Qty); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cs.UpdateField(ci, "Qty", f.Qty); err != nil {
+		return nil, err
+	}
+
+	return cs.Cart(u)
+}
+
+func (cs *Cart) RemoveItem(u *app.User, id int) (*app.Cart, error) {
+	c, err := cs.OneCartByUser(u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ci, ok := c.Item(id)
+	if !ok {
+		return nil, errCartItemNotFound
+	}
+
+	if err := cs.DeleteCartItem(ci); err != nil {
+		return nil, err
+	}
+
+	return cs.Cart(u)
+}
+
+func (cs *Cart) Clear(u *app.User) error {
+	c, err := cs.OneCartByUser(u.ID)
+	if err != nil {
+		return err
+	}
+	return cs.ClearCart(c)
+}
+
+// CartItemForm adds or updates a cart item, Options are ids of the chosen option values
+type CartItemForm struct {
+	ID        int   `json:"-"`
+	ProductID int   `json:"productId"`
+	Qty       int   `json:"qty"`
+	Options   []int `json:"options"`
+}
+
+// checkStock checks the product and its chosen option values which track stock
+// are available if qty more is added to the cart
+func checkStock(c *app.Cart, p *app.Product, po app.ProductOptions, qty int) error {
+	if p.TrackStock && c.ProductQty(p.ID)+qty > p.Available() {
+		return outOfStock(p)
+	}
+	for _, id := range po.ValueIDs() {
+		v, ok := p.OptionValue(id)
+		if ok && v.TrackStock && c.OptionQty(id)+qty > v.Available() {
+			return optionOutOfStock(p, v)
+		}
+	}
+	return nil
+}
@@ -1,24 +1,39 @@
 package usecases
 
-import "app"
+import (
+	"app"
+	"app/interfaces/errs"
+)
+
+var (
+	errCategoryNotFound = errs.NotFound("category not found")
+	errInvalidPrice     = errs.BadRequest("price can't be negative")
+)
 
 type cRepo interface {
 	app.Databaser
 	OneActiveProduct(interface{}) (*app.Product, error)
 	FindActiveProducts(*app.DBFilter) ([]app.Product, error)
 	FindActiveProductsByCategory([]interface{}, *app.DBFilter) ([]app.Product, error)
+	CountActiveProducts([]interface{}) (int, error)
 	FindActiveCategories(*app.DBFilter) ([]app.Category, error)
 	DeleteProduct(id interface{}) error
 	SetProductCategories(*app.Product, []app.Category) error
 	SetProductImage(*app.Product, *app.Image) error
+	DeleteCategory(id interface{}) error
+	SetCategoryProducts(*app.Category, []app.Product) error
+	SetCategoryImage(*app.Category, *app.Image) error
 }
 
 func NewCatalog(r cRepo) *Catalog {
-	return &Catalog{r}
+	return &Catalog{cRepo: r}
 }
 
 type Catalog struct {
 	cRepo
+
+	// Indexer is updated when products or their categories change, it's optional
+	Indexer ProductIndexer
 }
 
 // todo: validate input
@@ -28,6 +43,16 @@ func (cs *Catalog) CreateProduct(f *ProductForm) (*app.Product, error) {
 	p.Description = f.Description
 	p.Price = *f.Price
 	p.IsActive = *f.IsActive
+	if p.Price.Amount < 0 {
+		return nil, errInvalidPrice
+	}
+
+	if f.TaxClassID != nil {
+		if err := cs.checkTaxClass(*f.TaxClassID); err != nil {
+			return nil, err
+		}
+		p.TaxClassID = *f.TaxClassID
+	}
 
 	if f.Image != "" {
 		var img app.Image
@@ -47,7 +72,10 @@ func (cs *Catalog) CreateProduct(f *ProductForm) (*app.Product, error) {
 		}
 	}
 
-	return &p, cs.Store(&p)
+	if err := cs.Store(&p); err != nil {
+		return nil, err
+	}
+	return &p, cs.reindex(p.ID)
 }
 
 // todo: validate input
@@ -64,11 +92,20 @@ func (cs *Catalog) UpdateProduct(f *ProductForm) (*app.Product, error) {
 		kv["Description"] = f.Description
 	}
 	if f.Price != nil {
+		if f.Price.Amount < 0 {
+			return nil, errInvalidPrice
+		}
 		kv["Price"] = *f.Price
 	}
 	if f.IsActive != nil {
 		kv["IsActive"] = *f.IsActive
 	}
+	if f.TaxClassID != nil {
+		if err := cs.checkTaxClass(*f.TaxClassID); err != nil {
+			return nil, err
+		}
+		kv["TaxClassID"] = *f.TaxClassID
+	}
 
 	if f.Image != "" {
 		var img app.Image
@@ -98,15 +135,239 @@ func (cs *Catalog) UpdateProduct(f *ProductForm) (*app.Product, error) {
 		}
 	}
 
-	return &p, cs.UpdateFields(&p, kv)
+	if err := cs.UpdateFields(&p, kv); err != nil {
+		return nil, err
+	}
+	return &p, cs.reindex(p.ID)
+}
+
+func (cs *Catalog) DeleteProduct(id int) error {
+	if err := cs.cRepo.DeleteProduct(id); err != nil {
+		return err
+	}
+	return cs.reindex(id)
+}
+
+func (cs *Catalog) CreateCategory(f *CategoryForm) (*app.Category, error) {
+	if err := errs.CheckStringLen(f.Title, 2, 255, "title"); err != nil {
+		return nil, err
+	}
+	if err := errs.CheckStringLen(f.Description, 0, 1024, "description"); err != nil {
+		return nil, err
+	}
+
+	var c app.Category
+	c.Title = f.Title
+	c.Description = f.Description
+	if f.IsActive != nil {
+		c.IsActive = *f.IsActive
+	}
+	if f.TaxClassID != nil {
+		if err := cs.checkTaxClass(*f.TaxClassID); err != nil {
+			return nil, err
+		}
+		c.TaxClassID = *f.TaxClassID
+	}
+
+	if f.Image != "" {
+		var img app.Image
+		if err := cs.FirstOrInit(&img, app.DBWhere{"public_id": f.Image}); err != nil {
+			return nil, err
+		}
+		c.Image = &img
+	}
+
+	for _, id := range f.Products {
+		var p app.Product
+		if err := cs.One(&p, id); err != nil {
+			return nil, err
+		}
+		c.Products = append(c.Products, p)
+	}
+
+	if err := cs.Store(&c); err != nil {
+		return nil, err
+	}
+	return &c, cs.reindex(f.Products...)
+}
+
+func (cs *Catalog) UpdateCategory(f *CategoryForm) (*app.Category, error) {
+	c, err := cs.category(f.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	kv := make(map[string]interface{})
+
+	if f.Title != "" {
+		if err := errs.CheckStringLen(f.Title, 2, 255, "title"); err != nil {
+			return nil, err
+		}
+		kv["Title"] = f.Title
+	}
+	if f.Description != "" {
+		if err := errs.CheckStringLen(f.Description, 0, 1024, "description"); err != nil {
+			return nil, err
+		}
+		kv["Description"] = f.Description
+	}
+	if f.IsActive != nil {
+		kv["IsActive"] = *f.IsActive
+	}
+	if f.TaxClassID != nil {
+		if err := cs.checkTaxClass(*f.TaxClassID); err != nil {
+			return nil, err
+		}
+		kv["TaxClassID"] = *f.TaxClassID
+	}
+
+	if f.Image != "" {
+		var img app.Image
+		if err := cs.FirstOrInit(&img, app.DBWhere{"public_id": f.Image}); err != nil {
+			return nil, err
+		}
+
+		if c.ImageID == 0 || img.ID != c.ImageID {
+			if err := cs.SetCategoryImage(c, &img); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// products which were in the category are reindexed too, its title may be changed or they may be removed
+	ids, err := cs.categoryProductIDs(c.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.Products != nil {
+		ids = append(ids, f.Products...)
+		var ps []app.Product
+		for _, id := range f.Products {
+			var p app.Product
+			if err := cs.One(&p, id); err != nil {
+				return nil, err
+			}
+			ps = append(ps, p)
+		}
+		if err := cs.SetCategoryProducts(c, ps); err != nil {
+			return nil, err
+		}
+		c.Products = ps
+	}
+
+	if len(kv) > 0 {
+		if err := cs.UpdateFields(c, kv); err != nil {
+			return nil, err
+		}
+	}
+	return c, cs.reindex(ids...)
+}
+
+// SetCategoryActive activates or deactivates the category
+func (cs *Catalog) SetCategoryActive(id int, active bool) (*app.Category, error) {
+	c, err := cs.category(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, cs.UpdateField(c, "IsActive", active)
+}
+
+func (cs *Catalog) DeleteCategory(id int) error {
+	ids, err := cs.categoryProductIDs(id)
+	if err != nil {
+		return err
+	}
+
+	if err := cs.cRepo.DeleteCategory(id); err != nil {
+		if cs.IsNotFoundErr(err) {
+			return errCategoryNotFound
+		}
+		return err
+	}
+	return cs.reindex(ids...)
+}
+
+// reindex updates the indexer with the products, inactive or deleted ones are removed
+func (cs *Catalog) reindex(ids ...int) error {
+	if cs.Indexer == nil {
+		return nil
+	}
+
+	for _, id := range ids {
+		p, err := cs.OneActiveProduct(id)
+		if err != nil {
+			if cs.IsNotFoundErr(err) {
+				cs.Indexer.Remove(id)
+				continue
+			}
+			return err
+		}
+		cs.Indexer.Index(*p)
+	}
+	return nil
+}
+
+// categoryProductIDs gets ids of the category's active products
+func (cs *Catalog) categoryProductIDs(id int) ([]int, error) {
+	if cs.Indexer == nil {
+		return nil, nil
+	}
+
+	ps, err := cs.FindActiveProductsByCategory([]interface{}{id}, nil)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(ps))
+	for i, p := range ps {
+		ids[i] = p.ID
+	}
+	return ids, nil
+}
+
+func (cs *Catalog) category(id int) (*app.Category, error) {
+	var c app.Category
+	if err := cs.One(&c, id); err != nil {
+		if cs.IsNotFoundErr(err) {
+			return nil, errCategoryNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// checkTaxClass checks the tax class exists, zero removes the tax class
+func (cs *Catalog) checkTaxClass(id int) error {
+	if id == 0 {
+		return nil
+	}
+	exists, err := cs.ExistsBy(&app.TaxClass{}, app.DBWhere{"id": id})
+	if err != nil {
+		return err
+	} else if !exists {
+		return errTaxClassNotFound
+	}
+	return nil
+}
+
+type CategoryForm struct {
+	ID          int    `json:"-"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	IsActive    *bool  `json:"isActive"`
+	Image       string `json:"image"`
+	Products    []int  `json:"products"`
+	TaxClassID  *int   `json:"taxClassId"`
 }
 
 type ProductForm struct {
-	ID          int      `json:"-"`
-	Title       string   `json:"title"`
-	Description string   `json:"description"`
-	Price       *float32 `json:"price"`
-	IsActive    *bool    `json:"isActive"`
-	Image       string   `json:"image"`
-	Categories  []int    `json:"categories"`
+	ID          int        `json:"-"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Price       *app.Money `json:"price"`
+	IsActive    *bool      `json:"isActive"`
+	Image       string     `json:"image"`
+	Categories  []int      `json:"categories"`
+	TaxClassID  *int       `json:"taxClassId"`
 }
@@ -0,0 +1,168 @@
+package usecases
+
+import (
+	"app"
+	"app/interfaces/errs"
+	"log"
+	"net/http"
+	"time"
+)
+
+var (
+	errCartEmpty             = errs.BadRequest("cart is empty")
+	errAddressNotFound       = errs.NotFound("address not found")
+	errPaymentMethodNotFound = errs.NotFound("payment method not found")
+	errPaymentMethodInactive = errs.BadRequest("payment method isn't available")
+	errInvalidDeliveryTime   = errs.BadRequest("delivery time must be in the future")
+	errOrderStatusNotFound   = errs.New(errs.InternalServerError, http.StatusInternalServerError, "initial order status not found")
+)
+
+type checkoutRepo interface {
+	app.Databaser
+	PlaceOrder(*app.Order, *app.OrderHistory, *app.Cart) error
+}
+
+type cartGetter interface {
+	Cart(*app.User) (*app.Cart, error)
+}
+
+type taxCalculator interface {
+	Apply(*app.Order) error
+}
+
+type couponApplier interface {
+	Apply(u *app.User, code string, o *app.Order) error
+}
+
+type stockReserver interface {
+	Reserve(o *app.Order, items []app.CartItem) error
+}
+
+type emailSender interface {
+	Send(name, locale string, to []string, d *EmailData) error
+}
+
+func NewCheckout(r checkoutRepo, cg cartGetter, tc taxCalculator, ca couponApplier, sr stockReserver, wf *app.OrderWorkflow, es emailSender) *Checkout {
+	return &Checkout{checkoutRepo: r, cg: cg, tc: tc, ca: ca, sr: sr, wf: wf, es: es}
+}
+
+// Checkout turns user's cart into an order
+type Checkout struct {
+	checkoutRepo
+	cg cartGetter
+	tc taxCalculator
+	ca couponApplier
+	sr stockReserver
+	wf *app.OrderWorkflow
+	es emailSender
+
+	// DeliveryFee is added to every order, free delivery coupons waive it
+	DeliveryFee app.Money
+}
+
+func (cs *Checkout) PlaceOrder(u *app.User, f *CheckoutForm) (*app.Order, error) {
+	c, err := cs.cg.Cart(u)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Items) == 0 {
+		return nil, errCartEmpty
+	}
+
+	if f.DeliveryTime != nil && f.DeliveryTime.Before(time.Now()) {
+		return nil, errInvalidDeliveryTime
+	}
+
+	var a app.Address
+	if err := cs.OneBy(&a, app.DBWhere{"id": f.AddressID, "user_id": u.ID}); err != nil {
+		if cs.IsNotFoundErr(err) {
+			return nil, errAddressNotFound
+		}
+		return nil, err
+	}
+
+	var pm app.PaymentMethod
+	if err := cs.One(&pm, f.PaymentMethodID); err != nil {
+		if cs.IsNotFoundErr(err) {
+			return nil, errPaymentMethodNotFound
+		}
+		return nil, err
+	}
+	if !pm.Status {
+		return nil, errPaymentMethodInactive
+	}
+
+	status, err := cs.initialStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	var o app.Order
+	o.UserID = u.ID
+	o.StatusID = status.ID
+	o.PaymentMethodID = pm.ID
+	o.CustomerNote = f.CustomerNote
+	o.DeliveryTime = f.DeliveryTime
+	o.Address = &app.OrderAddress{AddressBody: a.AddressBody}
+
+	for _, ci := range c.Items {
+		o.Products = append(o.Products, app.OrderProduct{
+			ProductID: ci.ProductID,
+			Qty:       ci.Qty,
+			Price:     ci.Price,
+			Options:   ci.Options,
+		})
+	}
+	o.DeliveryFee = cs.DeliveryFee
+
+	if err := cs.sr.Reserve(&o, c.Items); err != nil {
+		return nil, err
+	}
+
+	if f.CouponCode != "" {
+		if err := cs.ca.Apply(u, f.CouponCode, &o); err != nil {
+			return nil, err
+		}
+	}
+	if err := cs.tc.Apply(&o); err != nil {
+		return nil, err
+	}
+
+	h := app.OrderHistory{UserID: u.ID, StatusID: int16(status.ID)}
+
+	if err := cs.checkoutRepo.PlaceOrder(&o, &h, c); err != nil {
+		return nil, err
+	}
+
+	o.Status = status
+	o.PaymentMethod = &pm
+	for i := range o.Products {
+		o.Products[i].Product = c.Items[i].Product
+	}
+
+	if err := cs.es.Send(EmailOrderConfirmation, "", []string{u.Email}, &EmailData{User: u, Order: &o}); err != nil {
+		log.Printf("order confirmation mail can't sent, err:%s", err)
+	}
+
+	return &o, nil
+}
+
+// initialStatus gets the workflow's initial order status
+func (cs *Checkout) initialStatus() (*app.OrderStatus, error) {
+	var st app.OrderStatus
+	if err := cs.One(&st, cs.wf.Initial); err != nil {
+		if cs.IsNotFoundErr(err) {
+			return nil, errOrderStatusNotFound
+		}
+		return nil, err
+	}
+	return &st, nil
+}
+
+type CheckoutForm struct {
+	AddressID       int        `json:"addressId"`
+	PaymentMethodID int        `json:"paymentMethodId"`
+	CustomerNote    string     `json:"customerNote"`
+	DeliveryTime    *time.Time `json:"deliveryTime"`
+	CouponCode      string     `json:"couponCode"`
+}
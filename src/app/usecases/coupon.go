@@ -0,0 +1,353 @@
+package usecases
+
+import (
+	"app"
+	"app/interfaces/errs"
+	"strings"
+	"time"
+)
+
+var (
+	errCouponNotFound      = errs.NotFound("coupon not found")
+	errCouponNotValid      = errs.BadRequest("coupon isn't valid")
+	errCouponNotApplicable = errs.BadRequest("coupon doesn't apply to the cart")
+	errCouponCodeExists    = errs.Conflict("coupon code already exists")
+	errCouponInUse         = errs.Conflict("coupon is used by orders, deactivate it instead")
+	errInvalidCouponType   = errs.BadRequest("coupon type must be one of percentage, fixed, free_delivery, buy_x_get_y")
+	errInvalidCouponRule   = errs.BadRequest("percent must be between 1 and 100, amount must be positive, buy and get quantities must be at least 1")
+	errInvalidCouponLimit  = errs.BadRequest("minimum basket and usage limits can't be negative")
+	errInvalidCouponWindow = errs.BadRequest("coupon must end after it starts")
+)
+
+type couponRepo interface {
+	app.Databaser
+	FindCoupons(f *app.DBFilter) (cs []app.Coupon, total int, err error)
+	// OneCoupon and OneCouponByCode get the coupon with its products and categories
+	OneCoupon(id int) (*app.Coupon, error)
+	OneCouponByCode(code string) (*app.Coupon, error)
+	SetCouponScope(c *app.Coupon, ps []app.Product, cs []app.Category) error
+	CountCouponUsages(couponID, userID int) (int, error)
+	ProductCategoryIDs(productIDs []int) (map[int][]int, error)
+	DeleteCoupon(*app.Coupon) error
+}
+
+func NewCoupons(r couponRepo) *Coupons {
+	return &Coupons{couponRepo: r, now: time.Now}
+}
+
+// Coupons manages coupons and applies them to orders
+type Coupons struct {
+	couponRepo
+	now func() time.Time
+}
+
+// Coupons gets coupons and total count of them
+func (cs *Coupons) Coupons(f *app.DBFilter) ([]app.Coupon, int, error) {
+	if f.OrderBy == "" {
+		f.OrderBy = "created_at"
+		f.Reverse = true
+	}
+	return cs.FindCoupons(f)
+}
+
+// Coupon gets the coupon with its products and categories
+func (cs *Coupons) Coupon(id int) (*app.Coupon, error) {
+	c, err := cs.OneCoupon(id)
+	if err != nil {
+		if cs.IsNotFoundErr(err) {
+			return nil, errCouponNotFound
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+func (cs *Coupons) CreateCoupon(f *CouponForm) (*app.Coupon, error) {
+	c := app.Coupon{Type: f.Type, IsActive: true}
+	if err := cs.fill(&c, f); err != nil {
+		return nil, err
+	}
+	if err := checkCoupon(&c); err != nil {
+		return nil, err
+	}
+
+	exists, err := cs.ExistsBy(&app.Coupon{}, app.DBWhere{"code": c.Code})
+	if err != nil {
+		return nil, err
+	} else if exists {
+		return nil, errCouponCodeExists
+	}
+
+	if err := cs.Store(&c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// UpdateCoupon updates the coupon, placed orders aren't changed.
+// Products and categories are replaced only if they're given, empty ones clear the scope.
+func (cs *Coupons) UpdateCoupon(f *CouponForm) (*app.Coupon, error) {
+	c, err := cs.Coupon(f.ID)
+	if err != nil {
+		return nil, err
+	}
+	code := c.Code
+
+	if f.Type != "" {
+		c.Type = f.Type
+	}
+	ps, cats := c.Products, c.Categories
+	if err := cs.fill(c, f); err != nil {
+		return nil, err
+	}
+	if err := checkCoupon(c); err != nil {
+		return nil, err
+	}
+
+	if c.Code != code {
+		exists, err := cs.ExistsBy(&app.Coupon{}, app.DBWhere{"code": c.Code})
+		if err != nil {
+			return nil, err
+		} else if exists {
+			return nil, errCouponCodeExists
+		}
+	}
+
+	// only the editable columns are updated, so the used count which orders increase isn't overwritten.
+	// the scope is saved by SetCouponScope.
+	newPs, newCats := c.Products, c.Categories
+	c.Products, c.Categories = nil, nil
+	kv := map[string]interface{}{
+		"Code":         c.Code,
+		"Description":  c.Description,
+		"Type":         c.Type,
+		"Percent":      c.Percent,
+		"Amount":       c.Amount,
+		"BuyQty":       c.BuyQty,
+		"GetQty":       c.GetQty,
+		"MinBasket":    c.MinBasket,
+		"StartsAt":     c.StartsAt,
+		"EndsAt":       c.EndsAt,
+		"UsageLimit":   c.UsageLimit,
+		"PerUserLimit": c.PerUserLimit,
+		"IsActive":     c.IsActive,
+	}
+	if err := cs.UpdateFields(c, kv); err != nil {
+		return nil, err
+	}
+
+	if f.Products != nil || f.Categories != nil {
+		if f.Products == nil {
+			newPs = ps
+		}
+		if f.Categories == nil {
+			newCats = cats
+		}
+		if err := cs.SetCouponScope(c, newPs, newCats); err != nil {
+			return nil, err
+		}
+	}
+	c.Products, c.Categories = newPs, newCats
+	return c, nil
+}
+
+// DeleteCoupon deletes the coupon if no order used it
+func (cs *Coupons) DeleteCoupon(id int) error {
+	c, err := cs.Coupon(id)
+	if err != nil {
+		return err
+	}
+	if c.UsedCount > 0 {
+		return errCouponInUse
+	}
+	return cs.couponRepo.DeleteCoupon(c)
+}
+
+// Apply applies the coupon of the code to the user's order. Products' prices must be set
+// and the order's delivery fee must be set before, totals must be calculated after.
+// Usage limits are checked here and again while placing the order, concurrent orders can't exceed them.
+func (cs *Coupons) Apply(u *app.User, code string, o *app.Order) error {
+	c, err := cs.OneCouponByCode(normalizeCouponCode(code))
+	if err != nil {
+		if cs.IsNotFoundErr(err) {
+			return errCouponNotFound
+		}
+		return err
+	}
+
+	if !c.IsValidAt(cs.now()) {
+		return errCouponNotValid
+	}
+
+	var basket app.Money
+	for _, op := range o.Products {
+		basket = basket.Add(op.Price.Mul(op.Qty))
+	}
+	if basket.Cmp(c.MinBasket) < 0 {
+		return errs.BadRequest("cart total must be at least %s for the coupon", c.MinBasket)
+	}
+
+	if c.UsageLimit > 0 && c.UsedCount >= c.UsageLimit {
+		return app.ErrCouponUsedUp
+	}
+	if c.PerUserLimit > 0 {
+		n, err := cs.CountCouponUsages(c.ID, u.ID)
+		if err != nil {
+			return err
+		}
+		if n >= c.PerUserLimit {
+			return app.ErrCouponUserLimit
+		}
+	}
+
+	cats := make(map[int][]int)
+	if len(c.Categories) > 0 {
+		ids := make([]int, len(o.Products))
+		for i, op := range o.Products {
+			ids[i] = op.ProductID
+		}
+		if cats, err = cs.ProductCategoryIDs(ids); err != nil {
+			return err
+		}
+	}
+
+	eligible := func(op *app.OrderProduct) bool {
+		return c.Covers(op.ProductID, cats[op.ProductID])
+	}
+	if !c.Apply(o, eligible) {
+		return errCouponNotApplicable
+	}
+	return nil
+}
+
+// fill sets the coupon's fields which are given in the form
+func (cs *Coupons) fill(c *app.Coupon, f *CouponForm) error {
+	if f.Code != "" {
+		c.Code = normalizeCouponCode(f.Code)
+	}
+	if f.Description != nil {
+		c.Description = *f.Description
+	}
+	if f.Percent != nil {
+		c.Percent = *f.Percent
+	}
+	if f.Amount != nil {
+		c.Amount = *f.Amount
+	}
+	if f.BuyQty != nil {
+		c.BuyQty = *f.BuyQty
+	}
+	if f.GetQty != nil {
+		c.GetQty = *f.GetQty
+	}
+	if f.MinBasket != nil {
+		c.MinBasket = *f.MinBasket
+	}
+	if f.StartsAt != nil {
+		c.StartsAt = f.StartsAt
+	} else if f.ClearStartsAt {
+		c.StartsAt = nil
+	}
+	if f.EndsAt != nil {
+		c.EndsAt = f.EndsAt
+	} else if f.ClearEndsAt {
+		c.EndsAt = nil
+	}
+	if f.UsageLimit != nil {
+		c.UsageLimit = *f.UsageLimit
+	}
+	if f.PerUserLimit != nil {
+		c.PerUserLimit = *f.PerUserLimit
+	}
+	if f.IsActive != nil {
+		c.IsActive = *f.IsActive
+	}
+
+	if f.Products != nil {
+		c.Products = nil
+		for _, id := range f.Products {
+			var p app.Product
+			if err := cs.One(&p, id); err != nil {
+				if cs.IsNotFoundErr(err) {
+					return errProductNotFound
+				}
+				return err
+			}
+			c.Products = append(c.Products, p)
+		}
+	}
+	if f.Categories != nil {
+		c.Categories = nil
+		for _, id := range f.Categories {
+			var cat app.Category
+			if err := cs.One(&cat, id); err != nil {
+				if cs.IsNotFoundErr(err) {
+					return errCategoryNotFound
+				}
+				return err
+			}
+			c.Categories = append(c.Categories, cat)
+		}
+	}
+	return nil
+}
+
+func checkCoupon(c *app.Coupon) error {
+	if err := errs.CheckStringLen(c.Code, 3, 64, "code"); err != nil {
+		return err
+	}
+
+	var ok bool
+	switch c.Type {
+	case app.CouponPercentage:
+		ok = c.Percent >= 1 && c.Percent <= 100
+	case app.CouponFixed:
+		ok = c.Amount.Amount > 0
+	case app.CouponFreeDelivery:
+		ok = true
+	case app.CouponBuyXGetY:
+		ok = c.BuyQty >= 1 && c.GetQty >= 1
+	default:
+		return errInvalidCouponType
+	}
+	if !ok {
+		return errInvalidCouponRule
+	}
+
+	if c.MinBasket.Amount < 0 || c.UsageLimit < 0 || c.PerUserLimit < 0 {
+		return errInvalidCouponLimit
+	}
+	if c.StartsAt != nil && c.EndsAt != nil && !c.EndsAt.After(*c.StartsAt) {
+		return errInvalidCouponWindow
+	}
+	return nil
+}
+
+// normalizeCouponCode makes codes case insensitive
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// CouponForm creates or updates a coupon, nil fields aren't changed on update.
+// Percent is used by percentage coupons, Amount by fixed ones, BuyQty and GetQty by buy_x_get_y ones.
+type CouponForm struct {
+	ID           int            `json:"-"`
+	Code         string         `json:"code"`
+	Description  *string        `json:"description"`
+	Type         app.CouponType `json:"type"`
+	Percent      *int           `json:"percent"`
+	Amount       *app.Money     `json:"amount"`
+	BuyQty       *int           `json:"buyQty"`
+	GetQty       *int           `json:"getQty"`
+	MinBasket    *app.Money     `json:"minBasket"`
+	StartsAt     *time.Time     `json:"startsAt"`
+	EndsAt       *time.Time     `json:"endsAt"`
+	UsageLimit   *int           `json:"usageLimit"`
+	PerUserLimit *int           `json:"perUserLimit"`
+	IsActive     *bool          `json:"isActive"`
+	Products     []int          `json:"products"`
+	Categories   []int          `json:"categories"`
+	// ClearStartsAt and ClearEndsAt remove the dates, so the coupon is valid from now or never ends
+	ClearStartsAt bool `json:"clearStartsAt"`
+	ClearEndsAt   bool `json:"clearEndsAt"`
+}
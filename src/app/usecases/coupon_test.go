@@ -0,0 +1,172 @@
+package usecases
+
+import (
+	"app"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errCouponStubNotFound = errors.New("not found")
+
+type couponRepoStub struct {
+	app.Databaser
+	coupons map[string]*app.Coupon
+	usages  int
+	cats    map[int][]int
+	updated map[string]interface{}
+}
+
+func (r *couponRepoStub) FindCoupons(*app.DBFilter) ([]app.Coupon, int, error) { return nil, 0, nil }
+
+func (r *couponRepoStub) OneCoupon(id int) (*app.Coupon, error) {
+	for _, c := range r.coupons {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, errCouponStubNotFound
+}
+
+func (r *couponRepoStub) UpdateFields(m interface{}, kv map[string]interface{}) error {
+	r.updated = kv
+	return nil
+}
+
+func (r *couponRepoStub) OneCouponByCode(code string) (*app.Coupon, error) {
+	if c, ok := r.coupons[code]; ok {
+		return c, nil
+	}
+	return nil, errCouponStubNotFound
+}
+
+func (r *couponRepoStub) SetCouponScope(*app.Coupon, []app.Product, []app.Category) error {
+	return nil
+}
+
+func (r *couponRepoStub) CountCouponUsages(couponID, userID int) (int, error) { return r.usages, nil }
+
+func (r *couponRepoStub) ProductCategoryIDs([]int) (map[int][]int, error) { return r.cats, nil }
+
+func (r *couponRepoStub) DeleteCoupon(*app.Coupon) error { return nil }
+
+func (r *couponRepoStub) IsNotFoundErr(err error) bool {
+	return err == errCouponStubNotFound
+}
+
+func testCouponsOrder() *app.Order {
+	return &app.Order{Products: []app.OrderProduct{
+		{ProductID: 1, Qty: 2, Price: app.MustParseMoney("20")},
+		{ProductID: 2, Qty: 1, Price: app.MustParseMoney("10")},
+	}}
+}
+
+func TestCoupons_Apply(t *testing.T) {
+	c := &app.Coupon{
+		Code: "SPRING", Type: app.CouponPercentage, Percent: 50, IsActive: true,
+		Categories: []app.Category{{Model: app.Model{ID: 5}}},
+	}
+	r := &couponRepoStub{coupons: map[string]*app.Coupon{"SPRING": c}, cats: map[int][]int{2: {4, 5}}}
+	cs := NewCoupons(r)
+	u := &app.User{}
+	u.ID = 1
+
+	o := testCouponsOrder()
+	if err := cs.Apply(u, " spring ", o); err != nil {
+		t.Fatal(err)
+	}
+	if !o.Products[0].Discount.IsZero() || o.Products[1].Discount != app.MustParseMoney("5") {
+		t.Errorf("expected only the product in the category to be discounted got %s, %s", o.Products[0].Discount, o.Products[1].Discount)
+	}
+
+	if err := cs.Apply(u, "WINTER", testCouponsOrder()); err != errCouponNotFound {
+		t.Errorf("expected %v got %v", errCouponNotFound, err)
+	}
+
+	r.cats = nil
+	if err := cs.Apply(u, "SPRING", testCouponsOrder()); err != errCouponNotApplicable {
+		t.Errorf("expected %v got %v", errCouponNotApplicable, err)
+	}
+}
+
+func TestCoupons_ApplyLimits(t *testing.T) {
+	end := time.Now().Add(-time.Minute)
+	c := &app.Coupon{Code: "FIVE", Type: app.CouponFixed, Amount: app.MustParseMoney("5"), IsActive: true}
+	r := &couponRepoStub{coupons: map[string]*app.Coupon{"FIVE": c}}
+	cs := NewCoupons(r)
+	u := &app.User{}
+	u.ID = 1
+
+	tests := []struct {
+		name     string
+		setup    func()
+		expected error
+	}{
+		{"expired", func() { c.EndsAt = &end }, errCouponNotValid},
+		{"global limit", func() { c.UsageLimit, c.UsedCount = 10, 10 }, app.ErrCouponUsedUp},
+		{"per user limit", func() { c.PerUserLimit, r.usages = 1, 1 }, app.ErrCouponUserLimit},
+	}
+	for _, tt := range tests {
+		*c = app.Coupon{Code: "FIVE", Type: app.CouponFixed, Amount: app.MustParseMoney("5"), IsActive: true}
+		r.usages = 0
+		tt.setup()
+		if err := cs.Apply(u, "FIVE", testCouponsOrder()); err != tt.expected {
+			t.Errorf("%s: expected %v got %v", tt.name, tt.expected, err)
+		}
+	}
+
+	*c = app.Coupon{Code: "FIVE", Type: app.CouponFixed, Amount: app.MustParseMoney("5"), IsActive: true, MinBasket: app.MustParseMoney("100")}
+	if err := cs.Apply(u, "FIVE", testCouponsOrder()); err == nil {
+		t.Error("expected minimum basket to be checked")
+	}
+}
+
+func TestCoupons_UpdateCoupon(t *testing.T) {
+	starts, ends := time.Now(), time.Now().Add(time.Hour)
+	c := &app.Coupon{Code: "SPRING", Type: app.CouponPercentage, Percent: 10, StartsAt: &starts, EndsAt: &ends, UsedCount: 3, IsActive: true}
+	c.ID = 1
+	r := &couponRepoStub{coupons: map[string]*app.Coupon{"SPRING": c}}
+	cs := NewCoupons(r)
+
+	percent := 20
+	uc, err := cs.UpdateCoupon(&CouponForm{ID: 1, Percent: &percent, ClearEndsAt: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uc.Percent != 20 || uc.StartsAt == nil || uc.EndsAt != nil {
+		t.Errorf("expected percent 20 and no end got %+v", uc)
+	}
+
+	// orders increase the used count meanwhile, so it's never written by updates
+	if _, ok := r.updated["UsedCount"]; ok {
+		t.Errorf("expected used count not to be updated got %v", r.updated)
+	}
+	if ends, ok := r.updated["EndsAt"].(*time.Time); !ok || ends != nil {
+		t.Errorf("expected end date to be cleared got %v", r.updated["EndsAt"])
+	}
+	if r.updated["Percent"] != 20 {
+		t.Errorf("expected percent to be updated got %v", r.updated["Percent"])
+	}
+}
+
+func TestCheckCoupon(t *testing.T) {
+	start := time.Now()
+	tests := []struct {
+		c        app.Coupon
+		expected error
+	}{
+		{app.Coupon{Code: "OK10", Type: app.CouponPercentage, Percent: 10}, nil},
+		{app.Coupon{Code: "BAD", Type: "gift"}, errInvalidCouponType},
+		{app.Coupon{Code: "P00", Type: app.CouponPercentage}, errInvalidCouponRule},
+		{app.Coupon{Code: "FIX", Type: app.CouponFixed}, errInvalidCouponRule},
+		{app.Coupon{Code: "B2G0", Type: app.CouponBuyXGetY, BuyQty: 2}, errInvalidCouponRule},
+		{app.Coupon{Code: "NEG", Type: app.CouponFreeDelivery, UsageLimit: -1}, errInvalidCouponLimit},
+		{app.Coupon{Code: "WIN", Type: app.CouponFreeDelivery, StartsAt: &start, EndsAt: &start}, errInvalidCouponWindow},
+	}
+
+	for _, tt := range tests {
+		if err := checkCoupon(&tt.c); err != tt.expected {
+			t.Errorf("%s: expected %v got %v", tt.c.Code, tt.expected, err)
+		}
+	}
+}
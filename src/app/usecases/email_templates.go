@@ -0,0 +1,145 @@
+package usecases
+
+type emailSource struct {
+	subject string
+	text    string
+	html    string
+}
+
+// emailSources maps locales to named email templates
+var emailSources = map[string]map[string]emailSource{
+	"en": {
+		EmailWelcome: {
+			subject: `Welcome to GoCart`,
+			text: `Hi {{.User.FirstName}},
+
+Welcome to GoCart! Your account has been created with {{.User.Email}}.`,
+			html: `<p>Hi {{.User.FirstName}},</p>
+<p>Welcome to GoCart! Your account has been created with <b>{{.User.Email}}</b>.</p>`,
+		},
+		EmailPasswordReset: {
+			subject: `Reset your password`,
+			text: `Hi {{.User.FirstName}},
+
+Please click below link to reset your password:
+{{.Link}}
+
+If you didn't request a password reset, you can ignore this email.`,
+			html: `<p>Hi {{.User.FirstName}},</p>
+<p>Please click below link to reset your password:<br/><a href="{{.Link}}">{{.Link}}</a></p>
+<p>If you didn't request a password reset, you can ignore this email.</p>`,
+		},
+		EmailVerification: {
+			subject: `Verify your email address`,
+			text: `Hi {{.User.FirstName}},
+
+Please click below link to verify your email address:
+{{.Link}}`,
+			html: `<p>Hi {{.User.FirstName}},</p>
+<p>Please click below link to verify your email address:<br/><a href="{{.Link}}">{{.Link}}</a></p>`,
+		},
+		EmailOrderConfirmation: {
+			subject: `Your order #{{.Order.ID}} has been received`,
+			text: `Hi {{.User.FirstName}},
+
+We have received your order #{{.Order.ID}}.
+{{range .Order.Products}}
+{{.Qty}} x {{if .Product}}{{.Product.Title}}{{end}}{{with .Options}} ({{.}}){{end}} {{money .Total}}{{end}}
+
+{{if .Order.CouponCode}}Discount ({{.Order.CouponCode}}): -{{money .Order.Discount}}
+{{end}}{{if not .Order.DeliveryFee.IsZero}}Delivery: {{money .Order.DeliveryFee}}
+{{end}}{{range .Order.Taxes}}VAT {{.Rate}}%: {{money .Amount}}
+{{end}}Total: {{money .Order.Total}}
+{{with .Order.Address}}
+Delivery address: {{.Address}} {{.District}}/{{.City}}{{end}}`,
+			html: `<p>Hi {{.User.FirstName}},</p>
+<p>We have received your order <b>#{{.Order.ID}}</b>.</p>
+<table>
+{{range .Order.Products}}<tr><td>{{.Qty}} x</td><td>{{if .Product}}{{.Product.Title}}{{end}}{{with .Options}} ({{.}}){{end}}</td><td>{{money .Total}}</td></tr>
+{{end}}{{if .Order.CouponCode}}<tr><td colspan="2">Discount ({{.Order.CouponCode}})</td><td>-{{money .Order.Discount}}</td></tr>
+{{end}}{{if not .Order.DeliveryFee.IsZero}}<tr><td colspan="2">Delivery</td><td>{{money .Order.DeliveryFee}}</td></tr>
+{{end}}{{range .Order.Taxes}}<tr><td colspan="2">VAT {{.Rate}}%</td><td>{{money .Amount}}</td></tr>
+{{end}}<tr><td colspan="2"><b>Total</b></td><td><b>{{money .Order.Total}}</b></td></tr>
+</table>
+{{with .Order.Address}}<p>Delivery address: {{.Address}} {{.District}}/{{.City}}</p>{{end}}`,
+		},
+		EmailOrderStatusChange: {
+			subject: `Your order #{{.Order.ID}} is {{.Status.Name}}`,
+			text: `Hi {{.User.FirstName}},
+
+Your order #{{.Order.ID}} status has changed to: {{.Status.Name}}
+{{with .Note}}
+{{.}}{{end}}`,
+			html: `<p>Hi {{.User.FirstName}},</p>
+<p>Your order <b>#{{.Order.ID}}</b> status has changed to: <b>{{.Status.Name}}</b></p>
+{{with .Note}}<p>{{.}}</p>{{end}}`,
+		},
+	},
+	"tr": {
+		EmailWelcome: {
+			subject: `GoCart'a hoş geldiniz`,
+			text: `Merhaba {{.User.FirstName}},
+
+GoCart'a hoş geldiniz! Hesabınız {{.User.Email}} adresiyle oluşturuldu.`,
+			html: `<p>Merhaba {{.User.FirstName}},</p>
+<p>GoCart'a hoş geldiniz! Hesabınız <b>{{.User.Email}}</b> adresiyle oluşturuldu.</p>`,
+		},
+		EmailPasswordReset: {
+			subject: `Şifrenizi sıfırlayın`,
+			text: `Merhaba {{.User.FirstName}},
+
+Şifrenizi sıfırlamak için aşağıdaki bağlantıya tıklayın:
+{{.Link}}
+
+Şifre sıfırlama talebinde bulunmadıysanız bu e-postayı dikkate almayın.`,
+			html: `<p>Merhaba {{.User.FirstName}},</p>
+<p>Şifrenizi sıfırlamak için aşağıdaki bağlantıya tıklayın:<br/><a href="{{.Link}}">{{.Link}}</a></p>
+<p>Şifre sıfırlama talebinde bulunmadıysanız bu e-postayı dikkate almayın.</p>`,
+		},
+		EmailVerification: {
+			subject: `E-posta adresinizi doğrulayın`,
+			text: `Merhaba {{.User.FirstName}},
+
+E-posta adresinizi doğrulamak için aşağıdaki bağlantıya tıklayın:
+{{.Link}}`,
+			html: `<p>Merhaba {{.User.FirstName}},</p>
+<p>E-posta adresinizi doğrulamak için aşağıdaki bağlantıya tıklayın:<br/><a href="{{.Link}}">{{.Link}}</a></p>`,
+		},
+		EmailOrderConfirmation: {
+			subject: `#{{.Order.ID}} numaralı siparişiniz alındı`,
+			text: `Merhaba {{.User.FirstName}},
+
+#{{.Order.ID}} numaralı siparişiniz alındı.
+{{range .Order.Products}}
+{{.Qty}} x {{if .Product}}{{.Product.Title}}{{end}}{{with .Options}} ({{.}}){{end}} {{money .Total}}{{end}}
+
+{{if .Order.CouponCode}}İndirim ({{.Order.CouponCode}}): -{{money .Order.Discount}}
+{{end}}{{if not .Order.DeliveryFee.IsZero}}Teslimat: {{money .Order.DeliveryFee}}
+{{end}}{{range .Order.Taxes}}KDV %{{.Rate}}: {{money .Amount}}
+{{end}}Toplam: {{money .Order.Total}}
+{{with .Order.Address}}
+Teslimat adresi: {{.Address}} {{.District}}/{{.City}}{{end}}`,
+			html: `<p>Merhaba {{.User.FirstName}},</p>
+<p><b>#{{.Order.ID}}</b> numaralı siparişiniz alındı.</p>
+<table>
+{{range .Order.Products}}<tr><td>{{.Qty}} x</td><td>{{if .Product}}{{.Product.Title}}{{end}}{{with .Options}} ({{.}}){{end}}</td><td>{{money .Total}}</td></tr>
+{{end}}{{if .Order.CouponCode}}<tr><td colspan="2">İndirim ({{.Order.CouponCode}})</td><td>-{{money .Order.Discount}}</td></tr>
+{{end}}{{if not .Order.DeliveryFee.IsZero}}<tr><td colspan="2">Teslimat</td><td>{{money .Order.DeliveryFee}}</td></tr>
+{{end}}{{range .Order.Taxes}}<tr><td colspan="2">KDV %{{.Rate}}</td><td>{{money .Amount}}</td></tr>
+{{end}}<tr><td colspan="2"><b>Toplam</b></td><td><b>{{money .Order.Total}}</b></td></tr>
+</table>
+{{with .Order.Address}}<p>Teslimat adresi: {{.Address}} {{.District}}/{{.City}}</p>{{end}}`,
+		},
+		EmailOrderStatusChange: {
+			subject: `#{{.Order.ID}} numaralı siparişinizin durumu: {{.Status.Name}}`,
+			text: `Merhaba {{.User.FirstName}},
+
+#{{.Order.ID}} numaralı siparişinizin durumu değişti: {{.Status.Name}}
+{{with .Note}}
+{{.}}{{end}}`,
+			html: `<p>Merhaba {{.User.FirstName}},</p>
+<p><b>#{{.Order.ID}}</b> numaralı siparişinizin durumu değişti: <b>{{.Status.Name}}</b></p>
+{{with .Note}}<p>{{.}}</p>{{end}}`,
+		},
+	},
+}
@@ -1 +1,162 @@
 package usecases
+
+import (
+	"app"
+	"app/interfaces/errs"
+	"bytes"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Email template names
+const (
+	EmailWelcome           = "welcome"
+	EmailPasswordReset     = "password_reset"
+	EmailVerification      = "email_verification"
+	EmailOrderConfirmation = "order_confirmation"
+	EmailOrderStatusChange = "order_status_change"
+)
+
+// EmailData is passed to email templates
+type EmailData struct {
+	User   *app.User
+	Link   string
+	Order  *app.Order
+	Status *app.OrderStatus
+	Note   string
+}
+
+// RenderedEmail is an email which is ready to send
+type RenderedEmail struct {
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+}
+
+type emailTemplate struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+var emailFuncs = map[string]interface{}{
+	"money": func(m app.Money) string {
+		return m.Decimal()
+	},
+}
+
+func NewEmails(ms app.MailSender, defaultLocale string) *Emails {
+	es := Emails{ms: ms, defaultLocale: defaultLocale, tpls: make(map[string]map[string]*emailTemplate)}
+
+	for locale, tpls := range emailSources {
+		es.tpls[locale] = make(map[string]*emailTemplate)
+		for name, src := range tpls {
+			es.tpls[locale][name] = &emailTemplate{
+				subject: texttemplate.Must(texttemplate.New(name).Funcs(emailFuncs).Parse(src.subject)),
+				text:    texttemplate.Must(texttemplate.New(name).Funcs(emailFuncs).Parse(src.text)),
+				html:    htmltemplate.Must(htmltemplate.New(name).Funcs(emailFuncs).Parse(src.html)),
+			}
+		}
+	}
+	return &es
+}
+
+// Emails renders named email templates and sends them through the mail sender.
+// Every template has per-locale variants, falls back to the default locale.
+type Emails struct {
+	ms            app.MailSender
+	defaultLocale string
+	tpls          map[string]map[string]*emailTemplate
+}
+
+// Names gets template names
+func (es *Emails) Names() []string {
+	var ns []string
+	for n := range es.tpls[es.defaultLocale] {
+		ns = append(ns, n)
+	}
+	sort.Strings(ns)
+	return ns
+}
+
+func (es *Emails) Render(name, locale string, d *EmailData) (*RenderedEmail, error) {
+	tpl, ok := es.template(name, locale)
+	if !ok {
+		return nil, errs.NotFound("email template not found: %s", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tpl.subject.Execute(&subject, d); err != nil {
+		return nil, errs.WrapMsg(err, "email subject can't rendered: %s", name)
+	}
+	if err := tpl.text.Execute(&text, d); err != nil {
+		return nil, errs.WrapMsg(err, "email text can't rendered: %s", name)
+	}
+	if err := tpl.html.Execute(&html, d); err != nil {
+		return nil, errs.WrapMsg(err, "email html can't rendered: %s", name)
+	}
+
+	return &RenderedEmail{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()),
+		HTML:    strings.TrimSpace(html.String()),
+	}, nil
+}
+
+// Send renders the template and sends it to the recipients
+func (es *Emails) Send(name, locale string, to []string, d *EmailData) error {
+	e, err := es.Render(name, locale, d)
+	if err != nil {
+		return err
+	}
+
+	if ms, ok := es.ms.(app.MultipartMailSender); ok {
+		return ms.SendMultipart(to, e.Subject, []byte(e.Text), []byte(e.HTML))
+	}
+	return es.ms.Send(to, e.Subject, []byte(e.HTML))
+}
+
+// Preview renders the template with sample data
+func (es *Emails) Preview(name, locale string) (*RenderedEmail, error) {
+	return es.Render(name, locale, sampleEmailData())
+}
+
+// template finds the template by locale like "tr-TR", then by its language "tr",
+// then by the default locale
+func (es *Emails) template(name, locale string) (*emailTemplate, bool) {
+	locale = strings.ToLower(strings.Replace(locale, "_", "-", -1))
+	candidates := []string{locale, strings.SplitN(locale, "-", 2)[0], es.defaultLocale}
+
+	for _, l := range candidates {
+		if tpl, ok := es.tpls[l][name]; ok {
+			return tpl, true
+		}
+	}
+	return nil, false
+}
+
+func sampleEmailData() *EmailData {
+	now := time.Now()
+	u := &app.User{FirstName: "Ali", LastName: "Oygur", Email: "user@example.com"}
+	p := &app.Product{Title: "Mercimek Çorbası", Price: app.MustParseMoney("12.50")}
+	st := &app.OrderStatus{Name: "Tamamlandi"}
+	o := &app.Order{
+		Model:        app.Model{ID: 1001, CreatedAt: now},
+		CustomerNote: "Zile basmayın",
+		Status:       st,
+		Address: &app.OrderAddress{AddressBody: app.AddressBody{
+			Name: "Ev", FirstName: "Ali", LastName: "Oygur", Address: "Atatürk Cad. No:1", District: "Kadıköy", City: "İstanbul",
+		}},
+		Products: []app.OrderProduct{{
+			ProductID: 1, Qty: 3, Price: app.MustParseMoney("15.00"), TaxRate: 8, Product: p,
+			Options: app.ProductOptions{{Group: "Porsiyon", Value: "Büyük", PriceDelta: app.MustParseMoney("2.50")}},
+		}},
+		PricesIncludeTax: true,
+		DeliveryFee:      app.MustParseMoney("5"),
+	}
+	o.SetTotal()
+	return &EmailData{User: u, Link: "https://example.com/?token=sample", Order: o, Status: st, Note: "Siparişiniz yola çıktı"}
+}
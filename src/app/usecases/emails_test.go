@@ -0,0 +1,100 @@
+package usecases
+
+import (
+	"strings"
+	"testing"
+)
+
+type mailRecorder struct {
+	to        []string
+	subject   string
+	text      []byte
+	html      []byte
+	multipart bool
+}
+
+func (mr *mailRecorder) Send(to []string, subject string, body []byte) error {
+	mr.to, mr.subject, mr.html = to, subject, body
+	return nil
+}
+
+type multipartMailRecorder struct {
+	mailRecorder
+}
+
+func (mr *multipartMailRecorder) SendMultipart(to []string, subject string, text, html []byte) error {
+	mr.to, mr.subject, mr.text, mr.html, mr.multipart = to, subject, text, html, true
+	return nil
+}
+
+func TestEmails_RenderAll(t *testing.T) {
+	es := NewEmails(&mailRecorder{}, "en")
+
+	for locale := range emailSources {
+		for _, name := range es.Names() {
+			e, err := es.Preview(name, locale)
+			if err != nil {
+				t.Errorf("%s/%s can't rendered: %v", locale, name, err)
+				continue
+			}
+			if e.Subject == "" || e.Text == "" || e.HTML == "" {
+				t.Errorf("%s/%s rendered empty part: %+v", locale, name, e)
+			}
+		}
+	}
+}
+
+func TestEmails_Render(t *testing.T) {
+	es := NewEmails(&mailRecorder{}, "en")
+	d := sampleEmailData()
+	d.Link = "https://example.com/?a=1&b=<2>"
+
+	var tests = []struct {
+		locale          string
+		expectedSubject string
+	}{
+		{"", "Reset your password"},
+		{"tr", "Şifrenizi sıfırlayın"},
+		{"tr-TR", "Şifrenizi sıfırlayın"},
+		{"tr_TR", "Şifrenizi sıfırlayın"},
+		{"de", "Reset your password"},
+	}
+
+	for _, test := range tests {
+		e, err := es.Render(EmailPasswordReset, test.locale, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Subject != test.expectedSubject {
+			t.Errorf("locale %q: expected subject %q got %q", test.locale, test.expectedSubject, e.Subject)
+		}
+		if !strings.Contains(e.Text, d.Link) {
+			t.Errorf("locale %q: expected text to contain raw link got %q", test.locale, e.Text)
+		}
+		if strings.Contains(e.HTML, "<2>") {
+			t.Errorf("locale %q: expected html to be escaped got %q", test.locale, e.HTML)
+		}
+	}
+
+	if _, err := es.Render("unknown", "en", d); err == nil {
+		t.Error("expected error for unknown template")
+	}
+}
+
+func TestEmails_Send(t *testing.T) {
+	mr := &mailRecorder{}
+	if err := NewEmails(mr, "en").Send(EmailWelcome, "", []string{"user@example.com"}, sampleEmailData()); err != nil {
+		t.Fatal(err)
+	}
+	if mr.subject != "Welcome to GoCart" || !strings.Contains(string(mr.html), "<p>") {
+		t.Errorf("expected html welcome mail got %q %q", mr.subject, mr.html)
+	}
+
+	mmr := &multipartMailRecorder{}
+	if err := NewEmails(mmr, "en").Send(EmailWelcome, "", []string{"user@example.com"}, sampleEmailData()); err != nil {
+		t.Fatal(err)
+	}
+	if !mmr.multipart || strings.Contains(string(mmr.text), "<p>") {
+		t.Errorf("expected multipart mail with plain text part got %+v", mmr)
+	}
+}
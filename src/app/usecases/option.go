@@ -0,0 +1,246 @@
+package usecases
+
+import (
+	"app"
+	"app/interfaces/errs"
+)
+
+var (
+	errOptionGroupNotFound = errs.NotFound("option group not found")
+	errOptionValueNotFound = errs.NotFound("option value not found")
+	errInvalidOptionSelect = errs.BadRequest("min and max selections can't be negative and max selection can't be less than min selection")
+	errOptionValueReserved = errs.Conflict("option value is reserved by orders, deactivate it instead")
+)
+
+type optionRepo interface {
+	app.Databaser
+	// FindOptionGroups gets the product's option groups with their values, sorted
+	FindOptionGroups(productID int) ([]app.OptionGroup, error)
+	// DeleteOptionGroup deletes the group with its values
+	DeleteOptionGroup(*app.OptionGroup) error
+	DeleteOptionValue(*app.OptionValue) error
+}
+
+func NewOptions(r optionRepo) *Options {
+	return &Options{r}
+}
+
+// Options manages products' option groups and their values.
+// Cart items and orders keep snapshots of chosen options, so changes don't affect placed orders.
+type Options struct {
+	optionRepo
+}
+
+// OptionGroups gets the product's option groups with their values
+func (ops *Options) OptionGroups(productID int) ([]app.OptionGroup, error) {
+	if _, err := ops.product(productID); err != nil {
+		return nil, err
+	}
+	return ops.FindOptionGroups(productID)
+}
+
+func (ops *Options) CreateOptionGroup(f *OptionGroupForm) (*app.OptionGroup, error) {
+	p, err := ops.product(f.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	g := app.OptionGroup{ProductID: p.ID}
+	fillOptionGroup(&g, f)
+	if err := checkOptionGroup(&g); err != nil {
+		return nil, err
+	}
+	return &g, ops.Store(&g)
+}
+
+func (ops *Options) UpdateOptionGroup(f *OptionGroupForm) (*app.OptionGroup, error) {
+	g, err := ops.optionGroup(f.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	fillOptionGroup(g, f)
+	if err := checkOptionGroup(g); err != nil {
+		return nil, err
+	}
+	return g, ops.Save(g)
+}
+
+// DeleteOptionGroup deletes the group with its values if none of them is reserved
+func (ops *Options) DeleteOptionGroup(id int) error {
+	g, err := ops.optionGroup(id)
+	if err != nil {
+		return err
+	}
+
+	var vs []app.OptionValue
+	if err := ops.FindBy(&vs, app.DBWhere{"group_id": g.ID}, nil); err != nil {
+		return err
+	}
+	for _, v := range vs {
+		if err := ops.checkNotReserved(&v); err != nil {
+			return err
+		}
+	}
+	return ops.optionRepo.DeleteOptionGroup(g)
+}
+
+func (ops *Options) CreateOptionValue(f *OptionValueForm) (*app.OptionValue, error) {
+	g, err := ops.optionGroup(f.GroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	v := app.OptionValue{GroupID: g.ID, Name: f.Name, IsActive: true}
+	if f.PriceDelta != nil {
+		v.PriceDelta = *f.PriceDelta
+	}
+	if f.SortNumber != nil {
+		v.SortNumber = *f.SortNumber
+	}
+	if f.IsActive != nil {
+		v.IsActive = *f.IsActive
+	}
+	if err := errs.CheckStringLen(v.Name, 1, 255, "name"); err != nil {
+		return nil, err
+	}
+	return &v, ops.Store(&v)
+}
+
+// UpdateOptionValue updates the value, its stock is changed by the stock service
+func (ops *Options) UpdateOptionValue(f *OptionValueForm) (*app.OptionValue, error) {
+	v, err := ops.optionValue(f.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// only the given fields are updated, so the stock reserved meanwhile isn't overwritten
+	kv := make(map[string]interface{})
+	if f.Name != "" {
+		if err := errs.CheckStringLen(f.Name, 1, 255, "name"); err != nil {
+			return nil, err
+		}
+		kv["Name"] = f.Name
+	}
+	if f.PriceDelta != nil {
+		kv["PriceDelta"] = *f.PriceDelta
+	}
+	if f.SortNumber != nil {
+		kv["SortNumber"] = *f.SortNumber
+	}
+	if f.IsActive != nil {
+		kv["IsActive"] = *f.IsActive
+	}
+
+	if len(kv) == 0 {
+		return v, nil
+	}
+	return v, ops.UpdateFields(v, kv)
+}
+
+// DeleteOptionValue deletes the value if it isn't reserved
+func (ops *Options) DeleteOptionValue(id int) error {
+	v, err := ops.optionValue(id)
+	if err != nil {
+		return err
+	}
+	if err := ops.checkNotReserved(v); err != nil {
+		return err
+	}
+	return ops.optionRepo.DeleteOptionValue(v)
+}
+
+// checkNotReserved checks no order holds the value's stock, so reservations can be committed or released
+func (ops *Options) checkNotReserved(v *app.OptionValue) error {
+	reserved, err := ops.ExistsBy(&app.StockReservation{}, app.DBWhere{"option_value_id": v.ID, "status": app.ReservationReserved})
+	if err != nil {
+		return err
+	} else if reserved {
+		return errOptionValueReserved
+	}
+	return nil
+}
+
+func (ops *Options) product(id int) (*app.Product, error) {
+	var p app.Product
+	if err := ops.One(&p, id); err != nil {
+		if ops.IsNotFoundErr(err) {
+			return nil, errProductNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (ops *Options) optionGroup(id int) (*app.OptionGroup, error) {
+	var g app.OptionGroup
+	if err := ops.One(&g, id); err != nil {
+		if ops.IsNotFoundErr(err) {
+			return nil, errOptionGroupNotFound
+		}
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (ops *Options) optionValue(id int) (*app.OptionValue, error) {
+	var v app.OptionValue
+	if err := ops.One(&v, id); err != nil {
+		if ops.IsNotFoundErr(err) {
+			return nil, errOptionValueNotFound
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// fillOptionGroup sets the group's fields which are given in the form
+func fillOptionGroup(g *app.OptionGroup, f *OptionGroupForm) {
+	if f.Name != "" {
+		g.Name = f.Name
+	}
+	if f.Required != nil {
+		g.Required = *f.Required
+	}
+	if f.MinSelect != nil {
+		g.MinSelect = *f.MinSelect
+	}
+	if f.MaxSelect != nil {
+		g.MaxSelect = *f.MaxSelect
+	}
+	if f.SortNumber != nil {
+		g.SortNumber = *f.SortNumber
+	}
+}
+
+func checkOptionGroup(g *app.OptionGroup) error {
+	if err := errs.CheckStringLen(g.Name, 1, 255, "name"); err != nil {
+		return err
+	}
+	if g.MinSelect < 0 || g.MaxSelect < 0 || (g.MaxSelect > 0 && g.MaxSelect < g.MinSelect) {
+		return errInvalidOptionSelect
+	}
+	return nil
+}
+
+// OptionGroupForm creates or updates an option group, nil fields aren't changed on update.
+// Zero MaxSelect allows selecting any number of values.
+type OptionGroupForm struct {
+	ID         int    `json:"-"`
+	ProductID  int    `json:"-"`
+	Name       string `json:"name"`
+	Required   *bool  `json:"required"`
+	MinSelect  *int   `json:"minSelect"`
+	MaxSelect  *int   `json:"maxSelect"`
+	SortNumber *int   `json:"sortNumber"`
+}
+
+// OptionValueForm creates or updates an option value, nil fields aren't changed on update
+type OptionValueForm struct {
+	ID         int        `json:"-"`
+	GroupID    int        `json:"-"`
+	Name       string     `json:"name"`
+	PriceDelta *app.Money `json:"priceDelta"`
+	SortNumber *int       `json:"sortNumber"`
+	IsActive   *bool      `json:"isActive"`
+}
@@ -0,0 +1,151 @@
+package usecases
+
+import (
+	"app"
+	"app/interfaces/errs"
+	"log"
+)
+
+var (
+	errOrderNotFound       = errs.NotFound("order not found")
+	errOrderStatusNotExist = errs.BadRequest("order status not exists")
+)
+
+type orderRepo interface {
+	app.Databaser
+	FindOrdersByUser(userID int, f *app.DBFilter) ([]app.Order, error)
+	OneOrderByUser(userID int, id interface{}) (*app.Order, error)
+	FindOrders(app.DBWhere, *app.DBFilter) ([]app.Order, error)
+	OneOrder(id interface{}) (*app.Order, error)
+	// ChangeOrderStatus changes the order's status if it's still the from status,
+	// and commits or releases its stock by the action in the same transaction
+	ChangeOrderStatus(o *app.Order, from int, h *app.OrderHistory, a app.StockAction) error
+}
+
+type stockSyncer interface {
+	StockAction(statusID int) app.StockAction
+}
+
+func NewOrders(r orderRepo, ss stockSyncer, wf *app.OrderWorkflow, es emailSender) *Orders {
+	return &Orders{r, ss, wf, es}
+}
+
+type Orders struct {
+	orderRepo
+	ss stockSyncer
+	wf *app.OrderWorkflow
+	es emailSender
+}
+
+// UserOrders gets user's orders, newest first
+func (os *Orders) UserOrders(u *app.User, f *app.DBFilter) ([]app.Order, error) {
+	f.OrderBy = "created_at"
+	f.Reverse = true
+	return os.FindOrdersByUser(u.ID, f)
+}
+
+// UserOrder gets user's order with its details
+func (os *Orders) UserOrder(u *app.User, id int) (*app.Order, error) {
+	o, err := os.OneOrderByUser(u.ID, id)
+	if err != nil {
+		if os.IsNotFoundErr(err) {
+			return nil, errOrderNotFound
+		}
+		return nil, err
+	}
+	return o, nil
+}
+
+// Orders gets all orders matching the filter, newest first
+func (os *Orders) Orders(ff *OrderFilterForm, f *app.DBFilter) ([]app.Order, error) {
+	w := app.DBWhere{}
+	if ff.StatusID != 0 {
+		w["status_id"] = ff.StatusID
+	}
+	if ff.UserID != 0 {
+		w["user_id"] = ff.UserID
+	}
+
+	f.OrderBy = "created_at"
+	f.Reverse = true
+	return os.FindOrders(w, f)
+}
+
+// Order gets an order with its details
+func (os *Orders) Order(id int) (*app.Order, error) {
+	o, err := os.OneOrder(id)
+	if err != nil {
+		if os.IsNotFoundErr(err) {
+			return nil, errOrderNotFound
+		}
+		return nil, err
+	}
+	return o, nil
+}
+
+// ChangeStatus moves the order to a new status if the workflow allows it
+// and records the change to the order's history. It fails if the status
+// is changed by another request after it's checked.
+// The order's stock is committed or released by the new status with the change.
+func (os *Orders) ChangeStatus(by *app.User, f *OrderStatusForm) (*app.Order, error) {
+	o, err := os.Order(f.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var st app.OrderStatus
+	if err := os.One(&st, f.StatusID); err != nil {
+		if os.IsNotFoundErr(err) {
+			return nil, errOrderStatusNotExist
+		}
+		return nil, err
+	}
+
+	if !os.wf.CanTransition(o.StatusID, st.ID) {
+		from := ""
+		if o.Status != nil {
+			from = o.Status.Name
+		}
+		return nil, errs.BadRequest("order status can't change from %q to %q", from, st.Name)
+	}
+
+	from := o.StatusID
+	o.StatusID = st.ID
+	h := app.OrderHistory{UserID: by.ID, StatusID: int16(st.ID), Note: f.Note}
+	if err := os.ChangeOrderStatus(o, from, &h, os.ss.StockAction(st.ID)); err != nil {
+		o.StatusID = from
+		return nil, err
+	}
+
+	h.Status = &st
+	o.Status = &st
+	o.History = append(o.History, h)
+
+	os.notifyStatusChange(o, f.Note)
+	return o, nil
+}
+
+// notifyStatusChange sends status change mail to the order's customer
+func (os *Orders) notifyStatusChange(o *app.Order, note string) {
+	var u app.User
+	if err := os.One(&u, o.UserID); err != nil {
+		log.Printf("order's user can't get, order: %d, err:%s", o.ID, err)
+		return
+	}
+
+	d := &EmailData{User: &u, Order: o, Status: o.Status, Note: note}
+	if err := os.es.Send(EmailOrderStatusChange, "", []string{u.Email}, d); err != nil {
+		log.Printf("order status change mail can't sent, err:%s", err)
+	}
+}
+
+type OrderFilterForm struct {
+	StatusID int
+	UserID   int
+}
+
+type OrderStatusForm struct {
+	ID       int    `json:"-"`
+	StatusID int    `json:"statusId"`
+	Note     string `json:"note"`
+}
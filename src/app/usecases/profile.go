@@ -0,0 +1,155 @@
+package usecases
+
+import (
+	"app"
+	"app/interfaces/errs"
+	"log"
+	"net/url"
+	"strings"
+)
+
+var (
+	errWrongPassword    = errs.BadRequest("current password is wrong")
+	errEmailExists      = errs.BadRequest("email address already exists")
+	errPasswordRequired = errs.BadRequest("set a password before changing email address")
+	errVerifyLinkEmpty  = errs.BadRequest("email verification link isn't configured")
+)
+
+type profileRepo interface {
+	ExistsByEmail(string) (bool, error)
+	UpdateUser(*app.User, map[string]interface{}) error
+}
+
+type profileTokens interface {
+	VerificationToken(*app.User) (string, error)
+	RevokeAll(*app.User) error
+}
+
+func NewProfile(r profileRepo, ts profileTokens, es emailSender) *Profile {
+	return &Profile{profileRepo: r, ts: ts, es: es}
+}
+
+// Profile updates signed in user's profile
+type Profile struct {
+	profileRepo
+	ts profileTokens
+	es emailSender
+	// VerifyURL is the email verification link which the token is added to
+	VerifyURL string
+}
+
+// ProfileForm has the profile fields to change, empty fields aren't changed.
+// CurrentPassword is required to change email or password.
+type ProfileForm struct {
+	FirstName       *string `json:"firstName"`
+	LastName        *string `json:"lastName"`
+	Email           string  `json:"email"`
+	Password        string  `json:"password"`
+	CurrentPassword string  `json:"currentPassword"`
+}
+
+// Update updates the user's profile. A new email address isn't used until
+// it's verified, it's kept as the pending email and a verification email is sent.
+// Changing password signs out all sessions of the user.
+func (ps *Profile) Update(u *app.User, f *ProfileForm, locale string) error {
+	fields := make(map[string]interface{})
+
+	if f.FirstName != nil {
+		if err := errs.CheckName(*f.FirstName); err != nil {
+			return err
+		}
+		fields["FirstName"] = *f.FirstName
+	}
+	if f.LastName != nil {
+		if err := errs.CheckName(*f.LastName); err != nil {
+			return err
+		}
+		fields["LastName"] = *f.LastName
+	}
+
+	var verifyURL *url.URL
+	email := strings.TrimSpace(f.Email)
+	if email != "" && email != u.Email {
+		if err := errs.CheckEmail(email); err != nil {
+			return err
+		}
+		if u.Password == "" {
+			return errPasswordRequired
+		}
+		if err := ps.checkPassword(u, f.CurrentPassword); err != nil {
+			return err
+		}
+
+		exists, err := ps.ExistsByEmail(email)
+		if err != nil {
+			return err
+		} else if exists {
+			return errEmailExists
+		}
+
+		if ps.VerifyURL == "" {
+			return errVerifyLinkEmpty
+		}
+		if verifyURL, err = url.Parse(ps.VerifyURL); err != nil {
+			return errs.BadRequest("invalid url").SetInner(err)
+		}
+		fields["PendingEmail"] = email
+	}
+
+	if f.Password != "" {
+		if err := errs.CheckPassword(f.Password); err != nil {
+			return err
+		}
+		if err := ps.checkPassword(u, f.CurrentPassword); err != nil {
+			return err
+		}
+
+		nu := *u
+		nu.SetPassword(f.Password)
+		fields["Password"] = nu.Password
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	if err := ps.UpdateUser(u, fields); err != nil {
+		return err
+	}
+
+	if f.Password != "" {
+		if err := ps.ts.RevokeAll(u); err != nil {
+			return err
+		}
+	}
+
+	if verifyURL != nil {
+		ps.sendVerification(u, verifyURL, locale)
+	}
+	return nil
+}
+
+// checkPassword checks the current password, users who haven't a password can set one without it
+func (ps *Profile) checkPassword(u *app.User, password string) error {
+	if u.Password == "" || u.IsCredentialsVerified(password) {
+		return nil
+	}
+	return errWrongPassword
+}
+
+func (ps *Profile) sendVerification(u *app.User, verifyURL *url.URL, locale string) {
+	token, err := ps.ts.VerificationToken(u)
+	if err != nil {
+		log.Printf("verification token can't generated, err:%s", err)
+		return
+	}
+
+	q := verifyURL.Query()
+	q.Set("token", token)
+	verifyURL.RawQuery = q.Encode()
+
+	d := &EmailData{User: u, Link: verifyURL.String()}
+	if err := ps.es.Send(EmailVerification, locale, []string{u.PendingEmail}, d); err != nil {
+		log.Printf("verification mail can't sent, err:%s", err)
+	}
+}
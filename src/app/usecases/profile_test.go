@@ -0,0 +1,108 @@
+package usecases
+
+import (
+	"app"
+	"app/interfaces/repos/mockdb"
+	"strings"
+	"testing"
+)
+
+func newTestProfile(t *testing.T) (*Profile, *Tokens, *mailRecorder, *app.User) {
+	ur := &mockdb.User{}
+	u := &app.User{Email: "user@gmail.com", FirstName: "Old", IsActivated: true}
+	u.SetPassword("good password")
+	if err := ur.Create(u); err != nil {
+		t.Fatal(err)
+	}
+	taken := &app.User{Email: "taken@gmail.com"}
+	if err := ur.Create(taken); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := NewTokens(&mockdb.Token{Users: ur}, testKeySet(t, "secret"))
+	mr := &mailRecorder{}
+	ps := NewProfile(ur, ts, NewEmails(mr, "en"))
+	ps.VerifyURL = "http://localhost/verify"
+	return ps, ts, mr, u
+}
+
+func TestProfile_UpdateNames(t *testing.T) {
+	ps, _, _, u := newTestProfile(t)
+
+	first, last, bad := "New", "Name", "x"
+	if err := ps.Update(u, &ProfileForm{FirstName: &first, LastName: &last}, "en"); err != nil {
+		t.Fatal(err)
+	}
+	if u.FirstName != first || u.LastName != last {
+		t.Errorf("expected name %s %s got %s %s", first, last, u.FirstName, u.LastName)
+	}
+
+	if err := ps.Update(u, &ProfileForm{FirstName: &bad}, "en"); err == nil {
+		t.Error("expected invalid name to be rejected")
+	}
+	if u.FirstName != first {
+		t.Errorf("expected first name not to be changed got %s", u.FirstName)
+	}
+}
+
+func TestProfile_UpdateEmail(t *testing.T) {
+	ps, _, mr, u := newTestProfile(t)
+
+	var tests = []struct {
+		name string
+		form ProfileForm
+		err  error
+	}{
+		{"without current password", ProfileForm{Email: "new@gmail.com"}, errWrongPassword},
+		{"with wrong current password", ProfileForm{Email: "new@gmail.com", CurrentPassword: "bad"}, errWrongPassword},
+		{"to taken email", ProfileForm{Email: "taken@gmail.com", CurrentPassword: "good password"}, errEmailExists},
+	}
+	for _, test := range tests {
+		if err := ps.Update(u, &test.form, "en"); err != test.err {
+			t.Errorf("%s: expected error %v got %v", test.name, test.err, err)
+		}
+	}
+
+	f := &ProfileForm{Email: "new@gmail.com", CurrentPassword: "good password"}
+	ps.VerifyURL = ""
+	if err := ps.Update(u, f, "en"); err != errVerifyLinkEmpty {
+		t.Errorf("without verification link: expected error %v got %v", errVerifyLinkEmpty, err)
+	}
+	if u.PendingEmail != "" {
+		t.Errorf("expected no pending email without verification link got %s", u.PendingEmail)
+	}
+
+	ps.VerifyURL = "http://localhost/verify"
+	if err := ps.Update(u, f, "en"); err != nil {
+		t.Fatal(err)
+	}
+	if u.Email != "user@gmail.com" || u.PendingEmail != "new@gmail.com" {
+		t.Errorf("expected new email to be pending got email %s, pending %s", u.Email, u.PendingEmail)
+	}
+	if len(mr.to) != 1 || mr.to[0] != "new@gmail.com" || !strings.Contains(string(mr.html), "token=") {
+		t.Errorf("expected verification mail to the new email got %v: %s", mr.to, mr.html)
+	}
+}
+
+func TestProfile_UpdatePassword(t *testing.T) {
+	ps, ts, _, u := newTestProfile(t)
+
+	tp, err := ts.Issue(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ps.Update(u, &ProfileForm{Password: "new password", CurrentPassword: "bad"}, "en"); err != errWrongPassword {
+		t.Errorf("expected wrong current password to be rejected got %v", err)
+	}
+	if err := ps.Update(u, &ProfileForm{Password: "new password", CurrentPassword: "good password"}, "en"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !u.IsCredentialsVerified("new password") {
+		t.Error("expected password to be changed")
+	}
+	if _, err := ts.Authenticate(tp.AccessToken); err == nil {
+		t.Error("expected sessions to be revoked after password change")
+	}
+}
@@ -0,0 +1,208 @@
+package usecases
+
+import (
+	"app"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ProductSearcher searches active products, the most relevant first
+type ProductSearcher interface {
+	Search(*app.SearchQuery) (ps []app.Product, total int, err error)
+}
+
+// ProductIndexer keeps an index of active products up to date
+type ProductIndexer interface {
+	// Index adds or updates given products, inactive ones are removed from the index
+	Index(ps ...app.Product)
+	Remove(ids ...int)
+}
+
+func matchPrice(q *app.SearchQuery, p *app.Product) bool {
+	if q.MinPrice != nil && p.Price.Cmp(*q.MinPrice) < 0 {
+		return false
+	}
+	if q.MaxPrice != nil && p.Price.Cmp(*q.MaxPrice) > 0 {
+		return false
+	}
+	return true
+}
+
+func matchCategory(q *app.SearchQuery, p *app.Product) bool {
+	if len(q.Categories) == 0 {
+		return true
+	}
+	for _, c := range p.Categories {
+		for _, id := range q.Categories {
+			if c.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func NewIndexSearcher() *IndexSearcher {
+	return &IndexSearcher{
+		products: make(map[int]app.Product),
+		index:    make(map[string]map[int]int),
+	}
+}
+
+// IndexSearcher is an in-process inverted index product searcher.
+// It matches a query term to indexed words which start with the term,
+// they're found by binary search in the sorted words.
+type IndexSearcher struct {
+	mu       sync.RWMutex
+	products map[int]app.Product
+	// index maps words to product ids and their weights
+	index map[string]map[int]int
+	// words are the index's words, sorted
+	words []string
+}
+
+// Reindex replaces all indexed products with given ones
+func (is *IndexSearcher) Reindex(ps []app.Product) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	is.products = make(map[int]app.Product)
+	is.index = make(map[string]map[int]int)
+	is.words = nil
+	for _, p := range ps {
+		is.add(p)
+	}
+}
+
+// Index adds or updates given products, inactive ones are removed from the index
+func (is *IndexSearcher) Index(ps ...app.Product) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	for _, p := range ps {
+		is.remove(p.ID)
+		is.add(p)
+	}
+}
+
+// Remove removes products from the index
+func (is *IndexSearcher) Remove(ids ...int) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	for _, id := range ids {
+		is.remove(id)
+	}
+}
+
+func (is *IndexSearcher) Search(q *app.SearchQuery) ([]app.Product, int, error) {
+	terms := q.Terms()
+	if len(terms) == 0 {
+		return nil, 0, nil
+	}
+
+	is.mu.RLock()
+	defer is.mu.RUnlock()
+
+	// every term must match, the product's score is sum of the terms' weights
+	var scores map[int]int
+	for _, t := range terms {
+		ts := make(map[int]int)
+		for i := sort.SearchStrings(is.words, t); i < len(is.words) && strings.HasPrefix(is.words[i], t); i++ {
+			for id, weight := range is.index[is.words[i]] {
+				if weight > ts[id] {
+					ts[id] = weight
+				}
+			}
+		}
+
+		if scores == nil {
+			scores = ts
+			continue
+		}
+		for id := range scores {
+			if _, ok := ts[id]; !ok {
+				delete(scores, id)
+				continue
+			}
+			scores[id] += ts[id]
+		}
+	}
+
+	var ps []app.Product
+	for id := range scores {
+		p := is.products[id]
+		if matchPrice(q, &p) && matchCategory(q, &p) {
+			ps = append(ps, p)
+		}
+	}
+
+	sort.Slice(ps, func(i, j int) bool {
+		si, sj := scores[ps[i].ID], scores[ps[j].ID]
+		if si != sj {
+			return si > sj
+		}
+		return ps[i].ID < ps[j].ID
+	})
+
+	total := len(ps)
+	if q.Offset >= total {
+		return nil, total, nil
+	}
+	ps = ps[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(ps) {
+		ps = ps[:q.Limit]
+	}
+	return ps, total, nil
+}
+
+func (is *IndexSearcher) add(p app.Product) {
+	if !p.IsActive {
+		return
+	}
+	is.products[p.ID] = p
+
+	is.addWords(p.ID, p.Title, app.SearchTitleWeight)
+	is.addWords(p.ID, p.Description, app.SearchDescriptionWeight)
+	for _, c := range p.Categories {
+		is.addWords(p.ID, c.Title, app.SearchCategoryWeight)
+	}
+}
+
+func (is *IndexSearcher) addWords(id int, s string, weight int) {
+	for _, w := range app.SearchTerms(s) {
+		ids, ok := is.index[w]
+		if !ok {
+			ids = make(map[int]int)
+			is.index[w] = ids
+
+			i := sort.SearchStrings(is.words, w)
+			is.words = append(is.words, "")
+			copy(is.words[i+1:], is.words[i:])
+			is.words[i] = w
+		}
+		if weight > ids[id] {
+			ids[id] = weight
+		}
+	}
+}
+
+func (is *IndexSearcher) remove(id int) {
+	if _, ok := is.products[id]; !ok {
+		return
+	}
+	delete(is.products, id)
+
+	words := is.words[:0]
+	for _, w := range is.words {
+		ids := is.index[w]
+		delete(ids, id)
+		if len(ids) == 0 {
+			delete(is.index, w)
+			continue
+		}
+		words = append(words, w)
+	}
+	is.words = words
+}
@@ -0,0 +1,165 @@
+package usecases
+
+import (
+	"app"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func testProducts() []app.Product {
+	soup := app.Category{Model: app.Model{ID: 1}, Title: "Çorba & Atıştırma"}
+	dessert := app.Category{Model: app.Model{ID: 2}, Title: "Tatlılar"}
+
+	return []app.Product{
+		{Model: app.Model{ID: 1}, Title: "Mercimek Çorbası", Description: "Kırmızı mercimek", Price: app.MustParseMoney("12"), IsActive: true, Categories: []app.Category{soup}},
+		{Model: app.Model{ID: 2}, Title: "Ezogelin", Description: "Bulgurlu mercimek çorbası", Price: app.MustParseMoney("14"), IsActive: true, Categories: []app.Category{soup}},
+		{Model: app.Model{ID: 3}, Title: "Sütlaç", Description: "Fırında sütlaç", Price: app.MustParseMoney("18"), IsActive: true, Categories: []app.Category{dessert}},
+		{Model: app.Model{ID: 4}, Title: "Tavuk Çorbası", Description: "Şehriyeli", Price: app.MustParseMoney("15"), IsActive: false, Categories: []app.Category{soup}},
+	}
+}
+
+func productIDs(ps []app.Product) []int {
+	var ids []int
+	for _, p := range ps {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func TestIndexSearcher_Search(t *testing.T) {
+	is := NewIndexSearcher()
+	is.Reindex(testProducts())
+
+	price := func(s string) *app.Money { m := app.MustParseMoney(s); return &m }
+
+	var tests = []struct {
+		name          string
+		query         app.SearchQuery
+		expectedIDs   []int
+		expectedTotal int
+	}{
+		{"no term", app.SearchQuery{Text: " "}, nil, 0},
+		{"title ranks first", app.SearchQuery{Text: "mercimek"}, []int{1, 2}, 2},
+		{"prefix", app.SearchQuery{Text: "çorb"}, []int{1, 2}, 2},
+		{"all terms must match", app.SearchQuery{Text: "mercimek bulgurlu"}, []int{2}, 1},
+		{"category title", app.SearchQuery{Text: "tatlılar"}, []int{3}, 1},
+		{"inactive not indexed", app.SearchQuery{Text: "tavuk"}, nil, 0},
+		{"price range", app.SearchQuery{Text: "çorba", MinPrice: price("13"), MaxPrice: price("20")}, []int{2}, 1},
+		{"category filter", app.SearchQuery{Text: "fırında", Categories: []int{1}}, nil, 0},
+		{"paging", app.SearchQuery{Text: "mercimek", Limit: 1, Offset: 1}, []int{2}, 2},
+	}
+
+	for _, test := range tests {
+		ps, total, err := is.Search(&test.query)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", test.name, err)
+		}
+		if ids := productIDs(ps); !reflect.DeepEqual(ids, test.expectedIDs) {
+			t.Errorf("%s: expected products %v got %v", test.name, test.expectedIDs, ids)
+		}
+		if total != test.expectedTotal {
+			t.Errorf("%s: expected total %d got %d", test.name, test.expectedTotal, total)
+		}
+	}
+}
+
+func TestIndexSearcher_IndexAndRemove(t *testing.T) {
+	is := NewIndexSearcher()
+	is.Reindex(testProducts())
+
+	p := testProducts()[2]
+	p.Title = "Kazandibi"
+	is.Index(p)
+
+	if ps, _, _ := is.Search(&app.SearchQuery{Text: "kazandibi"}); len(ps) != 1 {
+		t.Errorf("expected updated product to be found, got %v", productIDs(ps))
+	}
+	if ps, _, _ := is.Search(&app.SearchQuery{Text: "sütlaç"}); len(ps) != 1 {
+		t.Errorf("expected product to be found by description, got %v", productIDs(ps))
+	}
+
+	is.Remove(p.ID)
+	if ps, _, _ := is.Search(&app.SearchQuery{Text: "kazandibi"}); len(ps) != 0 {
+		t.Errorf("expected removed product not to be found, got %v", productIDs(ps))
+	}
+	if !sort.StringsAreSorted(is.words) || len(is.words) != len(is.index) {
+		t.Errorf("expected sorted words of the index got %v", is.words)
+	}
+}
+
+// catalogRepoStub keeps products in memory, only the active ones are found
+type catalogRepoStub struct {
+	cRepo
+	products map[int]*app.Product
+}
+
+func (r *catalogRepoStub) OneActiveProduct(id interface{}) (*app.Product, error) {
+	p, ok := r.products[id.(int)]
+	if !ok || !p.IsActive {
+		return nil, errProductNotFound
+	}
+	return p, nil
+}
+
+func (r *catalogRepoStub) UpdateFields(m interface{}, kv map[string]interface{}) error {
+	p := r.products[m.(*app.Product).ID]
+	if v, ok := kv["Title"]; ok {
+		p.Title = v.(string)
+	}
+	if v, ok := kv["IsActive"]; ok {
+		p.IsActive = v.(bool)
+	}
+	return nil
+}
+
+func (r *catalogRepoStub) DeleteProduct(id interface{}) error {
+	delete(r.products, id.(int))
+	return nil
+}
+
+func (r *catalogRepoStub) IsNotFoundErr(err error) bool { return err == errProductNotFound }
+
+func TestCatalog_updatesIndex(t *testing.T) {
+	r := &catalogRepoStub{products: make(map[int]*app.Product)}
+	for _, p := range testProducts() {
+		p := p
+		r.products[p.ID] = &p
+	}
+	is := NewIndexSearcher()
+	is.Reindex(testProducts())
+	cs := NewCatalog(r)
+	cs.Indexer = is
+
+	search := func(text string) []int {
+		ps, _, _ := is.Search(&app.SearchQuery{Text: text})
+		return productIDs(ps)
+	}
+
+	if _, err := cs.UpdateProduct(&ProductForm{ID: 3, Title: "Kazandibi"}); err != nil {
+		t.Fatal(err)
+	}
+	if ids := search("kazandibi"); !reflect.DeepEqual(ids, []int{3}) {
+		t.Errorf("expected updated product to be found, got %v", ids)
+	}
+
+	inactive := false
+	if _, err := cs.UpdateProduct(&ProductForm{ID: 1, IsActive: &inactive}); err != nil {
+		t.Fatal(err)
+	}
+	if ids := search("mercimek"); !reflect.DeepEqual(ids, []int{2}) {
+		t.Errorf("expected deactivated product to be removed, got %v", ids)
+	}
+
+	negative := app.MustParseMoney("-1")
+	if _, err := cs.UpdateProduct(&ProductForm{ID: 2, Price: &negative}); err != errInvalidPrice {
+		t.Errorf("expected %v got %v", errInvalidPrice, err)
+	}
+
+	if err := cs.DeleteProduct(2); err != nil {
+		t.Fatal(err)
+	}
+	if ids := search("ezogelin"); len(ids) != 0 {
+		t.Errorf("expected deleted product to be removed, got %v", ids)
+	}
+}
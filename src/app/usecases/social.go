@@ -3,48 +3,157 @@ package usecases
 import (
 	"app"
 	"app/infra/social"
-	"app/infra/social/providers"
 	"app/interfaces/errs"
 )
 
-func NewSocialAuth() *socialAuth {
-	return &socialAuth{}
+var (
+	errSocialEmailRequired = errs.BadRequest("social account hasn't a verified email address")
+	errSocialEmailExists   = errs.Conflict("an account with this email address already exists, sign in and link the social account from your account")
+	errIdentityLinked      = errs.Conflict("social account is already linked to another user")
+	errProviderLinked      = errs.Conflict("an account of this provider is already linked")
+	errIdentityNotFound    = errs.NotFound("social identity not found")
+	errLastSignInMethod    = errs.BadRequest("the only sign in method can't be unlinked, set a password first")
+)
+
+type identityRepo interface {
+	OneByID(int) (*app.User, error)
+	OneByEmail(string) (*app.User, error)
+	OneIdentity(provider, subject string) (*app.SocialIdentity, error)
+	CreateIdentity(*app.SocialIdentity) error
+	CreateWithIdentity(*app.User, *app.SocialIdentity) error
+	FindIdentitiesByUser(userID int) ([]app.SocialIdentity, error)
+	DeleteIdentity(*app.SocialIdentity) error
+	app.DBNotFoundErrChecker
+}
+
+func NewSocialAuth(r identityRepo, reg *social.Registry) *SocialAuth {
+	return &SocialAuth{r, reg}
+}
+
+// SocialAuth signs users in through social login providers
+type SocialAuth struct {
+	identityRepo
+	reg *social.Registry
 }
 
-type socialAuth struct{}
+// Providers gets enabled providers' names
+func (sa *SocialAuth) Providers() []string {
+	return sa.reg.Names()
+}
 
-func (sa *socialAuth) GetUserFromFacebook(token string) (*app.User, error) {
-	fb := social.New(providers.NewFacebook(token))
-	su, err := fb.GetUser()
+// SignIn gets the provider's user of the token and returns the linked user.
+// If there isn't a linked user a new user is created, created reports it.
+// Accounts which have the same email are never signed in, they must link
+// the social account explicitly.
+func (sa *SocialAuth) SignIn(provider, token string) (u *app.User, created bool, err error) {
+	su, err := sa.socialUser(provider, token)
 	if err != nil {
-		if err, ok := err.(*social.ApiErr); ok {
-			return nil, errs.BadRequest("user can't get from facebook").SetInner(err)
+		return nil, false, err
+	}
+
+	i, err := sa.OneIdentity(provider, su.ID)
+	if err == nil {
+		u, err := sa.OneByID(i.UserID)
+		return u, false, err
+	} else if !sa.IsNotFoundErr(err) {
+		return nil, false, err
+	}
+
+	if su.Email == "" || !su.EmailVerified {
+		return nil, false, errSocialEmailRequired
+	}
+
+	i = &app.SocialIdentity{Provider: provider, Subject: su.ID, Email: su.Email}
+
+	if _, err := sa.OneByEmail(su.Email); err == nil {
+		return nil, false, errSocialEmailExists
+	} else if !sa.IsNotFoundErr(err) {
+		return nil, false, err
+	}
+
+	u = &app.User{
+		Email:       su.Email,
+		FirstName:   su.FirstName,
+		LastName:    su.LastName,
+		IsActivated: true,
+	}
+	if err := sa.CreateWithIdentity(u, i); err != nil {
+		return nil, false, err
+	}
+	return u, true, nil
+}
+
+// Identities gets user's linked social identities
+func (sa *SocialAuth) Identities(u *app.User) ([]app.SocialIdentity, error) {
+	return sa.FindIdentitiesByUser(u.ID)
+}
+
+// Link links the provider's account of the token to the signed in user
+func (sa *SocialAuth) Link(u *app.User, provider, token string) (*app.SocialIdentity, error) {
+	su, err := sa.socialUser(provider, token)
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := sa.OneIdentity(provider, su.ID)
+	if err == nil {
+		if i.UserID != u.ID {
+			return nil, errIdentityLinked
 		}
+		return i, nil
+	} else if !sa.IsNotFoundErr(err) {
 		return nil, err
 	}
 
-	var u app.User
-	u.Email = su.Email
-	u.FirstName = su.FirstName
-	u.LastName = su.LastName
-	return &u, nil
-}
+	is, err := sa.FindIdentitiesByUser(u.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range is {
+		if i.Provider == provider {
+			return nil, errProviderLinked
+		}
+	}
 
-func NewMockSocialAuth() *mockSocialAuth {
-	return &mockSocialAuth{}
+	i = &app.SocialIdentity{UserID: u.ID, Provider: provider, Subject: su.ID, Email: su.Email}
+	if err := sa.CreateIdentity(i); err != nil {
+		return nil, err
+	}
+	return i, nil
 }
 
-type mockSocialAuth struct{}
+// Unlink removes user's social identity. Users who haven't a password
+// can't remove their last identity, otherwise they can't sign in.
+func (sa *SocialAuth) Unlink(u *app.User, id int) error {
+	is, err := sa.FindIdentitiesByUser(u.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, i := range is {
+		if i.ID != id {
+			continue
+		}
+		if len(is) == 1 && u.Password == "" {
+			return errLastSignInMethod
+		}
+		return sa.DeleteIdentity(&i)
+	}
+	return errIdentityNotFound
+}
 
-func (msa *mockSocialAuth) GetUserFromFacebook(token string) (*app.User, error) {
-	validToken := "valid token"
-	if token != validToken {
-		return nil, errs.BadRequest("api error")
+func (sa *SocialAuth) socialUser(provider, token string) (*social.User, error) {
+	p, ok := sa.reg.Get(provider)
+	if !ok {
+		return nil, errs.NotFound("social provider %q not found", provider)
 	}
 
-	var u app.User
-	u.Email = "user@facebook.com"
-	u.FirstName = "Mark"
-	u.LastName = "Zuckerberg"
-	return &u, nil
+	su, err := p.GetUser(token)
+	if err != nil {
+		if err, ok := err.(*social.ApiErr); ok {
+			return nil, errs.BadRequest("user can't get from %s", provider).SetInner(err)
+		}
+		return nil, err
+	}
+	return su, nil
 }
@@ -0,0 +1,123 @@
+package usecases
+
+import (
+	"app"
+	"app/infra/social"
+	"app/infra/social/socialtest"
+	"app/interfaces/repos/mockdb"
+	"testing"
+)
+
+func newTestSocialAuth(t *testing.T) (*SocialAuth, *app.User) {
+	ur := &mockdb.User{}
+	existing := &app.User{Email: "user@gmail.com", IsActivated: true}
+	existing.SetPassword("good password")
+	if err := ur.Create(existing); err != nil {
+		t.Fatal(err)
+	}
+
+	sa := NewSocialAuth(ur, social.NewRegistry(
+		socialtest.NewProvider("google", &social.User{ID: "g1", Email: "user@gmail.com", EmailVerified: true}),
+		socialtest.NewProvider("github", &social.User{ID: "gh1", Email: "other@github.com", EmailVerified: true}),
+		socialtest.NewProvider("facebook", &social.User{ID: "f1", Email: "new@facebook.com", EmailVerified: true, FirstName: "Mark"}),
+		socialtest.NewProvider("unverified", &social.User{ID: "u1", Email: "unverified@gmail.com"}),
+	))
+	return sa, existing
+}
+
+func TestSocialAuth_SignIn(t *testing.T) {
+	sa, _ := newTestSocialAuth(t)
+
+	u, created, err := sa.SignIn("facebook", socialtest.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created || u.Email != "new@facebook.com" || u.FirstName != "Mark" || !u.IsActivated {
+		t.Errorf("expected a new activated user got %+v, created: %v", u, created)
+	}
+	if u, created, _ = sa.SignIn("facebook", socialtest.Token); created || u.Email != "new@facebook.com" {
+		t.Errorf("expected linked user on second sign in got %+v, created: %v", u, created)
+	}
+
+	// existing accounts aren't signed in by email
+	if _, _, err := sa.SignIn("google", socialtest.Token); err != errSocialEmailExists {
+		t.Errorf("expected existing email to be rejected got %v", err)
+	}
+	if _, _, err := sa.SignIn("unverified", socialtest.Token); err != errSocialEmailRequired {
+		t.Errorf("expected unverified email to be rejected got %v", err)
+	}
+	if _, _, err := sa.SignIn("google", "bad token"); err == nil {
+		t.Error("expected invalid token to be rejected")
+	}
+	if _, _, err := sa.SignIn("myspace", socialtest.Token); err == nil {
+		t.Error("expected unknown provider to be rejected")
+	}
+}
+
+func TestSocialAuth_Link(t *testing.T) {
+	sa, existing := newTestSocialAuth(t)
+
+	// identities of several providers are linked to the same user
+	for _, p := range []string{"google", "github", "google"} {
+		i, err := sa.Link(existing, p, socialtest.Token)
+		if err != nil {
+			t.Fatalf("%s: %v", p, err)
+		}
+		if i.UserID != existing.ID || i.Provider != p {
+			t.Errorf("%s: unexpected identity %+v", p, i)
+		}
+
+		u, created, err := sa.SignIn(p, socialtest.Token)
+		if err != nil {
+			t.Fatalf("%s: %v", p, err)
+		}
+		if created || u.ID != existing.ID {
+			t.Errorf("%s: expected linked user got %+v, created: %v", p, u, created)
+		}
+	}
+
+	is, err := sa.Identities(existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(is) != 2 {
+		t.Fatalf("expected 2 identities got %d", len(is))
+	}
+
+	// an identity can't be linked to another user
+	other, _, err := sa.SignIn("facebook", socialtest.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sa.Link(other, "google", socialtest.Token); err != errIdentityLinked {
+		t.Errorf("expected identity of another user to be rejected got %v", err)
+	}
+}
+
+func TestSocialAuth_Unlink(t *testing.T) {
+	sa, existing := newTestSocialAuth(t)
+
+	i, err := sa.Link(existing, "google", socialtest.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sa.Unlink(existing, i.ID+1); err != errIdentityNotFound {
+		t.Errorf("expected unknown identity not found got %v", err)
+	}
+	if err := sa.Unlink(existing, i.ID); err != nil {
+		t.Errorf("expected identity to be unlinked got %v", err)
+	}
+
+	// users without password must keep one identity
+	u, _, err := sa.SignIn("facebook", socialtest.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	is, err := sa.Identities(u)
+	if err != nil || len(is) != 1 {
+		t.Fatalf("expected 1 identity got %v, err: %v", is, err)
+	}
+	if err := sa.Unlink(u, is[0].ID); err != errLastSignInMethod {
+		t.Errorf("expected the last identity can't be unlinked got %v", err)
+	}
+}
@@ -0,0 +1,255 @@
+package usecases
+
+import (
+	"app"
+	"app/interfaces/errs"
+	"sort"
+	"time"
+)
+
+var (
+	errInvalidStock       = errs.BadRequest("stock and low stock threshold can't be negative")
+	errStockBelowReserved = errs.Conflict("stock can't be less than the quantity reserved by orders")
+)
+
+// DefaultReservationTTL is how long the stock is held for an unconfirmed order
+const DefaultReservationTTL = 30 * time.Minute
+
+// stockRepo changes stock with conditional updates, so concurrent orders can't oversell.
+// Reservations are created with the order by the checkout repo.
+type stockRepo interface {
+	app.Databaser
+	// SetStock sets the product's or option value's stock unless it's less than the reserved quantity,
+	// it reports whether it's set
+	SetStock(k app.StockKey, stock int) (bool, error)
+	// AdjustStock adds delta to the stock unless it drops below the reserved quantity, it reports whether it's added
+	AdjustStock(k app.StockKey, delta int) (bool, error)
+	FindLowStockProducts(f *app.DBFilter) (ps []app.Product, total int, err error)
+	// ExpireReservations releases the reserved stock of reservations expired before the time
+	ExpireReservations(before time.Time) (int, error)
+}
+
+func NewStock(r stockRepo) *Stock {
+	return &Stock{stockRepo: r, ReservationTTL: DefaultReservationTTL, now: time.Now}
+}
+
+// Stock tracks products' stock. Checkout reserves the stock, confirming the order
+// decrements it and cancelling the order releases it.
+type Stock struct {
+	stockRepo
+
+	ReservationTTL time.Duration
+	// ConfirmStatuses are the order statuses which decrement the stock
+	ConfirmStatuses []int
+	// ReleaseStatuses are the order statuses which give the stock back
+	ReleaseStatuses []int
+
+	now func() time.Time
+}
+
+// Reserve sets the order's reservations for the cart's products and option values which track stock.
+// Availability is checked here for a friendly error, the checkout repo reserves
+// the stock with a conditional update while placing the order.
+// Reservations are sorted by stock key, so concurrent orders lock rows in the same order.
+func (ss *Stock) Reserve(o *app.Order, items []app.CartItem) error {
+	type held struct {
+		qty, available int
+		err            error
+	}
+	stock := make(map[app.StockKey]*held)
+	var keys []app.StockKey
+	add := func(k app.StockKey, qty, available int, err error) {
+		h, ok := stock[k]
+		if !ok {
+			h = &held{available: available, err: err}
+			stock[k] = h
+			keys = append(keys, k)
+		}
+		h.qty += qty
+	}
+
+	for _, ci := range items {
+		p := ci.Product
+		if p == nil {
+			continue
+		}
+		if p.TrackStock {
+			add(app.StockKey{ProductID: p.ID}, ci.Qty, p.Available(), outOfStock(p))
+		}
+		for _, id := range ci.Options.ValueIDs() {
+			if v, ok := p.OptionValue(id); ok && v.TrackStock {
+				add(app.StockKey{ProductID: p.ID, OptionValueID: v.ID}, ci.Qty, v.Available(), optionOutOfStock(p, v))
+			}
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].ProductID != keys[j].ProductID {
+			return keys[i].ProductID < keys[j].ProductID
+		}
+		return keys[i].OptionValueID < keys[j].OptionValueID
+	})
+
+	expires := ss.now().Add(ss.ReservationTTL)
+	o.Reservations = nil
+	for _, k := range keys {
+		h := stock[k]
+		if h.qty > h.available {
+			return h.err
+		}
+		o.Reservations = append(o.Reservations, app.StockReservation{
+			StockKey:  k,
+			Qty:       h.qty,
+			Status:    app.ReservationReserved,
+			ExpiresAt: expires,
+		})
+	}
+	return nil
+}
+
+// StockAction gets how the order's stock changes by its new status,
+// the order repo changes the stock with the status in a single transaction
+func (ss *Stock) StockAction(statusID int) app.StockAction {
+	switch {
+	case hasStatus(ss.ConfirmStatuses, statusID):
+		return app.StockCommit
+	case hasStatus(ss.ReleaseStatuses, statusID):
+		return app.StockRelease
+	}
+	return app.StockUnchanged
+}
+
+// Expire releases the stock of expired reservations
+func (ss *Stock) Expire() (int, error) {
+	return ss.ExpireReservations(ss.now())
+}
+
+// LowStock gets products which track stock and are at or below their low stock thresholds
+func (ss *Stock) LowStock(f *app.DBFilter) ([]app.Product, int, error) {
+	return ss.FindLowStockProducts(f)
+}
+
+// UpdateStock changes the product's stock settings. Stock sets the quantity on hand
+// and Adjust adds to it, both can't drop it below the reserved quantity.
+func (ss *Stock) UpdateStock(f *StockForm) (*app.Product, error) {
+	var p app.Product
+	if err := ss.One(&p, f.ProductID); err != nil {
+		if ss.IsNotFoundErr(err) {
+			return nil, errProductNotFound
+		}
+		return nil, err
+	}
+
+	kv := make(map[string]interface{})
+	if f.TrackStock != nil {
+		kv["TrackStock"] = *f.TrackStock
+	}
+	if f.LowStockThreshold != nil {
+		if *f.LowStockThreshold < 0 {
+			return nil, errInvalidStock
+		}
+		kv["LowStockThreshold"] = *f.LowStockThreshold
+	}
+	if len(kv) > 0 {
+		if err := ss.UpdateFields(&p, kv); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ss.changeStock(app.StockKey{ProductID: p.ID}, f.Stock, f.Adjust); err != nil {
+		return nil, err
+	}
+
+	return &p, ss.One(&p, p.ID)
+}
+
+// UpdateOptionStock changes the option value's stock settings like UpdateStock
+func (ss *Stock) UpdateOptionStock(f *OptionStockForm) (*app.OptionValue, error) {
+	var v app.OptionValue
+	if err := ss.One(&v, f.OptionValueID); err != nil {
+		if ss.IsNotFoundErr(err) {
+			return nil, errOptionValueNotFound
+		}
+		return nil, err
+	}
+	var g app.OptionGroup
+	if err := ss.One(&g, v.GroupID); err != nil {
+		return nil, err
+	}
+
+	if f.TrackStock != nil {
+		if err := ss.UpdateField(&v, "TrackStock", *f.TrackStock); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ss.changeStock(app.StockKey{ProductID: g.ProductID, OptionValueID: v.ID}, f.Stock, f.Adjust); err != nil {
+		return nil, err
+	}
+
+	return &v, ss.One(&v, v.ID)
+}
+
+// changeStock sets the stock if it isn't nil and adds adjust to it
+func (ss *Stock) changeStock(k app.StockKey, stock *int, adjust int) error {
+	if stock != nil {
+		if *stock < 0 {
+			return errInvalidStock
+		}
+		ok, err := ss.SetStock(k, *stock)
+		if err != nil {
+			return err
+		} else if !ok {
+			return errStockBelowReserved
+		}
+	}
+	if adjust != 0 {
+		ok, err := ss.AdjustStock(k, adjust)
+		if err != nil {
+			return err
+		} else if !ok {
+			return errStockBelowReserved
+		}
+	}
+	return nil
+}
+
+func hasStatus(ss []int, id int) bool {
+	for _, s := range ss {
+		if s == id {
+			return true
+		}
+	}
+	return false
+}
+
+func outOfStock(p *app.Product) error {
+	if p.Available() <= 0 {
+		return errs.Conflict("%s is out of stock", p.Title)
+	}
+	return errs.Conflict("only %d of %s left in stock", p.Available(), p.Title)
+}
+
+func optionOutOfStock(p *app.Product, v *app.OptionValue) error {
+	if v.Available() <= 0 {
+		return errs.Conflict("%s (%s) is out of stock", p.Title, v.Name)
+	}
+	return errs.Conflict("only %d of %s (%s) left in stock", v.Available(), p.Title, v.Name)
+}
+
+// StockForm changes a product's stock, nil fields aren't changed
+type StockForm struct {
+	ProductID         int   `json:"-"`
+	TrackStock        *bool `json:"trackStock"`
+	Stock             *int  `json:"stock"`
+	Adjust            int   `json:"adjust"`
+	LowStockThreshold *int  `json:"lowStockThreshold"`
+}
+
+// OptionStockForm changes an option value's stock, nil fields aren't changed
+type OptionStockForm struct {
+	OptionValueID int   `json:"-"`
+	TrackStock    *bool `json:"trackStock"`
+	Stock         *int  `json:"stock"`
+	Adjust        int   `json:"adjust"`
+}
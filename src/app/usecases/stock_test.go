@@ -0,0 +1,200 @@
+package usecases
+
+import (
+	"app"
+	"app/interfaces/repos/mockdb"
+	"testing"
+	"time"
+)
+
+// checkoutDBStub finds checkout's address, payment method and initial status
+type checkoutDBStub struct {
+	app.Databaser
+}
+
+func (checkoutDBStub) One(m interface{}, id interface{}) error {
+	switch m := m.(type) {
+	case *app.PaymentMethod:
+		m.ID, m.Status = id.(int), true
+	case *app.OrderStatus:
+		m.ID = id.(int)
+	}
+	return nil
+}
+
+func (checkoutDBStub) OneBy(m interface{}, w app.DBWhere) error {
+	if a, ok := m.(*app.Address); ok {
+		a.ID, a.UserID = w["id"].(int), w["user_id"].(int)
+	}
+	return nil
+}
+
+func (checkoutDBStub) IsNotFoundErr(error) bool { return false }
+
+type checkoutRepoStub struct {
+	checkoutDBStub
+	*mockdb.Stock
+}
+
+// stockCart is a cart of a single product with the chosen option values,
+// the product is read from the stock like the cart repo
+type stockCart struct {
+	st      *mockdb.Stock
+	qty     int
+	options []int
+}
+
+func (sc stockCart) Cart(u *app.User) (*app.Cart, error) {
+	p, err := sc.st.Product(1)
+	if err != nil {
+		return nil, err
+	}
+	po, err := p.SelectOptions(sc.options)
+	if err != nil {
+		return nil, err
+	}
+	ci := app.CartItem{ProductID: p.ID, Qty: sc.qty, Price: p.Price.Add(po.PriceDelta()), Options: po, Product: p}
+	c := &app.Cart{UserID: u.ID, Items: []app.CartItem{ci}}
+	c.SetTotal()
+	return c, nil
+}
+
+type taxStub struct{}
+
+func (taxStub) Apply(o *app.Order) error {
+	o.SetTotal()
+	return nil
+}
+
+type nopEmails struct{}
+
+func (nopEmails) Send(name, locale string, to []string, d *EmailData) error { return nil }
+
+func newTestStock(stock int) (*Stock, *mockdb.Stock) {
+	return newTestProductStock(&app.Product{Title: "Simit", Price: app.MustParseMoney("5"), IsActive: true, TrackStock: true, Stock: stock, LowStockThreshold: 2})
+}
+
+func newTestProductStock(p *app.Product) (*Stock, *mockdb.Stock) {
+	st := &mockdb.Stock{}
+	st.AddProduct(p)
+	return NewStock(struct {
+		checkoutDBStub
+		*mockdb.Stock
+	}{checkoutDBStub{}, st}), st
+}
+
+func TestStock_changeOrderStatus(t *testing.T) {
+	ss, st := newTestStock(3)
+	ss.ConfirmStatuses, ss.ReleaseStatuses = []int{2}, []int{3}
+	now := time.Now()
+	ss.now = func() time.Time { return now }
+	cart := stockCart{st: st, qty: 1}
+
+	var ids []int
+	for i := 0; i < 3; i++ {
+		c, _ := cart.Cart(&app.User{})
+		o := &app.Order{StatusID: 1}
+		if err := ss.Reserve(o, c.Items); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.PlaceOrder(o, nil, c); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, o.ID)
+	}
+
+	check := func(name string, stock, reserved int) {
+		p, _ := st.Product(1)
+		if p.Stock != stock || p.Reserved != reserved {
+			t.Errorf("%s: expected stock %d reserved %d got %d %d", name, stock, reserved, p.Stock, p.Reserved)
+		}
+	}
+	change := func(id, from, to int) error {
+		o := &app.Order{StatusID: to}
+		o.ID = id
+		return st.ChangeOrderStatus(o, from, &app.OrderHistory{}, ss.StockAction(to))
+	}
+	check("reserved", 3, 3)
+
+	if err := change(ids[0], 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	check("confirmed", 2, 2)
+
+	if err := change(ids[1], 1, 3); err != nil {
+		t.Fatal(err)
+	}
+	check("cancelled", 2, 1)
+
+	if err := change(ids[0], 2, 3); err != nil {
+		t.Fatal(err)
+	}
+	check("confirmed order cancelled", 3, 1)
+
+	ss.now = func() time.Time { return now.Add(ss.ReservationTTL) }
+	if n, err := ss.Expire(); err != nil || n != 1 {
+		t.Fatalf("expected 1 expired reservation got %d, err: %v", n, err)
+	}
+	check("expired", 3, 0)
+
+	// an expired reservation is committed again
+	if err := change(ids[2], 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	check("expired confirmed", 2, 0)
+
+	if ps, total, _ := ss.LowStock(&app.DBFilter{}); total != 1 || ps[0].ID != 1 {
+		t.Errorf("expected the product to be low on stock got %d", total)
+	}
+}
+
+func TestStock_optionValueReservation(t *testing.T) {
+	ss, st := newTestProductStock(&app.Product{Title: "Köfte", Price: app.MustParseMoney("40"), IsActive: true, OptionGroups: []app.OptionGroup{
+		{Name: "Porsiyon", Required: true, MaxSelect: 1, Values: []app.OptionValue{
+			{Name: "Normal", IsActive: true},
+			{Name: "1.5 Porsiyon", PriceDelta: app.MustParseMoney("10"), IsActive: true, TrackStock: true, Stock: 3},
+		}},
+	}})
+	ss.ReleaseStatuses = []int{3}
+	wf := &app.OrderWorkflow{Initial: 1}
+	u := &app.User{}
+	u.ID = 1
+
+	checkout := func(valueID, qty int) (*app.Order, error) {
+		cs := NewCheckout(checkoutRepoStub{Stock: st}, stockCart{st, qty, []int{valueID}}, taxStub{}, NewCoupons(&couponRepoStub{}), ss, wf, nopEmails{})
+		return cs.PlaceOrder(u, &CheckoutForm{AddressID: 1, PaymentMethodID: 1})
+	}
+	available := func() int {
+		p, _ := st.Product(1)
+		v, _ := p.OptionValue(2)
+		return v.Available()
+	}
+
+	o, err := checkout(2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op := o.Products[0]; op.Price != app.MustParseMoney("50") || op.Options.String() != "Porsiyon: 1.5 Porsiyon" {
+		t.Errorf("expected the option's price and snapshot got %s %s", op.Price, op.Options)
+	}
+	if available() != 1 {
+		t.Errorf("expected 1 available got %d", available())
+	}
+
+	if _, err := checkout(2, 2); err == nil {
+		t.Error("expected checkout to fail when the option is out of stock")
+	}
+	// the product and its other option don't track stock
+	if _, err := checkout(1, 5); err != nil {
+		t.Errorf("expected the untracked option to be ordered, err: %v", err)
+	}
+
+	from := o.StatusID
+	o.StatusID = 3
+	if err := st.ChangeOrderStatus(o, from, &app.OrderHistory{}, ss.StockAction(3)); err != nil {
+		t.Fatal(err)
+	}
+	if available() != 3 {
+		t.Errorf("expected cancelled order to release the option's stock got %d available", available())
+	}
+}
@@ -0,0 +1,158 @@
+package usecases
+
+import (
+	"app"
+	"app/interfaces/errs"
+)
+
+var (
+	errTaxClassNotFound = errs.NotFound("tax class not found")
+	errTaxClassInUse    = errs.Conflict("tax class is used by products or categories")
+	errInvalidTaxRate   = errs.BadRequest("tax rate must be between 0 and 100")
+)
+
+// DefaultTaxRate is the tax percentage of products which haven't a tax class
+const DefaultTaxRate = 18
+
+type taxRepo interface {
+	app.Databaser
+	FindTaxClasses() ([]app.TaxClass, error)
+	ProductTaxClass(productID int) (*app.TaxClass, error)
+	DeleteTaxClass(*app.TaxClass) error
+}
+
+func NewTaxes(r taxRepo) *Taxes {
+	return &Taxes{taxRepo: r, PricesIncludeTax: true, DefaultRate: DefaultTaxRate}
+}
+
+// Taxes manages tax classes and calculates orders' taxes
+type Taxes struct {
+	taxRepo
+
+	// PricesIncludeTax reports whether products' prices include the tax
+	PricesIncludeTax bool
+	// DefaultRate is used for products which haven't a tax class
+	DefaultRate int
+}
+
+// TaxClasses gets all tax classes
+func (ts *Taxes) TaxClasses() ([]app.TaxClass, error) {
+	return ts.FindTaxClasses()
+}
+
+func (ts *Taxes) CreateTaxClass(f *TaxClassForm) (*app.TaxClass, error) {
+	if err := errs.CheckStringLen(f.Name, 2, 255, "name"); err != nil {
+		return nil, err
+	}
+	if f.Rate == nil {
+		return nil, errInvalidTaxRate
+	}
+	if err := checkTaxRate(*f.Rate); err != nil {
+		return nil, err
+	}
+
+	tc := app.TaxClass{Name: f.Name, Rate: *f.Rate}
+	return &tc, ts.Store(&tc)
+}
+
+// UpdateTaxClass updates the tax class, placed orders aren't changed
+func (ts *Taxes) UpdateTaxClass(f *TaxClassForm) (*app.TaxClass, error) {
+	tc, err := ts.taxClass(f.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	kv := make(map[string]interface{})
+	if f.Name != "" {
+		if err := errs.CheckStringLen(f.Name, 2, 255, "name"); err != nil {
+			return nil, err
+		}
+		kv["Name"] = f.Name
+	}
+	if f.Rate != nil {
+		if err := checkTaxRate(*f.Rate); err != nil {
+			return nil, err
+		}
+		kv["Rate"] = *f.Rate
+	}
+
+	if len(kv) == 0 {
+		return tc, nil
+	}
+	return tc, ts.UpdateFields(tc, kv)
+}
+
+// DeleteTaxClass deletes the tax class if no product or category uses it
+func (ts *Taxes) DeleteTaxClass(id int) error {
+	tc, err := ts.taxClass(id)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range []interface{}{&app.Product{}, &app.Category{}} {
+		used, err := ts.ExistsBy(m, app.DBWhere{"tax_class_id": id})
+		if err != nil {
+			return err
+		} else if used {
+			return errTaxClassInUse
+		}
+	}
+	return ts.taxRepo.DeleteTaxClass(tc)
+}
+
+// Rate gets the product's tax rate by its tax class or its category's class
+func (ts *Taxes) Rate(productID int) (int, error) {
+	tc, err := ts.ProductTaxClass(productID)
+	if err != nil {
+		if ts.IsNotFoundErr(err) {
+			return ts.DefaultRate, nil
+		}
+		return 0, err
+	}
+	return tc.Rate, nil
+}
+
+// Apply sets tax rates of the order's products, and calculates the order's totals and taxes
+func (ts *Taxes) Apply(o *app.Order) error {
+	rates := make(map[int]int)
+	for i := range o.Products {
+		op := &o.Products[i]
+		rate, ok := rates[op.ProductID]
+		if !ok {
+			var err error
+			if rate, err = ts.Rate(op.ProductID); err != nil {
+				return err
+			}
+			rates[op.ProductID] = rate
+		}
+		op.TaxRate = rate
+	}
+
+	o.PricesIncludeTax = ts.PricesIncludeTax
+	o.SetTotal()
+	return nil
+}
+
+func (ts *Taxes) taxClass(id int) (*app.TaxClass, error) {
+	var tc app.TaxClass
+	if err := ts.One(&tc, id); err != nil {
+		if ts.IsNotFoundErr(err) {
+			return nil, errTaxClassNotFound
+		}
+		return nil, err
+	}
+	return &tc, nil
+}
+
+func checkTaxRate(rate int) error {
+	if rate < 0 || rate > 100 {
+		return errInvalidTaxRate
+	}
+	return nil
+}
+
+type TaxClassForm struct {
+	ID   int    `json:"-"`
+	Name string `json:"name"`
+	Rate *int   `json:"rate"`
+}
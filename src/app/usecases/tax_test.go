@@ -0,0 +1,69 @@
+package usecases
+
+import (
+	"app"
+	"errors"
+	"testing"
+)
+
+var errTaxClassStubNotFound = errors.New("not found")
+
+type taxRepoStub struct {
+	app.Databaser
+	classes map[int]*app.TaxClass
+	lookups int
+}
+
+func (r *taxRepoStub) FindTaxClasses() ([]app.TaxClass, error) { return nil, nil }
+
+func (r *taxRepoStub) DeleteTaxClass(*app.TaxClass) error { return nil }
+
+func (r *taxRepoStub) ProductTaxClass(productID int) (*app.TaxClass, error) {
+	r.lookups++
+	if tc, ok := r.classes[productID]; ok {
+		return tc, nil
+	}
+	return nil, errTaxClassStubNotFound
+}
+
+func (r *taxRepoStub) IsNotFoundErr(err error) bool {
+	return err == errTaxClassStubNotFound
+}
+
+func TestTaxes_Apply(t *testing.T) {
+	r := &taxRepoStub{classes: map[int]*app.TaxClass{1: {Name: "KDV %8", Rate: 8}}}
+	ts := NewTaxes(r)
+	ts.PricesIncludeTax = false
+
+	o := &app.Order{Products: []app.OrderProduct{
+		{ProductID: 1, Qty: 1, Price: app.MustParseMoney("10")},
+		{ProductID: 2, Qty: 1, Price: app.MustParseMoney("10")},
+		{ProductID: 1, Qty: 2, Price: app.MustParseMoney("10")},
+	}}
+	if err := ts.Apply(o); err != nil {
+		t.Fatal(err)
+	}
+
+	if o.Products[0].TaxRate != 8 || o.Products[1].TaxRate != DefaultTaxRate {
+		t.Errorf("expected product's class and default rate got %d, %d", o.Products[0].TaxRate, o.Products[1].TaxRate)
+	}
+	if r.lookups != 2 {
+		t.Errorf("expected tax classes to be looked up once per product got %d", r.lookups)
+	}
+	if o.PricesIncludeTax || o.Total != app.MustParseMoney("44.20") {
+		t.Errorf("expected tax exclusive total of 44.20 got %s", o.Total)
+	}
+}
+
+func TestTaxes_CreateTaxClass(t *testing.T) {
+	ts := NewTaxes(&taxRepoStub{})
+
+	for _, rate := range []int{-1, 101} {
+		if _, err := ts.CreateTaxClass(&TaxClassForm{Name: "KDV", Rate: &rate}); err != errInvalidTaxRate {
+			t.Errorf("rate %d: expected %v got %v", rate, errInvalidTaxRate, err)
+		}
+	}
+	if _, err := ts.CreateTaxClass(&TaxClassForm{Name: "KDV"}); err != errInvalidTaxRate {
+		t.Errorf("expected missing rate to be rejected got %v", err)
+	}
+}
@@ -0,0 +1,303 @@
+package usecases
+
+import (
+	"app"
+	"app/interfaces/errs"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+var (
+	errInvalidRefreshToken = errs.Unauthorized("invalid refresh token")
+	errRefreshTokenReused  = errs.Unauthorized("refresh token already used, all sessions of it are revoked")
+	errInvalidAccessToken  = errs.Unauthorized("invalid token")
+	errRevokedToken        = errs.Unauthorized("token revoked")
+	errInactiveUser        = errs.Unauthorized("inactive user")
+)
+
+// Default token lifetimes
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+type tokenRepo interface {
+	OneUser(id int) (*app.User, error)
+	CreateSession(*app.Session) error
+	OneSessionByHash(hash string) (*app.Session, error)
+	// RotateSession revokes the old session and creates the new one,
+	// returns false if the old session was already revoked
+	RotateSession(old, new *app.Session) (bool, error)
+	RevokeSessionFamily(family string) error
+	// RevokeUserSessions revokes all sessions of the user and increases its token version
+	RevokeUserSessions(*app.User) error
+	RevokeToken(*app.RevokedToken) error
+	IsTokenRevoked(jti string) (bool, error)
+	DeleteExpiredTokens(before time.Time) error
+	app.DBNotFoundErrChecker
+}
+
+// TokenPair is an access token and its refresh token
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+func NewTokens(r tokenRepo, ks app.TokenSigner) *Tokens {
+	return &Tokens{
+		tokenRepo:  r,
+		ks:         ks,
+		AccessTTL:  DefaultAccessTokenTTL,
+		RefreshTTL: DefaultRefreshTokenTTL,
+	}
+}
+
+// Tokens issues short-lived access tokens and rotating refresh tokens
+type Tokens struct {
+	tokenRepo
+	ks         app.TokenSigner
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+// Issue starts a new session for the user
+func (ts *Tokens) Issue(u *app.User) (*TokenPair, error) {
+	family, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	rt, s, err := ts.newSession(u.ID, family)
+	if err != nil {
+		return nil, err
+	}
+	if err := ts.CreateSession(s); err != nil {
+		return nil, err
+	}
+
+	return ts.pair(u, rt)
+}
+
+// Refresh rotates the refresh token and issues a new access token.
+// Presenting an already rotated refresh token revokes its whole family.
+func (ts *Tokens) Refresh(refreshToken string) (*TokenPair, error) {
+	if refreshToken == "" {
+		return nil, errInvalidRefreshToken
+	}
+
+	s, err := ts.OneSessionByHash(hashToken(refreshToken))
+	if err != nil {
+		if ts.IsNotFoundErr(err) {
+			return nil, errInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if s.IsRevoked() {
+		if err := ts.RevokeSessionFamily(s.Family); err != nil {
+			return nil, err
+		}
+		return nil, errRefreshTokenReused
+	}
+	if s.IsExpired(time.Now()) {
+		return nil, errInvalidRefreshToken
+	}
+
+	u, err := ts.OneUser(s.UserID)
+	if err != nil {
+		if ts.IsNotFoundErr(err) {
+			return nil, errInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if !u.IsActivated {
+		return nil, errInactiveUser
+	}
+
+	rt, ns, err := ts.newSession(u.ID, s.Family)
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := ts.RotateSession(s, ns)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// the token is used by a concurrent request
+		if err := ts.RevokeSessionFamily(s.Family); err != nil {
+			return nil, err
+		}
+		return nil, errRefreshTokenReused
+	}
+
+	return ts.pair(u, rt)
+}
+
+// Logout revokes the refresh token's session and the access token, both are optional
+func (ts *Tokens) Logout(accessToken, refreshToken string) error {
+	if refreshToken != "" {
+		s, err := ts.OneSessionByHash(hashToken(refreshToken))
+		if err != nil && !ts.IsNotFoundErr(err) {
+			return err
+		}
+		if err == nil {
+			if err := ts.RevokeSessionFamily(s.Family); err != nil {
+				return err
+			}
+		}
+	}
+
+	if accessToken != "" {
+		claims, err := ts.parse(accessToken)
+		if err == errs.ErrTokenExpired {
+			// an expired token can't be used anymore, so there is nothing to revoke
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		jti, _ := claims["jti"].(string)
+		exp, _ := claims["exp"].(float64)
+		if jti == "" {
+			return errInvalidAccessToken
+		}
+		if err := ts.RevokeToken(&app.RevokedToken{JTI: jti, ExpiresAt: time.Unix(int64(exp), 0)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevokeAll invalidates all access and refresh tokens of the user
+func (ts *Tokens) RevokeAll(u *app.User) error {
+	return ts.RevokeUserSessions(u)
+}
+
+// Authenticate validates the access token and returns its user
+func (ts *Tokens) Authenticate(tokenStr string) (*app.User, error) {
+	claims, err := ts.parse(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _ := claims["userID"].(string)
+	jti, _ := claims["jti"].(string)
+	ver, _ := claims["ver"].(float64)
+	id, err := strconv.Atoi(userID)
+	if err != nil || jti == "" {
+		return nil, errInvalidAccessToken
+	}
+
+	revoked, err := ts.IsTokenRevoked(jti)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errRevokedToken
+	}
+
+	u, err := ts.OneUser(id)
+	if err != nil {
+		if ts.IsNotFoundErr(err) {
+			return nil, errInvalidAccessToken
+		}
+		return nil, err
+	}
+	if int(ver) != u.TokenVersion {
+		return nil, errRevokedToken
+	}
+	return u, nil
+}
+
+// VerificationToken generates email verification token of the user
+func (ts *Tokens) VerificationToken(u *app.User) (string, error) {
+	return u.GenVerificationToken(ts.ks)
+}
+
+// ParseVerificationToken validates email verification token and returns its claims
+func (ts *Tokens) ParseVerificationToken(tokenStr string) (*app.VerificationClaims, error) {
+	return app.ParseVerificationToken(tokenStr, ts.ks)
+}
+
+// ResetToken generates password reset token of the user
+func (ts *Tokens) ResetToken(u *app.User) (string, error) {
+	return u.GenResetPasswordToken(ts.ks)
+}
+
+// ValidateResetToken validates the password reset token is issued for the user
+func (ts *Tokens) ValidateResetToken(u *app.User, tokenStr string) error {
+	return u.IsResetPasswordTokenValid(tokenStr, ts.ks)
+}
+
+// PurgeExpired removes the revoked access tokens which are already expired
+func (ts *Tokens) PurgeExpired() error {
+	return ts.DeleteExpiredTokens(time.Now())
+}
+
+func (ts *Tokens) parse(tokenStr string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenStr, ts.ks.Keyfunc)
+	if err != nil {
+		if errs.IsTokenExpiredErr(err) {
+			return nil, errs.ErrTokenExpired
+		}
+		return nil, errs.BadRequest("token string can't parsed.").SetInner(err)
+	}
+	return token.Claims.(jwt.MapClaims), nil
+}
+
+func (ts *Tokens) pair(u *app.User, refreshToken string) (*TokenPair, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"userID": strconv.Itoa(u.ID),
+		"jti":    jti,
+		"ver":    u.TokenVersion,
+		"iat":    now.Unix(),
+		"exp":    now.Add(ts.AccessTTL).Unix(),
+	}
+	token, err := ts.ks.Sign(claims)
+	if err != nil {
+		return nil, errs.WrapMsg(err, "token can't signed")
+	}
+
+	return &TokenPair{AccessToken: token, RefreshToken: refreshToken, ExpiresIn: ts.AccessTTL}, nil
+}
+
+// newSession creates a session and returns it with its plain refresh token
+func (ts *Tokens) newSession(userID int, family string) (string, *app.Session, error) {
+	rt, err := randomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	s := &app.Session{
+		UserID:    userID,
+		Family:    family,
+		TokenHash: hashToken(rt),
+		ExpiresAt: time.Now().Add(ts.RefreshTTL),
+	}
+	return rt, s, nil
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", errs.Wrap(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(t string) string {
+	h := sha256.Sum256([]byte(t))
+	return hex.EncodeToString(h[:])
+}
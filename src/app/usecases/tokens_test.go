@@ -0,0 +1,213 @@
+package usecases
+
+import (
+	"app"
+	"app/infra"
+	"app/interfaces/repos/mockdb"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+func newTestTokens(t *testing.T) (*Tokens, *app.User) {
+	ur := &mockdb.User{}
+	u := &app.User{Email: "user@gmail.com", IsActivated: true}
+	if err := ur.Create(u); err != nil {
+		t.Fatal(err)
+	}
+	return NewTokens(&mockdb.Token{Users: ur}, testKeySet(t, "secret")), u
+}
+
+func testKeySet(t *testing.T, secret string) app.TokenSigner {
+	ks, err := infra.NewKeySet(infra.NewHMACKey("test", []byte(secret)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+
+func TestTokens_Authenticate(t *testing.T) {
+	ts, u := newTestTokens(t)
+
+	tp, err := ts.Issue(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	au, err := ts.Authenticate(tp.AccessToken)
+	if err != nil {
+		t.Fatalf("expected token to be valid got %v", err)
+	}
+	if au.ID != u.ID {
+		t.Errorf("expected user %d got %d", u.ID, au.ID)
+	}
+
+	other := NewTokens(ts.tokenRepo, testKeySet(t, "other secret"))
+	if _, err := other.Authenticate(tp.AccessToken); err == nil {
+		t.Error("expected token signed by another key to be invalid")
+	}
+
+	ts.AccessTTL = -time.Minute
+	expired, err := ts.Issue(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.Authenticate(expired.AccessToken); err == nil {
+		t.Error("expected expired token to be invalid")
+	}
+}
+
+func TestTokens_RevokeAll(t *testing.T) {
+	ts, u := newTestTokens(t)
+
+	tp, err := ts.Issue(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ts.RevokeAll(u); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ts.Authenticate(tp.AccessToken); err != errRevokedToken {
+		t.Errorf("expected access token to be revoked got %v", err)
+	}
+	if _, err := ts.Refresh(tp.RefreshToken); err == nil {
+		t.Error("expected refresh token to be revoked")
+	}
+
+	// new sessions are valid
+	tp, err = ts.Issue(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.Authenticate(tp.AccessToken); err != nil {
+		t.Errorf("expected new access token to be valid got %v", err)
+	}
+}
+
+func TestTokens_Refresh(t *testing.T) {
+	ts, u := newTestTokens(t)
+
+	tp, err := ts.Issue(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := ts.Refresh(tp.RefreshToken)
+	if err != nil {
+		t.Fatalf("expected refresh token to be valid got %v", err)
+	}
+
+	// reusing the rotated token revokes the family
+	if _, err := ts.Refresh(tp.RefreshToken); err != errRefreshTokenReused {
+		t.Errorf("expected reuse to be detected got %v", err)
+	}
+	if _, err := ts.Refresh(rotated.RefreshToken); err != errRefreshTokenReused {
+		t.Errorf("expected the family to be revoked got %v", err)
+	}
+
+	ts.RefreshTTL = -time.Minute
+	expired, err := ts.Issue(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.Refresh(expired.RefreshToken); err != errInvalidRefreshToken {
+		t.Errorf("expected expired refresh token to be invalid got %v", err)
+	}
+
+	u.IsActivated = false
+	ts.RefreshTTL = time.Hour
+	tp, err = ts.Issue(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.Refresh(tp.RefreshToken); err != errInactiveUser {
+		t.Errorf("expected inactive user to be rejected got %v", err)
+	}
+}
+
+func TestTokens_Logout(t *testing.T) {
+	ts, u := newTestTokens(t)
+
+	ts.AccessTTL = -time.Minute
+	tp, err := ts.Issue(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an expired access token doesn't fail the logout
+	if err := ts.Logout(tp.AccessToken, tp.RefreshToken); err != nil {
+		t.Fatalf("expected logout with expired access token to succeed got %v", err)
+	}
+	if _, err := ts.Refresh(tp.RefreshToken); err == nil {
+		t.Error("expected refresh token to be revoked")
+	}
+
+	if err := ts.Logout("bad token", ""); err == nil {
+		t.Error("expected invalid access token to be rejected")
+	}
+}
+
+func TestTokens_ResetToken(t *testing.T) {
+	ts, u := newTestTokens(t)
+
+	// users without a password can't reset it
+	if _, err := ts.ResetToken(u); err != app.ErrPasswordNotSet {
+		t.Errorf("expected error %v got %v", app.ErrPasswordNotSet, err)
+	}
+
+	// a token signed by the empty password hash was accepted for users without a password
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"email": u.Email, "exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.ValidateResetToken(u, forged); err == nil {
+		t.Error("expected token forged by empty key to be rejected")
+	}
+
+	u.SetPassword("good password")
+	if err := ts.ValidateResetToken(u, forged); err == nil {
+		t.Error("expected token forged by empty key to be rejected")
+	}
+
+	token, err := ts.ResetToken(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.ValidateResetToken(u, token); err != nil {
+		t.Errorf("expected token to be valid got %v", err)
+	}
+
+	other := *u
+	other.ID++
+	if err := ts.ValidateResetToken(&other, token); err == nil {
+		t.Error("expected token of another user to be rejected")
+	}
+
+	verification, err := ts.VerificationToken(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.ValidateResetToken(u, verification); err == nil {
+		t.Error("expected verification token to be rejected")
+	}
+
+	// changing the password or revoking sessions invalidates the token
+	u.SetPassword("new password")
+	if err := ts.ValidateResetToken(u, token); err == nil {
+		t.Error("expected token of the old password to be rejected")
+	}
+	if token, err = ts.ResetToken(u); err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.RevokeAll(u); err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.ValidateResetToken(u, token); err == nil {
+		t.Error("expected token of revoked sessions to be rejected")
+	}
+}
@@ -0,0 +1,213 @@
+package usecases
+
+import (
+	"app"
+	"app/interfaces/errs"
+	"net/url"
+	"strings"
+)
+
+var (
+	errUserNotFound   = errs.NotFound("user not found")
+	errSelfManage     = errs.BadRequest("you can't change your own account from here")
+	errAdminRequired  = errs.Forbidden("only admins can manage admin users")
+	errResetLinkEmpty = errs.BadRequest("password reset link isn't configured")
+)
+
+// userRecentOrders is the number of orders shown in user's detail
+const userRecentOrders = 10
+
+type usersRepo interface {
+	FindUsers(query string, w app.DBWhere, f *app.DBFilter) (us []app.User, total int, err error)
+	OneByID(int) (*app.User, error)
+	UpdateUser(*app.User, map[string]interface{}) error
+	DeleteUser(*app.User) error
+	app.DBNotFoundErrChecker
+}
+
+type userAddressRepo interface {
+	FindAddressesByUser(userID int) ([]app.Address, error)
+}
+
+type userOrderRepo interface {
+	FindOrdersByUser(userID int, f *app.DBFilter) ([]app.Order, error)
+}
+
+type userTokens interface {
+	RevokeAll(*app.User) error
+	ResetToken(*app.User) (string, error)
+}
+
+func NewUsers(r usersRepo, ar userAddressRepo, or userOrderRepo, ts userTokens, es emailSender) *Users {
+	return &Users{usersRepo: r, ar: ar, or: or, ts: ts, es: es}
+}
+
+// Users manages customers for admins
+type Users struct {
+	usersRepo
+	ar userAddressRepo
+	or userOrderRepo
+	ts userTokens
+	es emailSender
+	// ResetURL is the password reset link which the token is added to
+	ResetURL string
+}
+
+// UserFilterForm filters users, nil fields aren't filtered.
+// Query is searched in email, first name and last name.
+type UserFilterForm struct {
+	Query       string
+	IsActivated *bool
+	IsAdmin     *bool
+}
+
+// UserDetail is a user with its addresses and recent orders
+type UserDetail struct {
+	User      *app.User
+	Addresses []app.Address
+	Orders    []app.Order
+}
+
+// Users gets users matching the filter and total count of them
+func (us *Users) Users(ff *UserFilterForm, f *app.DBFilter) ([]app.User, int, error) {
+	w := app.DBWhere{}
+	if ff.IsActivated != nil {
+		w["is_activated"] = *ff.IsActivated
+	}
+	if ff.IsAdmin != nil {
+		w["is_admin"] = *ff.IsAdmin
+	}
+
+	if f.OrderBy == "" {
+		f.OrderBy = "created_at"
+		f.Reverse = true
+	}
+	return us.FindUsers(strings.TrimSpace(ff.Query), w, f)
+}
+
+// User gets a user with its addresses and recent orders
+func (us *Users) User(id int) (*UserDetail, error) {
+	u, err := us.user(id)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &UserDetail{User: u}
+	if d.Addresses, err = us.ar.FindAddressesByUser(u.ID); err != nil {
+		return nil, err
+	}
+
+	f := &app.DBFilter{Limit: userRecentOrders, OrderBy: "created_at", Reverse: true}
+	if d.Orders, err = us.or.FindOrdersByUser(u.ID, f); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// SetActive activates or deactivates the user.
+// Deactivated users are signed out from all sessions.
+func (us *Users) SetActive(by *app.User, id int, active bool) (*app.User, error) {
+	u, err := us.manageable(by, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := us.UpdateUser(u, map[string]interface{}{"IsActivated": active}); err != nil {
+		return nil, err
+	}
+
+	if !active {
+		if err := us.ts.RevokeAll(u); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+// SetAdmin promotes the user to admin or demotes it, only admins can do it
+func (us *Users) SetAdmin(by *app.User, id int, admin bool) (*app.User, error) {
+	if !by.IsAdmin {
+		return nil, errAdminRequired
+	}
+
+	u, err := us.manageable(by, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := us.UpdateUser(u, map[string]interface{}{"IsAdmin": admin}); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// SendPasswordReset sends a password reset email to the user,
+// the reset token is added to the configured link
+func (us *Users) SendPasswordReset(by *app.User, id int, locale string) error {
+	if us.ResetURL == "" {
+		return errResetLinkEmpty
+	}
+	resetURL, err := url.Parse(us.ResetURL)
+	if err != nil {
+		return errs.BadRequest("invalid url").SetInner(err)
+	}
+
+	u, err := us.manageable(by, id)
+	if err != nil {
+		return err
+	}
+
+	token, err := us.ts.ResetToken(u)
+	if err != nil {
+		return err
+	}
+
+	q := resetURL.Query()
+	q.Set("token", token)
+	resetURL.RawQuery = q.Encode()
+
+	d := &EmailData{User: u, Link: resetURL.String()}
+	return us.es.Send(EmailPasswordReset, locale, []string{u.Email}, d)
+}
+
+// Delete soft deletes the user and signs it out from all sessions
+func (us *Users) Delete(by *app.User, id int) error {
+	u, err := us.manageable(by, id)
+	if err != nil {
+		return err
+	}
+
+	if err := us.DeleteUser(u); err != nil {
+		return err
+	}
+	return us.ts.RevokeAll(u)
+}
+
+func (us *Users) user(id int) (*app.User, error) {
+	u, err := us.OneByID(id)
+	if err != nil {
+		if us.IsNotFoundErr(err) {
+			return nil, errUserNotFound
+		}
+		return nil, err
+	}
+	return u, nil
+}
+
+// manageable gets the user if the admin can change it.
+// Admins can't change themselves and only admins can change other admins.
+func (us *Users) manageable(by *app.User, id int) (*app.User, error) {
+	if by.ID == id {
+		return nil, errSelfManage
+	}
+
+	u, err := us.user(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.IsAdmin && !by.IsAdmin {
+		return nil, errAdminRequired
+	}
+	return u, nil
+}
@@ -0,0 +1,154 @@
+package usecases
+
+import (
+	"app"
+	"app/interfaces/repos/mockdb"
+	"strings"
+	"testing"
+)
+
+type userDetailRepo struct{}
+
+func (userDetailRepo) FindAddressesByUser(userID int) ([]app.Address, error) {
+	return []app.Address{{UserID: userID}}, nil
+}
+
+func (userDetailRepo) FindOrdersByUser(userID int, f *app.DBFilter) ([]app.Order, error) {
+	return []app.Order{{UserID: userID}}, nil
+}
+
+func newTestUsers(t *testing.T) (*Users, *mailRecorder, *app.User, *app.User, *app.User) {
+	ur := &mockdb.User{}
+	admin := &app.User{Email: "admin@gmail.com", IsActivated: true, IsAdmin: true}
+	manager := &app.User{Email: "manager@gmail.com", IsActivated: true, Role: "manager"}
+	customer := &app.User{Email: "customer@gmail.com", FirstName: "Ayşe", IsActivated: true}
+	for _, u := range []*app.User{admin, manager, customer} {
+		if err := ur.Create(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mr := &mailRecorder{}
+	ts := NewTokens(&mockdb.Token{Users: ur}, testKeySet(t, "secret"))
+	return NewUsers(ur, userDetailRepo{}, userDetailRepo{}, ts, NewEmails(mr, "en")), mr, admin, manager, customer
+}
+
+func TestUsers_Users(t *testing.T) {
+	us, _, _, _, customer := newTestUsers(t)
+
+	res, total, err := us.Users(&UserFilterForm{Query: " Ayşe "}, &app.DBFilter{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(res) != 1 || res[0].ID != customer.ID {
+		t.Errorf("expected only the customer got %d users, total: %d", len(res), total)
+	}
+
+	yes := true
+	if _, total, _ = us.Users(&UserFilterForm{IsAdmin: &yes}, &app.DBFilter{Limit: 10}); total != 1 {
+		t.Errorf("expected 1 admin got %d", total)
+	}
+
+	res, total, _ = us.Users(&UserFilterForm{}, &app.DBFilter{Limit: 2})
+	if total != 3 || len(res) != 2 {
+		t.Errorf("expected 2 of 3 users got %d of %d", len(res), total)
+	}
+}
+
+func TestUsers_User(t *testing.T) {
+	us, _, _, _, customer := newTestUsers(t)
+
+	d, err := us.User(customer.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.User != customer || len(d.Addresses) != 1 || len(d.Orders) != 1 {
+		t.Errorf("unexpected user detail %+v", d)
+	}
+
+	if _, err := us.User(99); err != errUserNotFound {
+		t.Errorf("expected %v got %v", errUserNotFound, err)
+	}
+}
+
+func TestUsers_SetActive(t *testing.T) {
+	us, _, admin, manager, customer := newTestUsers(t)
+
+	u, err := us.SetActive(manager, customer.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.IsActivated || u.TokenVersion != 1 {
+		t.Errorf("expected deactivated and signed out user got %+v", u)
+	}
+	if u, _ = us.SetActive(manager, customer.ID, true); !u.IsActivated {
+		t.Error("expected activated user")
+	}
+
+	if _, err := us.SetActive(admin, admin.ID, false); err != errSelfManage {
+		t.Errorf("expected %v got %v", errSelfManage, err)
+	}
+	if _, err := us.SetActive(manager, admin.ID, false); err != errAdminRequired {
+		t.Errorf("expected %v got %v", errAdminRequired, err)
+	}
+}
+
+func TestUsers_SetAdmin(t *testing.T) {
+	us, _, admin, manager, customer := newTestUsers(t)
+
+	if _, err := us.SetAdmin(manager, customer.ID, true); err != errAdminRequired {
+		t.Errorf("expected %v got %v", errAdminRequired, err)
+	}
+
+	u, err := us.SetAdmin(admin, customer.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !u.IsAdmin {
+		t.Error("expected promoted user")
+	}
+	if u, _ = us.SetAdmin(admin, customer.ID, false); u.IsAdmin {
+		t.Error("expected demoted user")
+	}
+}
+
+func TestUsers_SendPasswordReset(t *testing.T) {
+	us, mr, admin, manager, customer := newTestUsers(t)
+
+	if err := us.SendPasswordReset(manager, customer.ID, "en"); err != errResetLinkEmpty {
+		t.Errorf("expected %v got %v", errResetLinkEmpty, err)
+	}
+
+	us.ResetURL = "http://localhost/reset"
+	if err := us.SendPasswordReset(manager, admin.ID, "en"); err != errAdminRequired {
+		t.Errorf("expected %v got %v", errAdminRequired, err)
+	}
+	if err := us.SendPasswordReset(manager, customer.ID, "en"); err != app.ErrPasswordNotSet {
+		t.Errorf("expected %v for a user without password got %v", app.ErrPasswordNotSet, err)
+	}
+
+	customer.SetPassword("good password")
+	if err := us.SendPasswordReset(manager, customer.ID, "en"); err != nil {
+		t.Fatal(err)
+	}
+	if len(mr.to) != 1 || mr.to[0] != customer.Email {
+		t.Errorf("expected mail to %s got %v", customer.Email, mr.to)
+	}
+	if !strings.Contains(string(mr.html), "http://localhost/reset?token=") {
+		t.Error("expected reset link in the mail")
+	}
+}
+
+func TestUsers_Delete(t *testing.T) {
+	us, _, _, manager, customer := newTestUsers(t)
+
+	if err := us.Delete(manager, customer.ID); err != nil {
+		t.Fatal(err)
+	}
+	if customer.DeletedAt == nil || customer.TokenVersion != 1 {
+		t.Errorf("expected deleted and signed out user got %+v", customer)
+	}
+	if _, err := us.User(customer.ID); err != errUserNotFound {
+		t.Errorf("expected deleted user to be not found got %v", err)
+	}
+}
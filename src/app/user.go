@@ -3,6 +3,9 @@ package app
 import (
 	"app/interfaces/errs"
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"strconv"
 	"time"
 
@@ -14,15 +17,53 @@ type contextKey string
 
 var userContextKey contextKey = "user"
 
+const (
+	verificationTokenPurpose = "verify"
+	resetTokenPurpose        = "reset"
+)
+
+// ErrPasswordNotSet is returned for password reset of users who sign in only by social accounts
+var ErrPasswordNotSet = errs.BadRequest("user hasn't a password, sign in with the social account")
+
 // User model
 type User struct {
-	Model       `storm:"inline"`
-	FirstName   string `json:"firstName" fako:"first_name"`
-	LastName    string `json:"lastName" fako:"last_name"`
-	Email       string `json:"email" fako:"email_address" storm:"unique"`
-	Password    string `json:"password" fako:"simple_password"`
-	IsActivated bool   `json:"isActivated"`
-	IsAdmin     bool   `json:"isAdmin"`
+	ModelSoftDelete `storm:"inline"`
+	FirstName       string `json:"firstName" fako:"first_name"`
+	LastName        string `json:"lastName" fako:"last_name"`
+	Email           string `json:"email" fako:"email_address" storm:"unique"`
+	Password        string `json:"-" fako:"simple_password"`
+	IsActivated     bool   `json:"isActivated"`
+	IsAdmin         bool   `json:"isAdmin"`
+	Role            string `json:"role"`
+
+	// PendingEmail is the new email address waiting for verification
+	PendingEmail       string     `json:"pendingEmail,omitempty"`
+	VerificationSentAt *time.Time `json:"-"`
+	// TokenVersion is increased to invalidate all issued access tokens
+	TokenVersion int `json:"-"`
+}
+
+// SocialIdentity links a user to an account of a social login provider.
+// A user can have identities of several providers.
+type SocialIdentity struct {
+	Model
+	UserID   int    `json:"-" gorm:"index"`
+	Provider string `json:"provider" gorm:"unique_index:idx_social_identity"`
+	Subject  string `json:"-" gorm:"unique_index:idx_social_identity"`
+	Email    string `json:"email"`
+}
+
+// Can checks user has all given permissions
+func (u *User) Can(ps ...Permission) bool {
+	if u.IsAdmin {
+		return true
+	}
+	for _, p := range ps {
+		if !HasPermission(u.Role, p) {
+			return false
+		}
+	}
+	return len(ps) > 0
 }
 
 // SetPassword sets user's password
@@ -42,55 +83,114 @@ func (u *User) IsCredentialsVerified(password string) bool {
 	return err == nil
 }
 
-// CreateJWT creates a Javascript Web Token (JWT)
-func (u *User) CreateJWT(secretKey string) (string, error) {
-	// claims
-	id := strconv.Itoa(int(u.ID))
+// GenResetPasswordToken generates password reset token. The token is bound to the user's
+// token version and password, so it's invalid once the password is changed or sessions are revoked.
+// Users without a password can't get a token.
+func (u *User) GenResetPasswordToken(s TokenSigner) (string, error) {
+	if u.Password == "" {
+		return "", ErrPasswordNotSet
+	}
 	claims := jwt.MapClaims{
-		"userID": id,
-		"exp":    time.Now().Add(time.Hour * 6).Unix(),
+		"userID":  strconv.Itoa(u.ID),
+		"ver":     u.TokenVersion,
+		"pwd":     u.passwordFingerprint(),
+		"purpose": resetTokenPurpose,
+		"exp":     time.Now().Add(time.Hour * 5).Unix(),
 	}
-
-	// token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// the final token (hashed string)
-	signedSecret, err := token.SignedString([]byte(secretKey))
+	tokenString, err := s.Sign(claims)
 	if err != nil {
 		return "", errs.WrapMsg(err, "token can't signed")
 	}
+	return tokenString, nil
+}
+
+// IsResetPasswordTokenValid validates the password reset token is issued for the user and its current password
+func (u *User) IsResetPasswordTokenValid(tokenStr string, s TokenSigner) error {
+	if u.Password == "" {
+		return ErrPasswordNotSet
+	}
+	token, err := jwt.Parse(tokenStr, s.Keyfunc)
+	if err != nil {
+		return err
+	}
 
-	return signedSecret, nil
+	claims := token.Claims.(jwt.MapClaims)
+	if claims["purpose"] != resetTokenPurpose {
+		return &jwt.ValidationError{Inner: errs.NewWithStack("token isn't a password reset token"), Errors: jwt.ValidationErrorClaimsInvalid}
+	}
+	idStr, _ := claims["userID"].(string)
+	ver, _ := claims["ver"].(float64)
+	if idStr != strconv.Itoa(u.ID) || int(ver) != u.TokenVersion {
+		return &jwt.ValidationError{Inner: errs.NewWithStack("token isn't issued for the user"), Errors: jwt.ValidationErrorClaimsInvalid}
+	}
+	pwd, _ := claims["pwd"].(string)
+	if subtle.ConstantTimeCompare([]byte(pwd), []byte(u.passwordFingerprint())) != 1 {
+		return &jwt.ValidationError{Inner: errs.NewWithStack("token is issued for another password"), Errors: jwt.ValidationErrorClaimsInvalid}
+	}
+	return nil
+}
+
+// passwordFingerprint identifies the password hash without exposing it in tokens
+func (u *User) passwordFingerprint() string {
+	sum := sha256.Sum256([]byte(u.Password))
+	return hex.EncodeToString(sum[:16])
 }
 
-func (u *User) GenResetPasswordToken() (string, error) {
-	claims := jwt.MapClaims{"email": u.Email, "exp": time.Now().Add(time.Hour * 5).Unix()}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(u.Password))
+// GenVerificationToken generates email verification token,
+// the token is for the pending email if the user has one
+func (u *User) GenVerificationToken(s TokenSigner) (string, error) {
+	email := u.Email
+	if u.PendingEmail != "" {
+		email = u.PendingEmail
+	}
+	claims := jwt.MapClaims{
+		"userID":  strconv.Itoa(u.ID),
+		"email":   email,
+		"ver":     u.TokenVersion,
+		"purpose": verificationTokenPurpose,
+		"exp":     time.Now().Add(time.Hour * 48).Unix(),
+	}
+	tokenString, err := s.Sign(claims)
 	if err != nil {
 		return "", errs.WrapMsg(err, "token can't signed")
 	}
 	return tokenString, nil
 }
 
-func (u *User) IsResetPasswordTokenValid(tokenStr string) error {
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		return []byte(u.Password), nil
-	})
+// VerificationClaims are the claims of an email verification token
+type VerificationClaims struct {
+	UserID int
+	Email  string
+	// Version is the user's token version when the token was issued
+	Version int
+}
+
+// ParseVerificationToken validates email verification token and returns its claims
+func ParseVerificationToken(tokenStr string, s TokenSigner) (*VerificationClaims, error) {
+	token, err := jwt.Parse(tokenStr, s.Keyfunc)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	email, ok := token.Claims.(jwt.MapClaims)["email"].(string)
-	if !ok {
-		return errs.NewWithStack("email can't get from token claims, token: %s", tokenStr)
+	claims := token.Claims.(jwt.MapClaims)
+	if claims["purpose"] != verificationTokenPurpose {
+		return nil, &jwt.ValidationError{Inner: errs.NewWithStack("token isn't a verification token"), Errors: jwt.ValidationErrorClaimsInvalid}
 	}
 
-	if email != u.Email {
-		return errs.NewWithStack("token's email and user's email aren't equal")
+	idStr, _ := claims["userID"].(string)
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return nil, &jwt.ValidationError{Inner: errs.NewWithStack("user id can't get from token claims"), Errors: jwt.ValidationErrorClaimsInvalid}
 	}
-
-	return nil
+	email, ok := claims["email"].(string)
+	if !ok {
+		return nil, &jwt.ValidationError{Inner: errs.NewWithStack("email can't get from token claims"), Errors: jwt.ValidationErrorClaimsInvalid}
+	}
+	ver, ok := claims["ver"].(float64)
+	if !ok {
+		return nil, &jwt.ValidationError{Inner: errs.NewWithStack("token version can't get from token claims"), Errors: jwt.ValidationErrorClaimsInvalid}
+	}
+	return &VerificationClaims{UserID: id, Email: email, Version: int(ver)}, nil
 }
 
 func (u *User) NewContext(ctx context.Context) context.Context {